@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// denoiseFrameSeconds is roughly how much audio each spectrum is taken over
+	denoiseFrameSeconds = 0.02
+	// denoiseOverSubtraction removes a little more than the noise estimate to keep musical noise down
+	denoiseOverSubtraction = 1.5
+	// denoiseFloor is the least gain any bin gets, -20dB, so speech never drops out entirely
+	denoiseFloor = 0.1
+	// powerSmoothing averages each bin's power over a few frames, steadying both
+	// the noise estimate and the gain against the randomness of a single frame
+	powerSmoothing = 0.7
+	// noiseTrackUp lets the noise estimate creep up per frame, about 3dB a second,
+	// so speech is not mistaken for noise but a fan switching on is learned
+	noiseTrackUp = 1.007
+	// noiseBias scales the minimum tracked noise up toward its mean, which sits
+	// several dB above the minimum of the smoothed power
+	noiseBias = 3
+)
+
+// NoiseSuppressor removes steady background noise, like fans, by spectral
+// subtraction.  It tracks the noise spectrum from the quietest recent frames
+// and attenuates each frequency bin by how much of it is noise.  Output lags
+// the input by one analysis frame.
+type NoiseSuppressor struct {
+	frameSize int
+	hopSize   int
+	window    []float64
+
+	channels []*denoiseChannel
+}
+
+type denoiseChannel struct {
+	input   []float64
+	overlap []float64
+	output  []float64
+	power   []float64
+	noise   []float64
+	learned bool
+}
+
+// NewNoiseSuppressor creates a NoiseSuppressor for audio at rate.
+func NewNoiseSuppressor(rate int) *NoiseSuppressor {
+	size := nextPowerOfTwo(int(float64(rate) * denoiseFrameSeconds))
+	n := &NoiseSuppressor{
+		frameSize: size,
+		hopSize:   size / 2,
+		window:    make([]float64, size),
+	}
+
+	// square root of a periodic Hann, applied before and after the transform
+	// so the half overlapped frames add back to unity
+	for i := range n.window {
+		n.window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size)))
+	}
+	return n
+}
+
+// Process suppresses noise in interleaved samples in place.
+func (n *NoiseSuppressor) Process(samples []int32, format Format) []int32 {
+	channels := format.channels()
+	for len(n.channels) < channels {
+		n.channels = append(n.channels, &denoiseChannel{
+			overlap: make([]float64, n.frameSize),
+			output:  make([]float64, n.frameSize),
+			power:   make([]float64, n.frameSize/2+1),
+			noise:   make([]float64, n.frameSize/2+1),
+		})
+	}
+
+	frames := len(samples) / channels
+	for ch := 0; ch < channels; ch++ {
+		state := n.channels[ch]
+		for i := 0; i < frames; i++ {
+			state.input = append(state.input, float64(samples[i*channels+ch]))
+		}
+
+		for len(state.input) >= n.frameSize {
+			n.processFrame(state)
+			state.input = state.input[n.hopSize:]
+		}
+
+		for i := 0; i < frames; i++ {
+			samples[i*channels+ch] = clip(state.output[i])
+		}
+		state.output = state.output[frames:]
+	}
+	return samples
+}
+
+func (n *NoiseSuppressor) processFrame(state *denoiseChannel) {
+	spectrum := make([]complex128, n.frameSize)
+	for i := range spectrum {
+		spectrum[i] = complex(state.input[i]*n.window[i], 0)
+	}
+	fft(spectrum, false)
+
+	for k := range state.noise {
+		power := real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+		if state.learned {
+			power = powerSmoothing*state.power[k] + (1-powerSmoothing)*power
+		}
+		state.power[k] = power
+
+		switch {
+		case !state.learned, power < state.noise[k]:
+			state.noise[k] = power
+		default:
+			state.noise[k] *= noiseTrackUp
+		}
+
+		gain := denoiseFloor
+		if power > 0 {
+			gain = math.Max(1-denoiseOverSubtraction*noiseBias*state.noise[k]/power, denoiseFloor)
+		}
+
+		spectrum[k] *= complex(gain, 0)
+		if k > 0 && k < n.frameSize/2 {
+			spectrum[n.frameSize-k] = cmplx.Conj(spectrum[k])
+		}
+	}
+	state.learned = true
+
+	fft(spectrum, true)
+	for i := range state.overlap {
+		state.overlap[i] += real(spectrum[i]) / float64(n.frameSize) * n.window[i]
+	}
+
+	state.output = append(state.output, state.overlap[:n.hopSize]...)
+	copy(state.overlap, state.overlap[n.hopSize:])
+	for i := n.frameSize - n.hopSize; i < n.frameSize; i++ {
+		state.overlap[i] = 0
+	}
+}
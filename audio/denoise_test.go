@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// whiteNoise returns seconds of gaussian noise at db dBFS RMS
+func whiteNoise(random *rand.Rand, rate int, db, seconds float64) []int32 {
+	rms := math.Pow(10, db/20)
+	samples := make([]int32, int(float64(rate)*seconds))
+	for i := range samples {
+		samples[i] = clip(random.NormFloat64() * rms * math.MaxInt32)
+	}
+	return samples
+}
+
+func TestNoiseSuppressor(t *testing.T) {
+	tests := []struct {
+		rate            int
+		toneDB, noiseDB float64
+		toneFreq        float64
+	}{
+		{rate: 8000, toneDB: -20, noiseDB: -40, toneFreq: 440},
+		{rate: 16000, toneDB: -20, noiseDB: -40, toneFreq: 1000},
+		{rate: 16000, toneDB: -30, noiseDB: -45, toneFreq: 2500},
+		{rate: 48000, toneDB: -20, noiseDB: -40, toneFreq: 1000},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%vHz at %vdB in %vdB noise at %v", test.toneFreq, test.toneDB, test.noiseDB, test.rate), func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			format := Format{SampleRate: test.rate, Channels: 1}
+
+			// noise alone for the suppressor to learn, then the tone over it
+			noise := whiteNoise(random, test.rate, test.noiseDB, 4)
+			tone := sine(test.rate, test.toneFreq, fromDB(test.toneDB), 2)
+			in := append([]int32(nil), noise...)
+			for i, s := range tone {
+				in[2*test.rate+i] = clip(float64(in[2*test.rate+i]) + float64(s))
+			}
+
+			out := processInChunks(NewNoiseSuppressor(test.rate), append([]int32(nil), in...), format)
+
+			// the noise floor drops where there is only noise
+			noiseOnly := test.rate / 2
+			inNoise, outNoise := rmsDB(in[noiseOnly:2*test.rate]), rmsDB(out[noiseOnly:2*test.rate])
+			if drop := inNoise - outNoise; drop < 10 {
+				t.Errorf("noise floor went from %.1fdB to %.1fdB, want it at least 10dB lower", inNoise, outNoise)
+			}
+
+			// the tone comes through at its level, with the noise around it lower
+			withTone := out[2*test.rate+test.rate/2:]
+			amplitude, snr := fitSine(withTone, test.rate, test.toneFreq)
+			if got := Decibels(amplitude / math.Sqrt2); math.Abs(got-test.toneDB) > 1 {
+				t.Errorf("tone came through at %.1fdB, want %vdB", got, test.toneDB)
+			}
+			_, inSNR := fitSine(in[2*test.rate+test.rate/2:], test.rate, test.toneFreq)
+			if snr < inSNR+6 {
+				t.Errorf("SNR went from %.1fdB to %.1fdB, want at least 6dB better", inSNR, snr)
+			}
+		})
+	}
+}
+
+func TestNoiseSuppressorLatency(t *testing.T) {
+	// output lags one analysis frame of the input and otherwise keeps up
+	n := NewNoiseSuppressor(16000)
+	format := Format{SampleRate: 16000, Channels: 2}
+	for i := 0; i < 10; i++ {
+		chunk := make([]int32, 2*160)
+		if got := len(n.Process(chunk, format)); got != len(chunk) {
+			t.Fatalf("chunk of %v samples came back as %v", len(chunk), got)
+		}
+	}
+}
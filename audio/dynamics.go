@@ -0,0 +1,169 @@
+package audio
+
+import "math"
+
+// smoothing returns the one pole coefficient reaching ~63% of a step in the given time
+func smoothing(rate int, seconds float64) float64 {
+	if seconds <= 0 || rate <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (seconds * float64(rate)))
+}
+
+// framePeak is the largest absolute value across the channels of one frame
+func framePeak(samples []int32, frame, channels int) float64 {
+	var peak float64
+	for ch := 0; ch < channels; ch++ {
+		peak = math.Max(peak, math.Abs(float64(samples[frame*channels+ch])/math.MaxInt32))
+	}
+	return peak
+}
+
+// applyGain scales one frame of every channel by the same gain
+func applyGain(samples []int32, frame, channels int, gain float64) {
+	for ch := 0; ch < channels; ch++ {
+		i := frame*channels + ch
+		samples[i] = clip(float64(samples[i]) * gain)
+	}
+}
+
+// NoiseGate silences the input while its level stays under a threshold, like
+// fan noise between sentences.  Channels are gated together.
+type NoiseGate struct {
+	threshold float64
+
+	envelopeAttack  float64
+	envelopeRelease float64
+	gainAttack      float64
+	gainRelease     float64
+	holdSamples     int
+
+	envelope float64
+	gain     float64
+	hold     int
+}
+
+// NewNoiseGate creates a gate closing below thresholdDB dBFS.
+func NewNoiseGate(rate int, thresholdDB float64) *NoiseGate {
+	return &NoiseGate{
+		threshold:       math.Pow(10, thresholdDB/20),
+		envelopeAttack:  smoothing(rate, 0.001),
+		envelopeRelease: smoothing(rate, 0.1),
+		gainAttack:      smoothing(rate, 0.002),
+		gainRelease:     smoothing(rate, 0.05),
+		holdSamples:     rate / 20,
+	}
+}
+
+// Process gates interleaved samples in place.
+func (g *NoiseGate) Process(samples []int32, format Format) []int32 {
+	channels := format.channels()
+	for frame := 0; frame < len(samples)/channels; frame++ {
+		peak := framePeak(samples, frame, channels)
+		if peak > g.envelope {
+			g.envelope = g.envelopeAttack*g.envelope + (1-g.envelopeAttack)*peak
+		} else {
+			g.envelope = g.envelopeRelease*g.envelope + (1-g.envelopeRelease)*peak
+		}
+
+		target := 1.0
+		if g.envelope < g.threshold {
+			if g.hold > 0 {
+				g.hold--
+			} else {
+				target = 0
+			}
+		} else {
+			g.hold = g.holdSamples
+		}
+
+		if target > g.gain {
+			g.gain = g.gainAttack*g.gain + (1-g.gainAttack)*target
+		} else {
+			g.gain = g.gainRelease*g.gain + (1-g.gainRelease)*target
+		}
+		applyGain(samples, frame, channels, g.gain)
+	}
+	return samples
+}
+
+const (
+	// agcFloor is the level under which the AGC holds its gain instead of boosting background noise
+	agcFloor = -55
+	// agcMaxGain bounds how far quiet mics are boosted or loud ones cut, in dB
+	agcMaxGain = 30
+)
+
+// AGC slowly adjusts gain so speech sits at a target loudness no matter how
+// close to the mic the speaker is or how sensitive the mic is.
+type AGC struct {
+	target float64
+
+	levelSmoothing float64
+	gainSmoothing  float64
+
+	meanSquare float64
+	gainDB     float64
+}
+
+// NewAGC creates an AGC aiming for targetDB dBFS RMS.
+func NewAGC(rate int, targetDB float64) *AGC {
+	return &AGC{
+		target:         targetDB,
+		levelSmoothing: smoothing(rate, 0.4),
+		gainSmoothing:  smoothing(rate, 1),
+	}
+}
+
+// Process applies the automatic gain to interleaved samples in place.
+func (a *AGC) Process(samples []int32, format Format) []int32 {
+	channels := format.channels()
+	for frame := 0; frame < len(samples)/channels; frame++ {
+		peak := framePeak(samples, frame, channels)
+		a.meanSquare = a.levelSmoothing*a.meanSquare + (1-a.levelSmoothing)*peak*peak
+
+		level := Decibels(math.Sqrt(a.meanSquare))
+		if level > agcFloor {
+			desired := math.Max(math.Min(a.target-level, agcMaxGain), -agcMaxGain)
+			a.gainDB = a.gainSmoothing*a.gainDB + (1-a.gainSmoothing)*desired
+		}
+		applyGain(samples, frame, channels, math.Pow(10, a.gainDB/20))
+	}
+	return samples
+}
+
+// Limiter keeps peaks under a ceiling, catching what the AGC and gain stages
+// overshoot before it clips.  Gain drops instantly and recovers over the release.
+type Limiter struct {
+	ceiling float64
+	release float64
+	gain    float64
+}
+
+// NewLimiter creates a limiter holding peaks under ceilingDB dBFS.
+func NewLimiter(rate int, ceilingDB float64) *Limiter {
+	return &Limiter{
+		ceiling: math.Pow(10, ceilingDB/20),
+		release: smoothing(rate, 0.05),
+		gain:    1,
+	}
+}
+
+// Process limits interleaved samples in place.
+func (l *Limiter) Process(samples []int32, format Format) []int32 {
+	channels := format.channels()
+	for frame := 0; frame < len(samples)/channels; frame++ {
+		required := 1.0
+		if peak := framePeak(samples, frame, channels); peak > l.ceiling {
+			required = l.ceiling / peak
+		}
+
+		if required < l.gain {
+			l.gain = required
+		} else {
+			l.gain = l.release*l.gain + (1-l.release)*required
+		}
+		applyGain(samples, frame, channels, l.gain)
+	}
+	return samples
+}
@@ -0,0 +1,182 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// rmsDB is the RMS level of samples in dBFS
+func rmsDB(samples []int32) float64 {
+	var sum float64
+	for _, s := range samples {
+		v := float64(s) / math.MaxInt32
+		sum += v * v
+	}
+	return Decibels(math.Sqrt(sum / float64(len(samples))))
+}
+
+// processInChunks runs samples through p in 10ms chunks, as the capture path does
+func processInChunks(p Processor, samples []int32, format Format) []int32 {
+	chunk := format.SampleRate / 100 * format.channels()
+	out := make([]int32, 0, len(samples))
+	for len(samples) > 0 {
+		n := chunk
+		if n > len(samples) {
+			n = len(samples)
+		}
+		out = append(out, p.Process(samples[:n], format)...)
+		samples = samples[n:]
+	}
+	return out
+}
+
+// fromDB is the amplitude of a sine at db dBFS RMS
+func fromDB(db float64) float64 {
+	return math.Sqrt2 * math.Pow(10, db/20)
+}
+
+func TestNoiseGate(t *testing.T) {
+	const rate = 16000
+	format := Format{SampleRate: rate, Channels: 1}
+	tests := []struct {
+		name    string
+		inputDB float64
+		// wantDB is the output level once the gate settles, or below it when closed
+		wantDB float64
+		open   bool
+	}{
+		{"well under the threshold", -60, -100, false},
+		{"just under the threshold", -45, -100, false},
+		{"just over the threshold", -35, -35, true},
+		{"well over the threshold", -10, -10, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gate := NewNoiseGate(rate, -40)
+			out := processInChunks(gate, sine(rate, 440, fromDB(test.inputDB), 1), format)
+			settled := out[rate/2:]
+			got := rmsDB(settled)
+			if test.open && math.Abs(got-test.wantDB) > 0.5 {
+				t.Errorf("open gate passed %.1fdB, want %vdB", got, test.wantDB)
+			}
+			if !test.open && got > test.wantDB {
+				t.Errorf("closed gate passed %.1fdB, want under %vdB", got, test.wantDB)
+			}
+		})
+	}
+
+	t.Run("opens and closes with the input", func(t *testing.T) {
+		gate := NewNoiseGate(rate, -40)
+		loud := sine(rate, 440, fromDB(-20), 1)
+		quiet := sine(rate, 440, fromDB(-60), 1)
+		var in []int32
+		in = append(in, quiet...)
+		in = append(in, loud...)
+		in = append(in, quiet...)
+		out := processInChunks(gate, in, format)
+
+		// each part is measured past the gate's attack, and its release and hold
+		part := len(quiet)
+		if got := rmsDB(out[part/2 : part]); got > -100 {
+			t.Errorf("gate open at %.1fdB before the loud part", got)
+		}
+		if got := rmsDB(out[part+part/10 : 2*part]); math.Abs(got+20) > 0.5 {
+			t.Errorf("gate passed %.1fdB of the loud part, want -20dB", got)
+		}
+		if got := rmsDB(out[2*part+part/2:]); got > -100 {
+			t.Errorf("gate open at %.1fdB after the loud part", got)
+		}
+	})
+}
+
+func TestAGC(t *testing.T) {
+	const rate = 8000
+	format := Format{SampleRate: rate, Channels: 1}
+	tests := []struct {
+		inputDB, targetDB, wantDB float64
+	}{
+		{-40, -20, -20},
+		{-30, -20, -20},
+		{-20, -20, -20},
+		{-6, -20, -20},
+		{-35, -12, -12},
+		// boosts no more than agcMaxGain
+		{-50, -12, -50 + agcMaxGain},
+		// under agcFloor is background noise, left alone
+		{-60, -20, -60},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%vdB to %vdB", test.inputDB, test.targetDB), func(t *testing.T) {
+			agc := NewAGC(rate, test.targetDB)
+			out := processInChunks(agc, sine(rate, 440, fromDB(test.inputDB), 10), format)
+			if got := rmsDB(out[len(out)-rate:]); math.Abs(got-test.wantDB) > 1 {
+				t.Errorf("converged to %.1fdB, want %vdB", got, test.wantDB)
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	const rate = 16000
+	tests := []struct {
+		name      string
+		ceilingDB float64
+		input     func() []int32
+		channels  int
+	}{
+		{"full scale sine", -1, func() []int32 { return sine(rate, 440, 1, 0.5) }, 1},
+		{"sine to -6dB", -6, func() []int32 { return sine(rate, 1000, 0.9, 0.5) }, 1},
+		{"square wave", -3, func() []int32 {
+			samples := sine(rate, 100, 1, 0.5)
+			for i, s := range samples {
+				samples[i] = math.MaxInt32
+				if s < 0 {
+					samples[i] = math.MinInt32
+				}
+			}
+			return samples
+		}, 1},
+		{"bursts after silence", -1, func() []int32 {
+			samples := make([]int32, rate)
+			for i := rate / 4; i < rate; i += rate / 4 {
+				copy(samples[i:], sine(rate, 2000, 1, 0.01))
+			}
+			return samples
+		}, 1},
+		{"one loud channel of two", -2, func() []int32 {
+			left := sine(rate, 440, 0.3, 0.5)
+			right := sine(rate, 660, 1, 0.5)
+			stereo := make([]int32, 0, 2*len(left))
+			for i := range left {
+				stereo = append(stereo, left[i], right[i])
+			}
+			return stereo
+		}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format := Format{SampleRate: rate, Channels: test.channels}
+			limiter := NewLimiter(rate, test.ceilingDB)
+			out := processInChunks(limiter, test.input(), format)
+			// a sample may round a step over the ceiling
+			ceiling := math.Pow(10, test.ceilingDB/20)*math.MaxInt32 + 1
+			for i, s := range out {
+				if math.Abs(float64(s)) > ceiling {
+					t.Fatalf("sample %v is %.2fdBFS, over the %vdB ceiling",
+						i, Decibels(math.Abs(float64(s))/math.MaxInt32), test.ceilingDB)
+				}
+			}
+		})
+	}
+
+	t.Run("under the ceiling is left alone", func(t *testing.T) {
+		in := sine(rate, 440, 0.5, 0.2)
+		out := NewLimiter(rate, -1).Process(append([]int32(nil), in...), Format{SampleRate: rate})
+		for i := range in {
+			if out[i] != in[i] {
+				t.Fatalf("sample %v changed from %v to %v", i, in[i], out[i])
+			}
+		}
+	})
+}
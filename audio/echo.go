@@ -0,0 +1,136 @@
+package audio
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// echoStepSize is the NLMS adaptation rate, smaller converges slower but steadier
+	echoStepSize = 0.3
+	// echoRegularization keeps the step bounded while the reference is near silent
+	echoRegularization = 1e-6
+	// doubleTalkRatio freezes adaptation when the mic is this loud relative to
+	// the recent reference peak, the Geigel detector, so the local talker does
+	// not get cancelled out
+	doubleTalkRatio = 0.5
+)
+
+// EchoCanceller removes the far end audio played on the speaker from the mic
+// input with a normalized least mean squares adaptive filter.  Reference is
+// fed from the playback path and Cancel from the capture path, both mono at
+// the capture sample rate, and they may run on different goroutines.
+type EchoCanceller struct {
+	mu sync.Mutex
+
+	weights []float64
+	// history is the tail of reference samples already lined up with the mic
+	history []float64
+	// pending holds reference samples played but not yet matched with mic input
+	pending []float64
+}
+
+// NewEchoCanceller creates an EchoCanceller modelling an echo path of taps samples.
+func NewEchoCanceller(taps int) *EchoCanceller {
+	return &EchoCanceller{
+		weights: make([]float64, taps),
+		history: make([]float64, taps-1),
+	}
+}
+
+// Reference queues mono samples as they are sent to the speaker.
+func (e *EchoCanceller) Reference(far []int32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range far {
+		e.pending = append(e.pending, float64(s)/math.MaxInt32)
+	}
+
+	// while the mic is not being read, keep only what could still echo into it
+	limit := len(e.weights)
+	if len(far) > limit {
+		limit = len(far)
+	}
+	if len(e.pending) > limit {
+		e.pending = append(e.pending[:0], e.pending[len(e.pending)-limit:]...)
+	}
+}
+
+// Reset drops the reference queued so far, for when the mic starts being read
+// again after audio has played without it.
+func (e *EchoCanceller) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pending = e.pending[:0]
+}
+
+// Cancel subtracts the estimated echo from interleaved mic samples in place.
+// The filter adapts on the channels' average and the same estimate is removed
+// from every channel.
+func (e *EchoCanceller) Cancel(near []int32, channels int) {
+	if channels < 1 {
+		channels = 1
+	}
+	frames := len(near) / channels
+	taps := len(e.weights)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// line up a reference sample with every mic frame, silence if playback is behind
+	buf := make([]float64, len(e.history)+frames)
+	copy(buf, e.history)
+	n := copy(buf[len(e.history):], e.pending)
+	e.pending = e.pending[n:]
+
+	var energy, peak float64
+	for _, x := range buf[:taps-1] {
+		energy += x * x
+	}
+
+	for i := 0; i < frames; i++ {
+		window := buf[i : i+taps]
+		newest := window[taps-1]
+		energy += newest * newest
+		peak = math.Max(peak*0.9995, math.Abs(newest))
+
+		var estimate float64
+		for k, w := range e.weights {
+			estimate += w * window[taps-1-k]
+		}
+
+		var near64 float64
+		for ch := 0; ch < channels; ch++ {
+			near64 += float64(near[i*channels+ch])
+		}
+		near64 /= float64(channels) * math.MaxInt32
+
+		residual := near64 - estimate
+		if math.Abs(near64) < doubleTalkRatio*peak {
+			step := echoStepSize * residual / (energy + echoRegularization)
+			for k := range e.weights {
+				e.weights[k] += step * window[taps-1-k]
+			}
+		}
+
+		correction := estimate * math.MaxInt32
+		for ch := 0; ch < channels; ch++ {
+			near[i*channels+ch] = clip(float64(near[i*channels+ch]) - correction)
+		}
+
+		oldest := window[0]
+		energy = math.Max(energy-oldest*oldest, 0)
+	}
+
+	copy(e.history, buf[frames:])
+}
+
+// ApplyGain scales samples in place by a gain in dB.
+func ApplyGain(samples []int32, db float64) {
+	gain := math.Pow(10, db/20)
+	for i, s := range samples {
+		samples[i] = clip(float64(s) * gain)
+	}
+}
@@ -0,0 +1,243 @@
+// Package audio holds the pure Go audio helpers shared by the intercom binaries.
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+// Clip is a decoded audio file.  Samples are interleaved and scaled to the
+// full int32 range, matching the portaudio streams used by the client.
+type Clip struct {
+	SampleRate int
+	Channels   int
+	Samples    []int32
+}
+
+// ReadFile decodes a PCM WAV or AIFF file.
+func ReadFile(path string) (*Clip, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(bufio.NewReader(f))
+}
+
+// Decode reads a PCM WAV or AIFF stream, detected by its header.
+func Decode(r io.Reader) (*Clip, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE":
+		return decodeWAV(r)
+	case string(header[0:4]) == "FORM" && (string(header[8:12]) == "AIFF" || string(header[8:12]) == "AIFC"):
+		return decodeAIFF(r, string(header[8:12]) == "AIFC")
+	}
+	return nil, errors.New("audio: unsupported file format")
+}
+
+func decodeWAV(r io.Reader) (*Clip, error) {
+	var (
+		clip          Clip
+		format        uint16
+		bitsPerSample int
+	)
+
+	for {
+		id, size, err := readChunkHeader(r, binary.LittleEndian)
+		if err != nil {
+			return nil, err
+		}
+
+		switch id {
+		case "fmt ":
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+			if size < 16 {
+				return nil, errors.New("audio: short wav fmt chunk")
+			}
+			format = binary.LittleEndian.Uint16(buf[0:2])
+			clip.Channels = int(binary.LittleEndian.Uint16(buf[2:4]))
+			clip.SampleRate = int(binary.LittleEndian.Uint32(buf[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(buf[14:16]))
+			if format == 0xfffe && size >= 26 {
+				// WAVE_FORMAT_EXTENSIBLE, the real format is the first two bytes of the sub-format GUID
+				format = binary.LittleEndian.Uint16(buf[24:26])
+			}
+		case "data":
+			if clip.Channels == 0 {
+				return nil, errors.New("audio: wav data chunk before fmt chunk")
+			}
+			buf := make([]byte, size)
+			n, err := io.ReadFull(r, buf)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			clip.Samples, err = decodePCM(buf[:n], binary.LittleEndian, bitsPerSample, format == 3, bitsPerSample == 8)
+			if err != nil {
+				return nil, err
+			}
+			return &clip, nil
+		default:
+			if err := skip(r, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func decodeAIFF(r io.Reader, compressed bool) (*Clip, error) {
+	var (
+		clip          Clip
+		bitsPerSample int
+		float         bool
+	)
+
+	for {
+		id, size, err := readChunkHeader(r, binary.BigEndian)
+		if err != nil {
+			return nil, err
+		}
+
+		switch id {
+		case "COMM":
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+			if size < 18 {
+				return nil, errors.New("audio: short aiff COMM chunk")
+			}
+			clip.Channels = int(binary.BigEndian.Uint16(buf[0:2]))
+			bitsPerSample = int(binary.BigEndian.Uint16(buf[6:8]))
+			clip.SampleRate = int(extendedToFloat(buf[8:18]))
+			if compressed && size >= 22 {
+				switch string(buf[18:22]) {
+				case "NONE", "twos":
+				case "fl32", "FL32":
+					float = true
+				default:
+					return nil, fmt.Errorf("audio: unsupported aifc compression %q", buf[18:22])
+				}
+			}
+		case "SSND":
+			if clip.Channels == 0 {
+				return nil, errors.New("audio: aiff SSND chunk before COMM chunk")
+			}
+			var offset [8]byte
+			if _, err := io.ReadFull(r, offset[:]); err != nil {
+				return nil, err
+			}
+			if err := skip(r, int64(binary.BigEndian.Uint32(offset[0:4]))); err != nil {
+				return nil, err
+			}
+			buf := make([]byte, size-8)
+			n, err := io.ReadFull(r, buf)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			clip.Samples, err = decodePCM(buf[:n], binary.BigEndian, bitsPerSample, float, false)
+			if err != nil {
+				return nil, err
+			}
+			return &clip, nil
+		default:
+			if err := skip(r, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func readChunkHeader(r io.Reader, order binary.ByteOrder) (string, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			err = errors.New("audio: no sample data found")
+		}
+		return "", 0, err
+	}
+	size := int64(order.Uint32(header[4:8]))
+	if size < 0 || size > math.MaxInt32 {
+		return "", 0, errors.New("audio: invalid chunk size")
+	}
+	return string(header[0:4]), size, nil
+}
+
+// skip discards size bytes plus the pad byte both formats add to odd sized chunks.
+func skip(r io.Reader, size int64) error {
+	_, err := io.CopyN(ioutil.Discard, r, size+size%2)
+	return err
+}
+
+// decodePCM scales samples of any supported width up to the full int32 range.
+func decodePCM(buf []byte, order binary.ByteOrder, bits int, float, unsigned bool) ([]int32, error) {
+	width := bits / 8
+	if width == 0 || bits%8 != 0 || width > 4 || (float && width != 4) {
+		return nil, fmt.Errorf("audio: unsupported sample size %d", bits)
+	}
+
+	samples := make([]int32, len(buf)/width)
+	for i := range samples {
+		b := buf[i*width : (i+1)*width]
+		switch {
+		case float:
+			v := float64(math.Float32frombits(order.Uint32(b)))
+			samples[i] = FloatToSample(v)
+		case width == 1 && unsigned:
+			samples[i] = (int32(b[0]) - 128) << 24
+		case width == 1:
+			samples[i] = int32(int8(b[0])) << 24
+		case width == 2:
+			samples[i] = int32(int16(order.Uint16(b))) << 16
+		case width == 3:
+			if order == binary.BigEndian {
+				samples[i] = int32(b[0])<<24 | int32(b[1])<<16 | int32(b[2])<<8
+			} else {
+				samples[i] = int32(b[2])<<24 | int32(b[1])<<16 | int32(b[0])<<8
+			}
+		case width == 4:
+			samples[i] = int32(order.Uint32(b))
+		}
+	}
+	return samples, nil
+}
+
+// extendedToFloat converts the 80-bit IEEE 754 extended float AIFF uses for its sample rate.
+func extendedToFloat(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if b[0]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// FloatToSample converts a [-1, 1] float to a clipped full scale int32 sample.
+func FloatToSample(v float64) int32 {
+	v *= math.MaxInt32
+	if v >= math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if v <= math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(v)
+}
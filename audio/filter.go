@@ -0,0 +1,72 @@
+package audio
+
+import "math"
+
+// butterworthQ gives the maximally flat pass band
+const butterworthQ = 1 / math.Sqrt2
+
+// Gain scales every sample by a fixed amount.
+type Gain struct {
+	gain float64
+}
+
+// NewGain creates a Gain of db decibels.
+func NewGain(db float64) *Gain {
+	return &Gain{gain: math.Pow(10, db/20)}
+}
+
+// Process applies the gain to samples in place.
+func (g *Gain) Process(samples []int32, format Format) []int32 {
+	for i, s := range samples {
+		samples[i] = clip(float64(s) * g.gain)
+	}
+	return samples
+}
+
+// Biquad is a second order IIR filter, using the RBJ audio EQ cookbook designs,
+// with separate state for each channel.
+type Biquad struct {
+	b0, b1, b2, a1, a2 float64
+
+	// state holds x[n-1], x[n-2], y[n-1], y[n-2] per channel
+	state [][4]float64
+}
+
+func newBiquad(b0, b1, b2, a0, a1, a2 float64) *Biquad {
+	return &Biquad{b0: b0 / a0, b1: b1 / a0, b2: b2 / a0, a1: a1 / a0, a2: a2 / a0}
+}
+
+// NewHighPass creates a Butterworth high-pass filter, useful for cutting rumble and hum.
+func NewHighPass(rate int, cutoff float64) *Biquad {
+	w0 := 2 * math.Pi * cutoff / float64(rate)
+	alpha := math.Sin(w0) / (2 * butterworthQ)
+	cos := math.Cos(w0)
+	return newBiquad((1+cos)/2, -(1 + cos), (1+cos)/2, 1+alpha, -2*cos, 1-alpha)
+}
+
+// NewEQ creates a peaking equalizer boosting or cutting gainDB around center, q sets the width.
+func NewEQ(rate int, center, gainDB, q float64) *Biquad {
+	a := math.Pow(10, gainDB/40)
+	w0 := 2 * math.Pi * center / float64(rate)
+	alpha := math.Sin(w0) / (2 * q)
+	cos := math.Cos(w0)
+	return newBiquad(1+alpha*a, -2*cos, 1-alpha*a, 1+alpha/a, -2*cos, 1-alpha/a)
+}
+
+// Process filters interleaved samples in place.
+func (f *Biquad) Process(samples []int32, format Format) []int32 {
+	channels := format.channels()
+	for len(f.state) < channels {
+		f.state = append(f.state, [4]float64{})
+	}
+
+	for i, s := range samples {
+		state := &f.state[i%channels]
+		x := float64(s)
+		y := f.b0*x + f.b1*state[0] + f.b2*state[1] - f.a1*state[2] - f.a2*state[3]
+		state[1], state[0] = state[0], x
+		state[3], state[2] = state[2], y
+		samples[i] = clip(y)
+	}
+	return samples
+}
@@ -0,0 +1,120 @@
+package audio
+
+// G.711 is the 8 bit companded audio of telephones, at 8000 Hz: mu-law in
+// North America and Japan, A-law elsewhere.
+
+// G711SampleRate is the sample rate of G.711 audio.
+const G711SampleRate = 8000
+
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+// EncodeULaw compands samples to G.711 mu-law, one byte per sample.
+func EncodeULaw(samples []int32) []byte {
+	out := make([]byte, len(samples))
+	for i, s := range samples {
+		out[i] = ulawByte(int16(s >> 16))
+	}
+	return out
+}
+
+// DecodeULaw expands G.711 mu-law to samples.
+func DecodeULaw(data []byte) []int32 {
+	out := make([]int32, len(data))
+	for i, b := range data {
+		out[i] = int32(ulawSample(b)) << 16
+	}
+	return out
+}
+
+// EncodeALaw compands samples to G.711 A-law, one byte per sample.
+func EncodeALaw(samples []int32) []byte {
+	out := make([]byte, len(samples))
+	for i, s := range samples {
+		out[i] = alawByte(int16(s >> 16))
+	}
+	return out
+}
+
+// DecodeALaw expands G.711 A-law to samples.
+func DecodeALaw(data []byte) []int32 {
+	out := make([]int32, len(data))
+	for i, b := range data {
+		out[i] = int32(alawSample(b)) << 16
+	}
+	return out
+}
+
+func ulawByte(pcm int16) byte {
+	s := int(pcm)
+	sign := 0
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > ulawClip {
+		s = ulawClip
+	}
+	s += ulawBias
+
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> uint(exponent+3)) & 0x0f
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+func ulawSample(b byte) int16 {
+	b = ^b
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0f)
+	s := (mantissa<<3 + ulawBias) << uint(exponent)
+	s -= ulawBias
+	if b&0x80 != 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
+
+func alawByte(pcm int16) byte {
+	s := int(pcm) >> 3
+	sign := 0x80
+	if s < 0 {
+		s = -s - 1
+		sign = 0
+	}
+
+	var b int
+	switch {
+	case s < 32:
+		b = s >> 1
+	default:
+		exponent := 1
+		for limit := 64; s >= limit && exponent < 7; limit <<= 1 {
+			exponent++
+		}
+		if s > 0xfff {
+			s = 0xfff
+		}
+		b = exponent<<4 | (s>>uint(exponent))&0x0f
+	}
+	return byte(sign|b) ^ 0x55
+}
+
+func alawSample(b byte) int16 {
+	b ^= 0x55
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0f)
+
+	s := mantissa<<4 + 8
+	if exponent > 0 {
+		s = (s + 0x100) << uint(exponent-1)
+	}
+	if b&0x80 == 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+var g711Laws = []struct {
+	name   string
+	encode func([]int32) []byte
+	decode func([]byte) []int32
+	// silence is what zero encodes to, peak the loudest sample decoded
+	silence byte
+	peak    int16
+}{
+	{"mu-law", EncodeULaw, DecodeULaw, 0xff, 32124},
+	{"A-law", EncodeALaw, DecodeALaw, 0xd5, 32256},
+}
+
+func TestG711Codes(t *testing.T) {
+	for _, law := range g711Laws {
+		t.Run(law.name, func(t *testing.T) {
+			if got := law.encode([]int32{0})[0]; got != law.silence {
+				t.Errorf("silence encoded as %#x, want %#x", got, law.silence)
+			}
+
+			// every code decodes to a sample that encodes back to it, but for
+			// mu-law's negative zero which comes back as zero
+			codes := make([]byte, 256)
+			for i := range codes {
+				codes[i] = byte(i)
+			}
+			samples := law.decode(codes)
+			again := law.encode(samples)
+			peak := int32(0)
+			for i, code := range codes {
+				if s := samples[i] >> 16; s > peak {
+					peak = s
+				}
+				if again[i] == code || (law.name == "mu-law" && code == 0x7f && again[i] == 0xff) {
+					continue
+				}
+				t.Errorf("%#x decoded to %v, encoded back to %#x", code, samples[i]>>16, again[i])
+			}
+			if peak != int32(law.peak) {
+				t.Errorf("loudest code decoded to %v, want %v", peak, law.peak)
+			}
+		})
+	}
+}
+
+func TestG711RoundTrip(t *testing.T) {
+	// every 16 bit sample comes back within half a step of its segment, the
+	// step doubling with each segment so never more than 1/32 of the sample
+	samples := make([]int32, 0, 1<<16)
+	for s := math.MinInt16; s <= math.MaxInt16; s++ {
+		samples = append(samples, int32(s)<<16)
+	}
+	for _, law := range g711Laws {
+		t.Run(law.name, func(t *testing.T) {
+			decoded := law.decode(law.encode(samples))
+			for i, s := range samples {
+				in, out := s>>16, decoded[i]>>16
+				if in > int32(law.peak) || in < -int32(law.peak) {
+					// over the loudest code, clipped to it or to the step under it
+					if abs32(out) < int32(law.peak)-1024 {
+						t.Fatalf("%v came back as %v, want it clipped", in, out)
+					}
+					continue
+				}
+				if err := abs32(out - in); err > abs32(in)/32+16 {
+					t.Fatalf("%v came back as %v", in, out)
+				}
+			}
+		})
+	}
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,26 @@
+package audio
+
+import "math"
+
+// Levels returns the RMS and peak of samples relative to full scale, 0 to 1.
+func Levels(samples []int32) (rms, peak float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, s := range samples {
+		v := float64(s) / math.MaxInt32
+		sum += v * v
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return math.Sqrt(sum / float64(len(samples))), peak
+}
+
+// Decibels converts a linear level relative to full scale to dBFS.
+func Decibels(level float64) float64 {
+	if level <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(level)
+}
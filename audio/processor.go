@@ -0,0 +1,180 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format describes the layout of a frame of samples.
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+func (f Format) channels() int {
+	if f.Channels < 1 {
+		return 1
+	}
+	return f.Channels
+}
+
+// Processor transforms a frame of interleaved samples.  Processors may work
+// in place and return frame, and keep state between frames of one stream.
+type Processor interface {
+	Process(frame []int32, format Format) []int32
+}
+
+// Pipeline chains processors, each fed the output of the one before.
+type Pipeline []Processor
+
+// Process runs the frame through every processor in order.
+func (p Pipeline) Process(frame []int32, format Format) []int32 {
+	for _, processor := range p {
+		frame = processor.Process(frame, format)
+	}
+	return frame
+}
+
+// processorFactory builds a processor from the arguments following its name in a pipeline spec
+type processorFactory struct {
+	defaults []float64
+	// check, if set, rejects arguments the processor cannot work with
+	check  func(args []float64, format Format) error
+	create func(args []float64, format Format) Processor
+}
+
+// checkFrequency rejects a filter frequency outside 0 to the Nyquist
+// frequency, where the biquad designs are unstable
+func checkFrequency(name string, hz float64, format Format) error {
+	nyquist := float64(format.SampleRate) / 2
+	if hz <= 0 || hz >= nyquist {
+		return fmt.Errorf("%v %vHz is not between 0 and %vHz", name, hz, nyquist)
+	}
+	return nil
+}
+
+// checkLevel rejects a level above full scale, which samples never reach
+func checkLevel(name string, dbfs float64) error {
+	if dbfs > 0 {
+		return fmt.Errorf("%v %vdBFS is above full scale", name, dbfs)
+	}
+	return nil
+}
+
+var processorFactories = map[string]processorFactory{
+	"gain": {
+		defaults: []float64{0},
+		create: func(args []float64, format Format) Processor {
+			return NewGain(args[0])
+		},
+	},
+	"highpass": {
+		defaults: []float64{100},
+		check: func(args []float64, format Format) error {
+			return checkFrequency("cutoff", args[0], format)
+		},
+		create: func(args []float64, format Format) Processor {
+			return NewHighPass(format.SampleRate, args[0])
+		},
+	},
+	"eq": {
+		defaults: []float64{1000, 0, 1},
+		check: func(args []float64, format Format) error {
+			if args[2] <= 0 {
+				return fmt.Errorf("q %v is not above 0", args[2])
+			}
+			return checkFrequency("center", args[0], format)
+		},
+		create: func(args []float64, format Format) Processor {
+			return NewEQ(format.SampleRate, args[0], args[1], args[2])
+		},
+	},
+	"denoise": {
+		create: func(args []float64, format Format) Processor {
+			return NewNoiseSuppressor(format.SampleRate)
+		},
+	},
+	"gate": {
+		defaults: []float64{-50},
+		check: func(args []float64, format Format) error {
+			return checkLevel("threshold", args[0])
+		},
+		create: func(args []float64, format Format) Processor {
+			return NewNoiseGate(format.SampleRate, args[0])
+		},
+	},
+	"agc": {
+		defaults: []float64{-20},
+		check: func(args []float64, format Format) error {
+			return checkLevel("target", args[0])
+		},
+		create: func(args []float64, format Format) Processor {
+			return NewAGC(format.SampleRate, args[0])
+		},
+	},
+	"limit": {
+		defaults: []float64{-1},
+		check: func(args []float64, format Format) error {
+			return checkLevel("ceiling", args[0])
+		},
+		create: func(args []float64, format Format) Processor {
+			return NewLimiter(format.SampleRate, args[0])
+		},
+	},
+}
+
+// ParsePipeline builds a Pipeline for streams of the given format from a comma
+// separated list of processors, each optionally followed by colon separated
+// arguments, e.g. "highpass:80,denoise,gate:-50,agc:-20,limit:-1".  Omitted
+// arguments take their defaults:
+//
+//	gain:dB
+//	highpass:cutoffHz
+//	eq:centerHz:gainDB:q
+//	denoise
+//	gate:thresholdDBFS
+//	agc:targetDBFS
+//	limit:ceilingDBFS
+//
+// Filter frequencies have to be between 0 and the Nyquist frequency, q above
+// 0 and levels at most 0dBFS.
+func ParsePipeline(spec string, format Format) (Pipeline, error) {
+	var pipeline Pipeline
+	for _, stage := range strings.Split(spec, ",") {
+		stage = strings.TrimSpace(stage)
+		if stage == "" {
+			continue
+		}
+
+		fields := strings.Split(stage, ":")
+		factory, ok := processorFactories[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("audio: unknown processor %q", fields[0])
+		}
+		if len(fields)-1 > len(factory.defaults) {
+			return nil, fmt.Errorf("audio: too many arguments for %q", fields[0])
+		}
+
+		args := append([]float64(nil), factory.defaults...)
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseFloat(field, 64)
+			if err == nil && (math.IsNaN(value) || math.IsInf(value, 0)) {
+				err = fmt.Errorf("%v is not a number", field)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("audio: invalid argument for %q: %v", fields[0], err)
+			}
+			args[i] = value
+		}
+		if factory.check != nil {
+			if err := factory.check(args, format); err != nil {
+				return nil, fmt.Errorf("audio: invalid argument for %q: %v", fields[0], err)
+			}
+		}
+
+		pipeline = append(pipeline, factory.create(args, format))
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParsePipeline(t *testing.T) {
+	format := Format{SampleRate: 16000, Channels: 1}
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"", nil},
+		{"highpass:80,denoise,gate:-50,agc:-20,limit:-1",
+			[]string{"*audio.Biquad", "*audio.NoiseSuppressor", "*audio.NoiseGate", "*audio.AGC", "*audio.Limiter"}},
+		{" eq:3000:4:1 , gain:-6 ,", []string{"*audio.Biquad", "*audio.Gain"}},
+		{"eq,highpass", []string{"*audio.Biquad", "*audio.Biquad"}},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			pipeline, err := ParsePipeline(test.spec, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, processor := range pipeline {
+				got = append(got, fmt.Sprintf("%T", processor))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("processors %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePipelineInvalid(t *testing.T) {
+	format := Format{SampleRate: 16000, Channels: 1}
+	for _, spec := range []string{
+		"reverb",
+		"gain:1:2",
+		"gain:loud",
+		"gain:NaN",
+		"gain:Inf",
+		"highpass:0",
+		"highpass:-80",
+		"highpass:8000",
+		"highpass:20000",
+		"eq:1000:0:0",
+		"eq:1000:0:-1",
+		"eq:0",
+		"eq:9000",
+		"gate:3",
+		"agc:1",
+		"limit:0.5",
+	} {
+		if _, err := ParsePipeline(spec, format); err == nil {
+			t.Errorf("%q parsed", spec)
+		}
+	}
+	// filters need a sample rate to place their frequency in
+	if _, err := ParsePipeline("highpass", Format{}); err == nil {
+		t.Error("highpass parsed without a sample rate")
+	}
+}
+
+// gainAt is the gain in dB f gives a tone at freq Hz once settled
+func gainAt(f *Biquad, rate int, freq float64) float64 {
+	const amplitude = 0.25
+	out := f.Process(sine(rate, freq, amplitude, 0.5), Format{SampleRate: rate, Channels: 1})
+	got, _ := fitSine(out[len(out)/2:], rate, freq)
+	return 20 * math.Log10(got/amplitude)
+}
+
+func TestHighPass(t *testing.T) {
+	const rate, cutoff = 16000, 200
+	tests := []struct {
+		freq   float64
+		wantDB float64
+	}{
+		// a second order Butterworth falls 12dB an octave, 3dB down at the cutoff
+		{cutoff / 4, -24},
+		{cutoff, -3},
+		{cutoff * 16, 0},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.freq), func(t *testing.T) {
+			if got := gainAt(NewHighPass(rate, cutoff), rate, test.freq); math.Abs(got-test.wantDB) > 0.5 {
+				t.Errorf("gain %.2fdB, want %vdB", got, test.wantDB)
+			}
+		})
+	}
+}
+
+func TestEQ(t *testing.T) {
+	const rate, center = 16000, 1000
+	tests := []struct {
+		gainDB float64
+		freq   float64
+		wantDB float64
+	}{
+		{6, center, 6},
+		{-6, center, -6},
+		{6, center * 6, 0},
+		{0, center, 0},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.gainDB, "dB at ", test.freq), func(t *testing.T) {
+			if got := gainAt(NewEQ(rate, center, test.gainDB, 1), rate, test.freq); math.Abs(got-test.wantDB) > 0.5 {
+				t.Errorf("gain %.2fdB, want %vdB", got, test.wantDB)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package audio
+
+// Remix converts interleaved samples between channel layouts.  Mono is copied
+// to every output channel, anything mixed down to fewer channels is averaged,
+// and extra output channels repeat the input channels in order, so stereo to
+// quad plays left, right, left, right.
+func Remix(samples []int32, from, to int) []int32 {
+	if from < 1 {
+		from = 1
+	}
+	if to < 1 {
+		to = 1
+	}
+	if from == to {
+		return samples
+	}
+
+	frames := len(samples) / from
+	out := make([]int32, frames*to)
+
+	if from < to {
+		for i := 0; i < frames; i++ {
+			for ch := 0; ch < to; ch++ {
+				out[i*to+ch] = samples[i*from+ch%from]
+			}
+		}
+		return out
+	}
+
+	// each output channel averages the input channels that fold onto it
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < to; ch++ {
+			var sum int64
+			var count int64
+			for in := ch; in < from; in += to {
+				sum += int64(samples[i*from+in])
+				count++
+			}
+			out[i*to+ch] = int32(sum / count)
+		}
+	}
+	return out
+}
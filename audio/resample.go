@@ -0,0 +1,197 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// MinSampleRate and MaxSampleRate bound the rates a Resampler converts
+	// between.  Rates come from other stations, and the filter grows with
+	// the ratio between them.
+	MinSampleRate = 8000
+	MaxSampleRate = 192000
+
+	// resampleTaps is the filter length per polyphase branch, multiplied by
+	// the decimation ratio when downsampling so the narrower cutoff is as steep
+	resampleTaps = 32
+	// resampleKaiserBeta trades transition width for stop band attenuation (~80dB)
+	resampleKaiserBeta = 8.0
+	// resampleRolloff places the cutoff just under the lower Nyquist frequency
+	resampleRolloff = 0.95
+)
+
+// Resampler converts a stream of interleaved samples between two sample rates
+// using a rational polyphase windowed-sinc filter.  It keeps the tail of the
+// previous call so consecutive chunks of the same stream join without clicks.
+type Resampler struct {
+	inRate   int
+	outRate  int
+	channels int
+
+	up   int
+	down int
+	taps int
+
+	// filter holds one row of taps coefficients per phase
+	filter [][]float64
+	// history holds the last frames of the previous chunk, one slice per channel
+	history [][]float64
+	pos     int
+}
+
+// ValidSampleRate reports whether rate is within MinSampleRate and MaxSampleRate.
+func ValidSampleRate(rate int) bool {
+	return rate >= MinSampleRate && rate <= MaxSampleRate
+}
+
+// NewResampler creates a Resampler converting from inRate to outRate.  It
+// returns an error if either rate is not a ValidSampleRate.
+func NewResampler(inRate, outRate, channels int) (*Resampler, error) {
+	for _, rate := range []int{inRate, outRate} {
+		if !ValidSampleRate(rate) {
+			return nil, fmt.Errorf("audio: sample rate %d outside %d to %d", rate, MinSampleRate, MaxSampleRate)
+		}
+	}
+	if channels < 1 {
+		channels = 1
+	}
+	divisor := gcd(inRate, outRate)
+	r := &Resampler{
+		inRate:   inRate,
+		outRate:  outRate,
+		channels: channels,
+		up:       outRate / divisor,
+		down:     inRate / divisor,
+		history:  make([][]float64, channels),
+	}
+	r.taps = resampleTaps * ((r.down + r.up - 1) / r.up)
+	for ch := range r.history {
+		r.history[ch] = make([]float64, r.taps-1)
+	}
+	r.pos = (r.taps - 1) * r.up
+	r.filter = polyphaseFilter(r.up, r.down, r.taps)
+	return r, nil
+}
+
+// InRate is the sample rate the Resampler expects.
+func (r *Resampler) InRate() int {
+	return r.inRate
+}
+
+// OutRate is the sample rate the Resampler produces.
+func (r *Resampler) OutRate() int {
+	return r.outRate
+}
+
+// Channels is the number of interleaved channels the Resampler expects.
+func (r *Resampler) Channels() int {
+	return r.channels
+}
+
+// Process resamples the next chunk of the stream.
+func (r *Resampler) Process(in []int32) []int32 {
+	if r.up == r.down {
+		out := make([]int32, len(in))
+		copy(out, in)
+		return out
+	}
+
+	frames := len(in) / r.channels
+	historyLength := r.taps - 1
+
+	// deinterleave behind each channel's history
+	bufs := make([][]float64, r.channels)
+	for ch := range bufs {
+		bufs[ch] = make([]float64, historyLength+frames)
+		copy(bufs[ch], r.history[ch])
+	}
+	for i := 0; i < frames*r.channels; i++ {
+		bufs[i%r.channels][historyLength+i/r.channels] = float64(in[i])
+	}
+
+	out := make([]int32, 0, (frames*r.up/r.down+1)*r.channels)
+	for ; r.pos/r.up < historyLength+frames; r.pos += r.down {
+		idx := r.pos / r.up
+		coefficients := r.filter[r.pos%r.up]
+
+		for _, buf := range bufs {
+			var sum float64
+			for k, h := range coefficients {
+				sum += buf[idx-k] * h
+			}
+			out = append(out, clip(sum))
+		}
+	}
+
+	for ch, buf := range bufs {
+		copy(r.history[ch], buf[frames:])
+	}
+	r.pos -= frames * r.up
+
+	return out
+}
+
+// polyphaseFilter designs a Kaiser windowed low-pass filter at the upsampled
+// rate and splits it into up branches, scaled to keep unity gain.
+func polyphaseFilter(up, down, taps int) [][]float64 {
+	length := taps * up
+	cutoff := resampleRolloff * 0.5 / float64(maxInt(up, down))
+	center := float64(length-1) / 2
+
+	filter := make([][]float64, up)
+	for phase := range filter {
+		filter[phase] = make([]float64, taps)
+	}
+
+	denominator := besselI0(resampleKaiserBeta)
+	for n := 0; n < length; n++ {
+		t := float64(n) - center
+		ratio := 2*float64(n)/float64(length-1) - 1
+		window := besselI0(resampleKaiserBeta*math.Sqrt(1-ratio*ratio)) / denominator
+
+		filter[n%up][n/up] = 2 * cutoff * sinc(2*cutoff*t) * window * float64(up)
+	}
+	return filter
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 is the zeroth order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > 1e-12*sum; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func clip(v float64) int32 {
+	if v >= math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if v <= math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(math.Round(v))
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,239 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+var testRates = []int{8000, 16000, 44100, 48000}
+
+// sine returns seconds of a tone at freq Hz and amplitude of full scale
+func sine(rate int, freq, amplitude, seconds float64) []int32 {
+	samples := make([]int32, int(float64(rate)*seconds))
+	for i := range samples {
+		samples[i] = int32(amplitude * math.MaxInt32 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return samples
+}
+
+// fitSine fits a tone at freq Hz to samples by least squares, returning its
+// amplitude relative to full scale and the signal to residual ratio in dB
+func fitSine(samples []int32, rate int, freq float64) (float64, float64) {
+	var ss, sc, cc, ys, yc float64
+	for i, s := range samples {
+		y := float64(s) / math.MaxInt32
+		phase := 2 * math.Pi * freq * float64(i) / float64(rate)
+		sin, cos := math.Sin(phase), math.Cos(phase)
+		ss += sin * sin
+		sc += sin * cos
+		cc += cos * cos
+		ys += y * sin
+		yc += y * cos
+	}
+	det := ss*cc - sc*sc
+	a := (ys*cc - yc*sc) / det
+	b := (yc*ss - ys*sc) / det
+
+	var signal, noise float64
+	for i, s := range samples {
+		y := float64(s) / math.MaxInt32
+		phase := 2 * math.Pi * freq * float64(i) / float64(rate)
+		fit := a*math.Sin(phase) + b*math.Cos(phase)
+		signal += fit * fit
+		noise += (y - fit) * (y - fit)
+	}
+	return math.Hypot(a, b), 10 * math.Log10(signal/noise)
+}
+
+// zeroCrossingFrequency estimates a tone's frequency from its rising zero crossings
+func zeroCrossingFrequency(samples []int32, rate int) float64 {
+	first, last, crossings := -1, -1, 0
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1] < 0 && samples[i] >= 0 {
+			if first < 0 {
+				first = i
+			} else {
+				crossings++
+			}
+			last = i
+		}
+	}
+	if crossings == 0 {
+		return 0
+	}
+	return float64(crossings) * float64(rate) / float64(last-first)
+}
+
+// processChunks resamples in through r in chunks of the given frame counts, repeated
+func processChunks(r *Resampler, in []int32, channels int, chunks ...int) []int32 {
+	var out []int32
+	for i := 0; len(in) > 0; i++ {
+		n := chunks[i%len(chunks)] * channels
+		if n > len(in) {
+			n = len(in)
+		}
+		out = append(out, r.Process(in[:n])...)
+		in = in[n:]
+	}
+	return out
+}
+
+// newResampler is NewResampler for rates known to be valid
+func newResampler(t *testing.T, inRate, outRate, channels int) *Resampler {
+	t.Helper()
+	r, err := NewResampler(inRate, outRate, channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// trim drops the ends of a resampled tone, where the filter starts from silence
+func trim(samples []int32) []int32 {
+	return samples[len(samples)/10 : len(samples)-len(samples)/10]
+}
+
+func TestResamplerTone(t *testing.T) {
+	const freq, amplitude, seconds = 1000, 0.5, 0.5
+	for _, inRate := range testRates {
+		for _, outRate := range testRates {
+			t.Run(fmt.Sprintf("%v to %v", inRate, outRate), func(t *testing.T) {
+				r := newResampler(t, inRate, outRate, 1)
+				out := processChunks(r, sine(inRate, freq, amplitude, seconds), 1, 441)
+
+				want := int(float64(outRate) * seconds)
+				if len(out) < want-resampleTaps || len(out) > want+1 {
+					t.Errorf("%v samples out, want about %v", len(out), want)
+				}
+
+				out = trim(out)
+				if got := zeroCrossingFrequency(out, outRate); math.Abs(got-freq) > 1 {
+					t.Errorf("frequency %.2fHz, want %vHz", got, freq)
+				}
+				gotAmplitude, snr := fitSine(out, outRate, freq)
+				if math.Abs(gotAmplitude-amplitude) > amplitude*0.01 {
+					t.Errorf("amplitude %.4f, want %v", gotAmplitude, amplitude)
+				}
+				if snr < 60 {
+					t.Errorf("SNR %.1fdB, want at least 60dB", snr)
+				}
+			})
+		}
+	}
+}
+
+func TestResamplerRemovesAliases(t *testing.T) {
+	// 6kHz is above 8kHz audio's Nyquist frequency, it has to be filtered out
+	// rather than fold down to 2kHz
+	r := newResampler(t, 48000, 8000, 1)
+	out := trim(r.Process(sine(48000, 6000, 0.5, 0.5)))
+	var sum float64
+	for _, s := range out {
+		v := float64(s) / math.MaxInt32
+		sum += v * v
+	}
+	if db := 10 * math.Log10(sum/float64(len(out))/(0.5*0.5/2)); db > -60 {
+		t.Errorf("6kHz tone left at %.1fdB, want under -60dB", db)
+	}
+}
+
+func TestResamplerChunkBoundaries(t *testing.T) {
+	in := sine(44100, 440, 0.8, 0.3)
+	for _, outRate := range []int{8000, 16000, 48000} {
+		t.Run(fmt.Sprint(outRate), func(t *testing.T) {
+			whole := newResampler(t, 44100, outRate, 1).Process(in)
+			for _, chunks := range [][]int{{1}, {7, 160, 3}, {441}, {1024, 1}} {
+				chunked := processChunks(newResampler(t, 44100, outRate, 1), in, 1, chunks...)
+				if len(chunked) != len(whole) {
+					t.Fatalf("chunks of %v gave %v samples, in one piece %v", chunks, len(chunked), len(whole))
+				}
+				for i := range whole {
+					if chunked[i] != whole[i] {
+						t.Fatalf("chunks of %v differ at sample %v: %v, in one piece %v", chunks, i, chunked[i], whole[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResamplerStereo(t *testing.T) {
+	const inRate, outRate = 48000, 16000
+	left := sine(inRate, 440, 0.5, 0.5)
+	right := sine(inRate, 1500, 0.25, 0.5)
+	stereo := make([]int32, 0, 2*len(left))
+	for i := range left {
+		stereo = append(stereo, left[i], right[i])
+	}
+
+	out := processChunks(newResampler(t, inRate, outRate, 2), stereo, 2, 480, 17)
+	if len(out)%2 != 0 {
+		t.Fatalf("%v samples out, want whole frames", len(out))
+	}
+	gotLeft := make([]int32, 0, len(out)/2)
+	gotRight := make([]int32, 0, len(out)/2)
+	for i := 0; i < len(out); i += 2 {
+		gotLeft = append(gotLeft, out[i])
+		gotRight = append(gotRight, out[i+1])
+	}
+
+	// each channel comes out as it would on its own
+	for _, channel := range []struct {
+		name      string
+		in, out   []int32
+		freq, amp float64
+	}{
+		{"left", left, gotLeft, 440, 0.5},
+		{"right", right, gotRight, 1500, 0.25},
+	} {
+		mono := newResampler(t, inRate, outRate, 1).Process(channel.in)
+		if len(mono) != len(channel.out) {
+			t.Fatalf("%v: %v samples, on its own %v", channel.name, len(channel.out), len(mono))
+		}
+		for i := range mono {
+			if mono[i] != channel.out[i] {
+				t.Fatalf("%v: sample %v is %v, on its own %v", channel.name, i, channel.out[i], mono[i])
+			}
+		}
+		amplitude, snr := fitSine(trim(channel.out), outRate, channel.freq)
+		if math.Abs(amplitude-channel.amp) > channel.amp*0.01 || snr < 60 {
+			t.Errorf("%v: amplitude %.4f at %.1fdB SNR, want %v", channel.name, amplitude, snr, channel.amp)
+		}
+	}
+}
+
+func TestResamplerSameRate(t *testing.T) {
+	in := sine(16000, 1000, 0.5, 0.01)
+	out := newResampler(t, 16000, 16000, 1).Process(in)
+	for i := range in {
+		if out[i] != in[i] {
+			t.Fatalf("sample %v changed from %v to %v", i, in[i], out[i])
+		}
+	}
+	out[0]++
+	if in[0] == out[0] {
+		t.Error("output shares the input's memory")
+	}
+}
+
+func TestResamplerRates(t *testing.T) {
+	tests := []struct {
+		inRate, outRate int
+		valid           bool
+	}{
+		{MinSampleRate, MaxSampleRate, true},
+		{44100, 48000, true},
+		{0, 48000, false},
+		{-1, 44100, false},
+		{44100, 7999, false},
+		{2147483647, 48000, false},
+		{48000, MaxSampleRate + 1, false},
+	}
+	for _, test := range tests {
+		r, err := NewResampler(test.inRate, test.outRate, 1)
+		if valid := err == nil && r != nil; valid != test.valid {
+			t.Errorf("%v to %v: %v, want valid %v", test.inRate, test.outRate, err, test.valid)
+		}
+	}
+}
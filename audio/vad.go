@@ -0,0 +1,72 @@
+package audio
+
+import "time"
+
+// VADConfig tunes the voice activity detector.
+type VADConfig struct {
+	// Threshold is the level in dBFS a chunk must exceed to be voice
+	Threshold float64
+	// MaxZeroCrossingRate is the fraction of samples changing sign above which a
+	// chunk is treated as broadband noise rather than voice, unless it is loud
+	MaxZeroCrossingRate float64
+	// HangTime keeps the detector active after the last voiced chunk so word
+	// endings and short pauses are not cut off
+	HangTime time.Duration
+}
+
+// loudMargin is how far over the threshold a chunk is treated as voice regardless of its zero crossing rate
+const loudMargin = 12
+
+// VAD is an energy and zero crossing rate voice activity detector.
+type VAD struct {
+	config VADConfig
+	hang   time.Duration
+}
+
+// NewVAD creates a detector with the given settings.
+func NewVAD(config VADConfig) *VAD {
+	return &VAD{config: config}
+}
+
+// Detect reports whether a chunk of interleaved samples holds voice, or follows
+// one closely enough to be within the hang time.
+func (v *VAD) Detect(samples []int32, rate, channels int) bool {
+	if channels < 1 {
+		channels = 1
+	}
+	mono := Remix(samples, channels, 1)
+	if len(mono) == 0 || rate == 0 {
+		return v.hang > 0
+	}
+
+	rms, _ := Levels(mono)
+	level := Decibels(rms)
+	voiced := level > v.config.Threshold &&
+		(zeroCrossingRate(mono) < v.config.MaxZeroCrossingRate || level > v.config.Threshold+loudMargin)
+
+	if voiced {
+		v.hang = v.config.HangTime
+		return true
+	}
+
+	duration := time.Duration(len(mono)) * time.Second / time.Duration(rate)
+	if v.hang <= 0 {
+		return false
+	}
+	v.hang -= duration
+	return true
+}
+
+func zeroCrossingRate(samples []int32) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(samples)-1)
+}
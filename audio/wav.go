@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// wavHeaderSize is the bytes before the samples in the files WAVWriter writes
+const wavHeaderSize = 44
+
+// WAVWriter writes 16 bit PCM WAV, filling in the sizes in the header on Close.
+type WAVWriter struct {
+	w        io.WriteSeeker
+	channels int
+	frames   int
+	buf      []byte
+}
+
+// NewWAVWriter writes the header of a WAV file at sampleRate with channels interleaved.
+func NewWAVWriter(w io.WriteSeeker, sampleRate, channels int) (*WAVWriter, error) {
+	wav := &WAVWriter{w: w, channels: channels}
+	if err := wav.writeHeader(sampleRate); err != nil {
+		return nil, err
+	}
+	return wav, nil
+}
+
+func (wav *WAVWriter) writeHeader(sampleRate int) error {
+	var header [wavHeaderSize]byte
+	le := binary.LittleEndian
+	copy(header[0:4], "RIFF")
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	le.PutUint32(header[16:20], 16)
+	le.PutUint16(header[20:22], 1) // PCM
+	le.PutUint16(header[22:24], uint16(wav.channels))
+	le.PutUint32(header[24:28], uint32(sampleRate))
+	le.PutUint32(header[28:32], uint32(sampleRate*wav.channels*2))
+	le.PutUint16(header[32:34], uint16(wav.channels*2))
+	le.PutUint16(header[34:36], 16)
+	copy(header[36:40], "data")
+	// the RIFF and data sizes are left 0 until Close
+	_, err := wav.w.Write(header[:])
+	return err
+}
+
+// Write appends interleaved samples, scaled to the full int32 range.
+func (wav *WAVWriter) Write(samples []int32) error {
+	if cap(wav.buf) < len(samples)*2 {
+		wav.buf = make([]byte, len(samples)*2)
+	}
+	buf := wav.buf[:len(samples)*2]
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(sample>>16))
+	}
+	if _, err := wav.w.Write(buf); err != nil {
+		return err
+	}
+	wav.frames += len(samples) / wav.channels
+	return nil
+}
+
+// Frames is how many frames have been written.
+func (wav *WAVWriter) Frames() int {
+	return wav.frames
+}
+
+// Close fills in the header's sizes, it does not close the underlying writer.
+func (wav *WAVWriter) Close() error {
+	dataSize := uint32(wav.frames * wav.channels * 2)
+
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], wavHeaderSize-8+dataSize)
+	if _, err := wav.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := wav.w.Write(size[:]); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(size[:], dataSize)
+	if _, err := wav.w.Seek(40, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := wav.w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := wav.w.Seek(0, io.SeekEnd)
+	return err
+}
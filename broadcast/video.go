@@ -0,0 +1,173 @@
+// Package broadcast holds what every station does with the video it sends
+// to the intercom: frames go out no faster than the frame rate and the
+// server's bitrate limit allow, only after motion is seen if the station is
+// set to wait for it, and as the tiles that changed since the last frame.
+package broadcast
+
+import (
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
+)
+
+// MatTypeBGR is gocv's type for 8 bit BGR frames, CV_8UC3, which tiled
+// images name so stations know how to show them.
+const MatTypeBGR = 16
+
+// Config is how a station sends its video.
+type Config struct {
+	// FrameRate is the most frames sent per second.
+	FrameRate int
+	// TileSize, Quality and KeyframeInterval are the tile encoder's, see video.NewEncoder.
+	TileSize         int
+	Quality          int
+	KeyframeInterval time.Duration
+	// Motion, if set, is what counts as motion in the frames watched, and
+	// MotionCooldown how long video is sent after the last motion seen.
+	Motion         *video.MotionConfig
+	MotionCooldown time.Duration
+}
+
+// Video paces, watches and encodes the frames a station sends.  It is safe
+// for concurrent use, the server's keyframe requests and bitrate limits
+// arrive on another goroutine than the frames.
+type Video struct {
+	frameRate int
+	// motion is nil unless frames are watched for motion
+	motion         *video.MotionDetector
+	motionCooldown time.Duration
+
+	mu            sync.Mutex
+	encoder       *video.Encoder
+	lastFrameSent time.Time
+	// lastFrameBytes is the size of the last frame sent and maxBitrate the
+	// server's latest limit in bits per second, together they pace the frames sent
+	lastFrameBytes int
+	maxBitrate     int
+	motionUntil    time.Time
+}
+
+// NewVideo returns the video of a station sending as config says.
+func NewVideo(config Config) *Video {
+	v := &Video{
+		frameRate:      config.FrameRate,
+		motionCooldown: config.MotionCooldown,
+		encoder:        video.NewEncoder(config.TileSize, config.Quality, config.KeyframeInterval),
+	}
+	if config.Motion != nil {
+		v.motion = video.NewMotionDetector(*config.Motion)
+	}
+	return v
+}
+
+// Interval is the time between frames sent, stretched to keep under the
+// server's bitrate limit.
+func (v *Video) Interval() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.interval()
+}
+
+// interval is Interval with v.mu held
+func (v *Video) interval() time.Duration {
+	interval := time.Second / time.Duration(v.frameRate)
+	if v.maxBitrate > 0 && v.lastFrameBytes > 0 {
+		limited := time.Duration(float64(v.lastFrameBytes*8) / float64(v.maxBitrate) * float64(time.Second))
+		if limited > interval {
+			interval = limited
+		}
+	}
+	return interval
+}
+
+// Due reports whether a frame Interval after the last one sent is due.
+func (v *Video) Due() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return time.Since(v.lastFrameSent) >= v.interval()
+}
+
+// Sent records a frame of size bytes as sent, the next is paced from it.
+func (v *Video) Sent(size int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastFrameSent = time.Now()
+	v.lastFrameBytes = size
+}
+
+// SetMaxBitrate sets the server's limit on the video sent in bits per
+// second, zero for none.
+func (v *Video) SetMaxBitrate(bitrate int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.maxBitrate = bitrate
+}
+
+// RequestKeyframe makes the next frame encoded a keyframe, for a station
+// that joined or lost track of the video.
+func (v *Video) RequestKeyframe() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.encoder.RequestKeyframe()
+}
+
+// WatchesMotion reports whether frames are watched for motion.
+func (v *Video) WatchesMotion() bool {
+	return v.motion != nil
+}
+
+// CheckMotion watches an 8 bit BGR frame for motion.  It returns the
+// fraction of the region that changed, and whether motion started with the
+// frame, none having been seen for the cooldown before it.
+func (v *Video) CheckMotion(pix []byte, width, height int) (float64, bool) {
+	fraction, moved := v.motion.Detect(pix, width, height)
+	if !moved {
+		return fraction, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	started := !time.Now().Before(v.motionUntil)
+	v.motionUntil = time.Now().Add(v.motionCooldown)
+	return fraction, started
+}
+
+// MotionSeen reports whether motion was seen within the cooldown.
+func (v *Video) MotionSeen() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return time.Now().Before(v.motionUntil)
+}
+
+// Frame encodes an 8 bit BGR frame as the tiles that changed since the
+// last one and records it as sent.  A frame with no tiles still goes out,
+// it tells the other stations video is still on.
+func (v *Video) Frame(pix []byte, width, height int) (*proto.Image, error) {
+	v.mu.Lock()
+	tiles, keyframe, err := v.encoder.Encode(pix, width, height)
+	v.lastFrameSent = time.Now()
+	tileSize := v.encoder.TileSize()
+	v.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	frame := &proto.Image{
+		Height:   int32(height),
+		Width:    int32(width),
+		Type:     MatTypeBGR,
+		TileSize: int32(tileSize),
+		Keyframe: keyframe,
+	}
+	size := 0
+	for _, tile := range tiles {
+		frame.Tiles = append(frame.Tiles, &proto.Tile{X: int32(tile.X), Y: int32(tile.Y), Jpeg: tile.JPEG})
+		size += len(tile.JPEG)
+	}
+
+	v.mu.Lock()
+	v.lastFrameBytes = size
+	v.mu.Unlock()
+	return frame, nil
+}
@@ -0,0 +1,159 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/3xcellent/intercom/video"
+)
+
+// frame is a BGR frame of one shade
+func frame(width, height int, shade byte) []byte {
+	pix := make([]byte, width*height*3)
+	for i := range pix {
+		pix[i] = shade
+	}
+	return pix
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		frameRate  int
+		frameBytes int
+		maxBitrate int
+		want       time.Duration
+	}{
+		{"frame rate", 10, 0, 0, 100 * time.Millisecond},
+		{"no limit", 10, 50000, 0, 100 * time.Millisecond},
+		{"under the limit", 10, 1000, 1000000, 100 * time.Millisecond},
+		{"over the limit", 10, 50000, 1000000, 400 * time.Millisecond},
+		{"limited to a frame a second", 30, 12500, 100000, time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewVideo(Config{FrameRate: test.frameRate, TileSize: 16, Quality: 75})
+			v.SetMaxBitrate(test.maxBitrate)
+			v.Sent(test.frameBytes)
+			if got := v.Interval(); got != test.want {
+				t.Errorf("interval %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	v := NewVideo(Config{FrameRate: 20, TileSize: 16, Quality: 75})
+	if !v.Due() {
+		t.Fatal("first frame not due")
+	}
+	v.Sent(100)
+	if v.Due() {
+		t.Error("frame due straight after one was sent")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !v.Due() {
+		t.Error("frame not due an interval after the last")
+	}
+
+	// a bitrate limit holds the next frame back until the last one's bits have gone
+	v.SetMaxBitrate(8000)
+	v.Sent(100)
+	time.Sleep(60 * time.Millisecond)
+	if v.Due() {
+		t.Errorf("frame due after 60ms, want the %v the limit allows", v.Interval())
+	}
+}
+
+func TestFrame(t *testing.T) {
+	const width, height = 64, 48
+	v := NewVideo(Config{FrameRate: 10, TileSize: 16, Quality: 75, KeyframeInterval: time.Hour})
+	// a byte a second
+	v.SetMaxBitrate(8)
+
+	first, err := v.Frame(frame(width, height, 100), width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Keyframe || len(first.Tiles) != 12 || first.Width != width || first.Height != height ||
+		first.TileSize != 16 || first.Type != MatTypeBGR {
+		t.Errorf("first frame keyframe %v with %v tiles, %vx%v in %v tiles of type %v",
+			first.Keyframe, len(first.Tiles), first.Width, first.Height, first.TileSize, first.Type)
+	}
+	size := 0
+	for _, tile := range first.Tiles {
+		size += len(tile.Jpeg)
+	}
+	if want := time.Duration(size) * time.Second; v.Interval() != want {
+		t.Errorf("interval %v after a frame of %v bytes at a byte a second, want %v", v.Interval(), size, want)
+	}
+	if v.Due() {
+		t.Error("frame due straight after one was encoded")
+	}
+
+	// nothing changed, the frame still goes out without tiles
+	same, err := v.Frame(frame(width, height, 100), width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Keyframe || len(same.Tiles) != 0 {
+		t.Errorf("unchanged frame keyframe %v with %v tiles", same.Keyframe, len(same.Tiles))
+	}
+	if v.Interval() != 100*time.Millisecond {
+		t.Errorf("interval %v after an empty frame, want the frame rate's", v.Interval())
+	}
+
+	v.RequestKeyframe()
+	again, err := v.Frame(frame(width, height, 100), width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Keyframe || len(again.Tiles) != 12 {
+		t.Errorf("requested keyframe %v with %v tiles", again.Keyframe, len(again.Tiles))
+	}
+}
+
+func TestMotion(t *testing.T) {
+	const width, height = 32, 32
+	if NewVideo(Config{FrameRate: 10}).WatchesMotion() {
+		t.Error("watching for motion without a motion config")
+	}
+
+	v := NewVideo(Config{
+		FrameRate:      10,
+		Motion:         &video.MotionConfig{Region: video.Region{Width: 1, Height: 1}, Threshold: 0.1},
+		MotionCooldown: 50 * time.Millisecond,
+	})
+	if !v.WatchesMotion() {
+		t.Fatal("not watching for motion")
+	}
+
+	steps := []struct {
+		shade   byte
+		started bool
+		seen    bool
+	}{
+		// the first frame is the background
+		{0, false, false},
+		{0, false, false},
+		{200, true, true},
+		// still, but within the cooldown
+		{0, false, true},
+	}
+	for i, step := range steps {
+		fraction, started := v.CheckMotion(frame(width, height, step.shade), width, height)
+		if started != step.started || v.MotionSeen() != step.seen {
+			t.Errorf("frame %v: started %v seen %v (%.2f changed), want %v %v",
+				i, started, v.MotionSeen(), fraction, step.started, step.seen)
+		}
+	}
+
+	// the cooldown passes without motion, the next motion starts again
+	time.Sleep(60 * time.Millisecond)
+	if v.MotionSeen() {
+		t.Error("motion seen after the cooldown")
+	}
+	if _, started := v.CheckMotion(frame(width, height, 255), width, height); !started {
+		t.Error("motion after the cooldown did not start")
+	}
+}
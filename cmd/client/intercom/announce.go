@@ -0,0 +1,94 @@
+package intercom
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+
+	"gocv.io/x/gocv"
+)
+
+// announceImageInterval is how long each image of a sequence is shown
+const announceImageInterval = time.Second
+
+// playAnnouncement broadcasts the configured audio file, and images if any, in
+// place of the mic and webcam.  Samples are sent in the same sized chunks and
+// at the same pace as startAudioBroadcast.
+func (c *intercomClient) playAnnouncement() {
+	if c.config.AnnounceAudio == "" {
+		c.log.Warnf("no announcement configured, use -announce")
+		return
+	}
+
+	clip, err := audio.ReadFile(c.config.AnnounceAudio)
+	if err != nil {
+		c.log.Errorf("Error reading announcement from: %v | %v", c.config.AnnounceAudio, err)
+		return
+	}
+	samples := clip.Samples
+
+	images := c.loadAnnouncementImages()
+	defer func() {
+		for i := range images {
+			images[i].Close()
+		}
+	}()
+
+	c.isAnnouncing = true
+	defer func() { c.isAnnouncing = false }()
+	c.log.Infof("announcement starting")
+
+	// sent at the file's own rate and channels, receivers convert to their output device
+	chunkSize := int(float64(clip.SampleRate)*sampleSeconds) * clip.Channels
+	ticker := time.NewTicker(time.Duration(sampleSeconds * float64(time.Second)))
+	defer ticker.Stop()
+
+	start := time.Now()
+	for offset := 0; offset < len(samples); offset += chunkSize {
+		select {
+		case <-c.context.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// the server stops relaying an image shortly after receiving it, so it is resent with every chunk
+		if len(images) > 0 {
+			frame := int(time.Since(start)/announceImageInterval) % len(images)
+			c.sendImage(images[frame])
+		}
+
+		chunk := make([]int32, chunkSize)
+		copy(chunk, samples[offset:])
+		c.micMeter.update(chunk)
+		c.sendAudio(chunk, clip.SampleRate, clip.Channels)
+	}
+	c.log.Infof("announcement ended")
+}
+
+// loadAnnouncementImages reads every image matching the configured pattern, in name order
+func (c *intercomClient) loadAnnouncementImages() []gocv.Mat {
+	if c.config.AnnounceImages == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(c.config.AnnounceImages)
+	if err != nil {
+		c.log.Errorf("invalid announcement image pattern: %v", err)
+		return nil
+	}
+	sort.Strings(paths)
+
+	var images []gocv.Mat
+	for _, path := range paths {
+		img := gocv.IMRead(path, gocv.IMReadColor)
+		if img.Empty() {
+			c.log.Errorf("Error reading image from: %v", path)
+			img.Close()
+			continue
+		}
+		images = append(images, img)
+	}
+	return images
+}
@@ -0,0 +1,89 @@
+package intercom
+
+import (
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/dial"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/video"
+)
+
+// Config holds the settings the client is started with.
+type Config struct {
+	// Name identifies this station to the others
+	Name string
+	// Room is the room the station joins, empty for the server's default
+	Room string
+	// Server is where the intercom server is and how to connect to it
+	Server dial.Config
+	// ControlAddr is where the local HTTP control API listens, empty disables it
+	ControlAddr string
+	// SettingsFile persists volume settings between runs, empty keeps them in memory only
+	SettingsFile string
+	// Logger is where the client logs to, nil logs info and above as text to stderr
+	Logger *logger.Logger
+
+	// DeviceID is the video capture device to broadcast from
+	DeviceID string
+	// BackgroundImage is shown when no broadcast is coming in
+	BackgroundImage string
+
+	// FrameRate is the most video frames sent per second
+	FrameRate int
+	// FrameWidth and FrameHeight bound the size of the video sent, frames are scaled
+	// down to fit keeping their aspect ratio, zero leaves them at the camera's size
+	FrameWidth  int
+	FrameHeight int
+	// VideoQuality is the JPEG quality, 1 to 100, video tiles are sent at
+	VideoQuality int
+	// KeyframeInterval is the longest between sending every tile of the video, rather than only those that changed
+	KeyframeInterval time.Duration
+
+	// Motion keeps the webcam open and broadcasts video whenever it sees motion
+	Motion bool
+	// MotionConfig holds the region watched and how much of it has to change
+	MotionConfig video.MotionConfig
+	// MotionCooldown is how long video keeps being broadcast after the last motion
+	MotionCooldown time.Duration
+
+	// Chime is a WAV or AIFF file played when a doorbell station rings
+	Chime string
+	// RingFromStdin rings this station, as a doorbell, for every line read from stdin
+	RingFromStdin bool
+
+	// InputSampleRate is the rate the mic is opened at
+	InputSampleRate int
+	// OutputSampleRate is the rate the speaker is opened at, incoming audio is resampled to it
+	OutputSampleRate int
+	// InputChannels is the number of interleaved channels captured from the mic
+	InputChannels int
+	// OutputChannels is the number of interleaved channels played, incoming audio is up or down mixed to it
+	OutputChannels int
+
+	// EchoCancel subtracts the audio being played from the mic input
+	EchoCancel bool
+	// EchoTaps is the length of the echo path the canceller models, in mic samples
+	EchoTaps int
+	// Duck turns the mic down while incoming audio is playing, a half duplex fallback for echo
+	Duck bool
+
+	// CapturePipeline lists the processors run on mic input before it is sent, see audio.ParsePipeline
+	CapturePipeline string
+	// PlaybackPipeline lists the processors run on incoming audio before it is played
+	PlaybackPipeline string
+
+	// VAD drops silent chunks of outgoing audio while broadcasting
+	VAD bool
+	// VOX keeps the mic open and broadcasts whenever voice is detected, instead of push-to-talk
+	VOX bool
+	// VADConfig holds the voice detection thresholds used by VAD and VOX
+	VADConfig audio.VADConfig
+
+	// AnnounceAudio is a WAV or AIFF file broadcast instead of the mic when an announcement is played
+	AnnounceAudio string
+	// AnnounceImages is an image path or glob pattern shown instead of the webcam during an announcement
+	AnnounceImages string
+	// AnnounceOnStart plays the announcement as soon as the client connects
+	AnnounceOnStart bool
+}
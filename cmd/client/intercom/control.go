@@ -0,0 +1,91 @@
+package intercom
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// serveControl runs the local HTTP control API:
+//
+//	GET  /volume                                 current volume settings as JSON
+//	POST /volume?master=-6&muted=true             set master volume in dB and/or mute
+//	POST /volume/station?name=kitchen&gain=-3     set a remote station's gain in dB
+//	POST /ring                                   ring the other stations as a doorbell
+func (c *intercomClient) serveControl(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/volume", c.handleVolume)
+	mux.HandleFunc("/volume/station", c.handleStationVolume)
+	mux.HandleFunc("/ring", c.handleRing)
+
+	c.log.Infof("control API listening on http://%v", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		c.log.Errorf("control API error: %v", err)
+	}
+}
+
+func (c *intercomClient) handleVolume(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if value := r.FormValue("master"); value != "" {
+			db, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				http.Error(w, "invalid master: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.volume.setMaster(db)
+		}
+		if value := r.FormValue("muted"); value != "" {
+			muted, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "invalid muted: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.volume.setMuted(muted)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.writeJSON(w, c.volume.snapshot())
+}
+
+func (c *intercomClient) handleStationVolume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+		return
+	}
+	db, err := strconv.ParseFloat(r.FormValue("gain"), 64)
+	if err != nil {
+		http.Error(w, "invalid gain: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.volume.setStation(name, db)
+
+	c.writeJSON(w, c.volume.snapshot())
+}
+
+func (c *intercomClient) handleRing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.ring()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *intercomClient) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		c.log.Errorf("control API write error: %v", err)
+	}
+}
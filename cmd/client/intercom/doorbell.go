@@ -0,0 +1,137 @@
+package intercom
+
+import (
+	"bufio"
+	"os"
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/proto"
+)
+
+// ringTimeout is how long a ring waits to be answered, the doorbell broadcasts video until then
+const ringTimeout = 30 * time.Second
+
+// ring tells every station the doorbell was rung and broadcasts video from it until answered
+func (c *intercomClient) ring() {
+	if c.callWith != "" {
+		return
+	}
+	c.log.Infof("ringing")
+	c.ringUntil = time.Now().Add(ringTimeout)
+	c.sendEvent(&proto.Event{Type: proto.EventType_RING})
+}
+
+// isRinging is true from a ring until it is answered or times out
+func (c *intercomClient) isRinging() bool {
+	return c.callWith == "" && time.Now().Before(c.ringUntil)
+}
+
+// hasRingWaiting is true while another station's ring can be answered
+func (c *intercomClient) hasRingWaiting() bool {
+	return c.ringingFrom != "" && time.Since(c.ringTime) < ringTimeout
+}
+
+// answer opens two-way audio with the doorbell that rang, or hangs up the call
+// if one is open
+func (c *intercomClient) answer() {
+	if c.callWith != "" {
+		c.hangUp()
+		return
+	}
+	if !c.hasRingWaiting() {
+		return
+	}
+
+	c.callWith = c.ringingFrom
+	c.isDoorbell = false
+	c.ringingFrom = ""
+	c.log.Infof("answered %v", c.callWith)
+	c.sendEvent(&proto.Event{Type: proto.EventType_ANSWER, Station: c.callWith})
+}
+
+func (c *intercomClient) hangUp() {
+	// the doorbell station is named in the event, whichever end hangs up
+	doorbell := c.callWith
+	if c.isDoorbell {
+		doorbell = c.config.Name
+	}
+
+	c.log.Infof("hung up on %v", c.callWith)
+	c.sendEvent(&proto.Event{Type: proto.EventType_HANGUP, Station: doorbell})
+	c.callWith = ""
+	c.isDoorbell = false
+}
+
+// handleDoorbellEvent updates the ring and call state for an event from another station
+// and returns the text to show for it
+func (c *intercomClient) handleDoorbellEvent(from string, event *proto.Event) string {
+	switch event.Type {
+	case proto.EventType_RING:
+		c.ringingFrom = from
+		c.ringTime = time.Now()
+		c.playChime()
+		return "ring at " + from
+
+	case proto.EventType_ANSWER:
+		if event.Station == c.config.Name {
+			// this is the doorbell, it takes the call
+			c.ringUntil = time.Time{}
+			c.callWith = from
+			c.isDoorbell = true
+			return "answered by " + from
+		}
+		if event.Station == c.ringingFrom {
+			c.ringingFrom = ""
+		}
+		return from + " answered " + event.Station
+
+	case proto.EventType_HANGUP:
+		if from == c.callWith {
+			c.callWith = ""
+			c.isDoorbell = false
+			return from + " hung up"
+		}
+	}
+	return ""
+}
+
+// loadChime reads the sound played when a doorbell rings, ringing is silent without one
+func (c *intercomClient) loadChime(path string) {
+	if path == "" {
+		return
+	}
+
+	clip, err := audio.ReadFile(path)
+	if err != nil {
+		c.log.Errorf("Error reading chime from: %v | %v", path, err)
+		return
+	}
+	c.chime = clip
+}
+
+func (c *intercomClient) playChime() {
+	if c.chime == nil {
+		return
+	}
+
+	samples := audio.Remix(c.chime.Samples, c.chime.Channels, c.config.OutputChannels)
+	resampler, err := audio.NewResampler(c.chime.SampleRate, c.config.OutputSampleRate, c.config.OutputChannels)
+	if err != nil {
+		c.log.Errorf("cannot play chime: %v", err)
+		return
+	}
+	c.queueOutput(resampler.Process(samples))
+}
+
+// readRings rings the doorbell for every line read from stdin, for a button
+// or sensor wired to a script
+func (c *intercomClient) readRings() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		c.ring()
+	}
+	if err := scanner.Err(); err != nil {
+		c.log.Errorf("Error reading rings from stdin: %v", err)
+	}
+}
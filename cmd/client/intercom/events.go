@@ -0,0 +1,80 @@
+package intercom
+
+import (
+	"image"
+	"time"
+
+	"github.com/3xcellent/intercom/proto"
+
+	"gocv.io/x/gocv"
+)
+
+// eventDisplayTime is how long an event from another station stays on screen
+const eventDisplayTime = 5 * time.Second
+
+// sendEvent tells the other stations something happened at this one
+func (c *intercomClient) sendEvent(event *proto.Event) {
+	req := proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Event{Event: event},
+	}
+
+	if err := c.send(&req); err != nil {
+		c.log.With("type", "event").Errorf("Send error: %v", err)
+	}
+}
+
+// handleEvent shows an event relayed from another station
+func (c *intercomClient) handleEvent(from string, event *proto.Event) {
+	var text string
+	switch event.Type {
+	case proto.EventType_MOTION:
+		text = "motion at " + from
+	case proto.EventType_RING, proto.EventType_ANSWER, proto.EventType_HANGUP:
+		text = c.handleDoorbellEvent(from, event)
+	case proto.EventType_ANNOUNCEMENT:
+		text = event.Text
+	}
+	if text == "" {
+		return
+	}
+
+	c.log.With("from", from).Infof("%v", text)
+	c.eventText = text
+	c.eventTime = time.Now()
+}
+
+// drawEvent shows a doorbell waiting to be answered, or the call with it, otherwise
+// the latest event from another station for a few seconds
+func (c *intercomClient) drawEvent(img *gocv.Mat) {
+	text := c.eventText
+	switch {
+	case c.callWith != "":
+		text = "talking to " + c.callWith + ", [a] to hang up"
+	case c.hasRingWaiting():
+		text = "ring at " + c.ringingFrom + ", [a] to answer"
+	case c.isRinging():
+		text = "ringing..."
+	case text == "" || time.Since(c.eventTime) > eventDisplayTime:
+		return
+	}
+	gocv.PutText(img, text, image.Pt(screenWidth/2-60, 20), gocv.FontHersheySimplex, 0.6, speakingColor, 2)
+}
+
+// watchesForMotion is true when the webcam is kept open to look for motion
+func (c *intercomClient) watchesForMotion() bool {
+	return c.video.WatchesMotion() && !c.isCameraOff && !c.isPrivate
+}
+
+// checkMotion runs the motion detector on a captured frame, video is broadcast
+// from the first frame with motion until the cooldown passes without any
+func (c *intercomClient) checkMotion(img gocv.Mat) {
+	if img.Type() != gocv.MatTypeCV8UC3 {
+		return
+	}
+
+	level, started := c.video.CheckMotion(img.ToBytes(), img.Cols(), img.Rows())
+	if started {
+		c.log.Infof("motion detected, %.0f%% of the region changed", level*100)
+		c.sendEvent(&proto.Event{Type: proto.EventType_MOTION, Level: float32(level)})
+	}
+}
@@ -2,17 +2,21 @@ package intercom
 
 import (
 	"context"
-	"fmt"
 	"image"
 	"io"
 	"math"
+	"sync"
 	"time"
 
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/broadcast"
+	"github.com/3xcellent/intercom/logger"
 	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
 
 	"github.com/gordonklaus/portaudio"
 	"gocv.io/x/gocv"
-	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 const (
@@ -29,8 +33,12 @@ const (
 	inBroadcastX      = screenHeight/2 - inBroadcastHeight/2 - inBroadcastHeight/4
 	inBroadcastY      = screenWidth/2 - inBroadcastWidth/2 - inBroadcastWidth/4
 
-	sampleRate    = 44100
-	sampleSeconds = .1
+	// defaultSampleRate is assumed for audio from senders that do not fill in proto.Audio.sampleRate
+	defaultSampleRate = 44100
+	sampleSeconds     = .1
+
+	// duckAttenuation is how far the mic is turned down in half duplex mode while audio plays
+	duckAttenuation = -30
 
 	matType = gocv.MatTypeCV8UC3
 )
@@ -41,10 +49,13 @@ type intercomClient struct {
 	audioInputStream  *portaudio.Stream
 	audioOutputStream *portaudio.Stream
 	deviceID          string
+	config            Config
+	log               *logger.Logger
 
 	context context.Context
 
 	intercomServer proto.Intercom_ConnectClient
+	sendMutex      sync.Mutex
 
 	bgImg           gocv.Mat
 	displayImg      gocv.Mat
@@ -52,41 +63,136 @@ type intercomClient struct {
 	inBroadcastImg  gocv.Mat
 
 	audioOutputCache [][]int32
+	audioOutputMutex sync.Mutex
+	// resamplers converts each station's audio to the output rate, each
+	// keeping the filter history of its own stream
+	resamplers map[string]*audio.Resampler
+
+	echoCanceller      *audio.EchoCanceller
+	referenceResampler *audio.Resampler
+
+	capturePipeline  audio.Pipeline
+	playbackPipeline audio.Pipeline
+
+	volume *volumeControl
+	// lastSpeaker is the station the latest incoming audio came from, for the station volume hotkeys
+	lastSpeaker string
+
+	roster      []*proto.Station
+	rosterMutex sync.Mutex
+
+	micMeter     levelMeter
+	speakerMeter levelMeter
 
 	lastInBroadcastTime time.Time
 
+	// video paces, watches for motion and encodes the webcam frames sent
+	video               *broadcast.Video
+	decoder             video.Decoder
+	lastKeyframeRequest time.Time
+
+	// eventText is the latest event from another station, shown until eventDisplayTime after eventTime
+	eventText string
+	eventTime time.Time
+
+	// chime is played when a doorbell rings, nil for none
+	chime *audio.Clip
+	// ringUntil is when this station's ring times out, ringingFrom and ringTime
+	// are the latest ring from another station
+	ringUntil   time.Time
+	ringingFrom string
+	ringTime    time.Time
+	// callWith is the station in a doorbell call with this one, isDoorbell is
+	// set when this station is the doorbell in it
+	callWith   string
+	isDoorbell bool
+
 	isReceivingBroadcast bool
 	hasWebcamOn          bool
 	hasMicOn             bool
 	isPlayingAudio       bool
+	isAnnouncing         bool
+	isVoiceActive        bool
+	isMicMuted           bool
+	isCameraOff          bool
+	isPrivate            bool
 	wantToBroadcast      bool
 	wantToQuit           bool
 }
 
-func CreateIntercomClient(ctx context.Context, vidoeCaptureDeviceId, filename string) intercomClient {
-	client := intercomClient{
+func CreateIntercomClient(ctx context.Context, config Config) *intercomClient {
+	log := config.Logger
+	if log == nil {
+		log = logger.Default()
+	}
+	log = log.With("station", config.Name)
+
+	client := &intercomClient{
 		window:          gocv.NewWindow("Capture Window"),
-		deviceID:        vidoeCaptureDeviceId,
+		deviceID:        config.DeviceID,
+		config:          config,
+		log:             log,
 		videoPreviewImg: gocv.NewMatWithSize(outPreviewHeight, outPreviewWidth, gocv.MatTypeCV8UC3),
 		inBroadcastImg:  gocv.NewMatWithSize(inBroadcastHeight, inBroadcastWidth, gocv.MatTypeCV8UC3),
 		context:         ctx,
+		volume:          loadVolumeControl(config.SettingsFile, log),
+		resamplers:      map[string]*audio.Resampler{},
+	}
+
+	videoConfig := broadcast.Config{
+		FrameRate:        config.FrameRate,
+		TileSize:         videoTileSize,
+		Quality:          config.VideoQuality,
+		KeyframeInterval: config.KeyframeInterval,
+		MotionCooldown:   config.MotionCooldown,
+	}
+	if config.Motion {
+		videoConfig.Motion = &config.MotionConfig
+	}
+	client.video = broadcast.NewVideo(videoConfig)
+
+	var err error
+	client.capturePipeline, err = audio.ParsePipeline(config.CapturePipeline, client.inputFormat())
+	if err != nil {
+		panic(err)
+	}
+	client.playbackPipeline, err = audio.ParsePipeline(config.PlaybackPipeline, client.outputFormat())
+	if err != nil {
+		panic(err)
+	}
+
+	if config.EchoCancel {
+		client.echoCanceller = audio.NewEchoCanceller(config.EchoTaps)
+		client.referenceResampler, err = audio.NewResampler(config.OutputSampleRate, config.InputSampleRate, 1)
+		if err != nil {
+			panic(err)
+		}
 	}
 
-	client.loadBackgroundImg(filename)
+	client.loadChime(config.Chime)
+	client.loadBackgroundImg(config.BackgroundImage)
 
 	return client
 }
 
+func (c *intercomClient) inputFormat() audio.Format {
+	return audio.Format{SampleRate: c.config.InputSampleRate, Channels: c.config.InputChannels}
+}
+
+func (c *intercomClient) outputFormat() audio.Format {
+	return audio.Format{SampleRate: c.config.OutputSampleRate, Channels: c.config.OutputChannels}
+}
+
 func (c *intercomClient) loadBackgroundImg(path string) {
 	c.bgImg = gocv.NewMatWithSize(screenHeight, screenWidth, matType)
 	defaultImg := gocv.IMRead(path, gocv.IMReadColor)
 	defer defaultImg.Close()
 
 	if defaultImg.Empty() {
-		fmt.Printf("Error reading image from: %v\n", path)
+		c.log.Errorf("Error reading image from: %v", path)
 		return
 	} else {
-		fmt.Printf("Opening image from: %v | %#v\n", path, defaultImg.Size())
+		c.log.Infof("Opening image from: %v | %#v", path, defaultImg.Size())
 	}
 	gocv.Resize(defaultImg, &c.bgImg, image.Point{X: screenWidth, Y: screenHeight}, 0, 0, gocv.InterpolationDefault)
 	c.ResetDisplayImg()
@@ -108,19 +214,72 @@ func (c *intercomClient) shutdown() {
 
 func (c *intercomClient) connectToServer() {
 	// dail server
-	conn, err := grpc.Dial(":6000", grpc.WithInsecure())
+	conn, err := c.config.Server.Dial()
 	if err != nil {
 		panic(err)
 	}
 
 	// create streams
 	client := proto.NewIntercomClient(conn)
-	c.intercomServer, err = client.Connect(c.context)
+	ctx := c.context
+	if c.config.Room != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "room", c.config.Room)
+	}
+	c.intercomServer, err = client.Connect(ctx)
 	if err != nil {
 		panic(err)
 	}
 }
 
+// send serializes writes to the server stream, which is not safe for concurrent Send calls,
+// and tags them with this station's name
+func (c *intercomClient) send(req *proto.Broadcast) error {
+	req.Name = c.config.Name
+
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	return c.intercomServer.Send(req)
+}
+
+// sendAudio sends interleaved samples, length is the number of frames
+func (c *intercomClient) sendAudio(samples []int32, rate, channels int) {
+	level, peak := audio.Levels(samples)
+	req := proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Audio{
+			Audio: &proto.Audio{
+				SampleRate: int32(rate),
+				Channels:   int32(channels),
+				Length:     int32(len(samples) / channels),
+				Samples:    samples,
+				Level:      float32(level),
+				Peak:       float32(peak),
+			},
+		},
+	}
+
+	if err := c.send(&req); err != nil {
+		c.log.With("type", "audio").Errorf("Send error: %v", err)
+	}
+}
+
+func (c *intercomClient) sendImage(img gocv.Mat) {
+	req := proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Image{
+			Image: &proto.Image{
+				Height: int32(img.Size()[0]),
+				Width:  int32(img.Size()[1]),
+				Type:   int32(img.Type()),
+				Bytes:  img.ToBytes(),
+			},
+		},
+	}
+	c.video.Sent(len(req.GetImage().Bytes))
+
+	if err := c.send(&req); err != nil {
+		c.log.With("type", "image").Errorf("Send error: %v", err)
+	}
+}
+
 func (c *intercomClient) ResetDisplayImg() {
 	c.displayImg = c.bgImg.Clone()
 }
@@ -136,6 +295,45 @@ func (c *intercomClient) handleGrpcStreamRec() {
 			panic(err)
 		}
 
+		respRoster := resp.GetRoster()
+		if respRoster != nil {
+			c.setRoster(respRoster)
+			c.dropResamplers(respRoster.Stations)
+			continue
+		}
+
+		respBitrate := resp.GetBitrate()
+		if respBitrate != nil {
+			limit := int(respBitrate.MaxVideoBitrate)
+			c.video.SetMaxBitrate(limit)
+			if limit > 0 {
+				c.log.Infof("server asked to keep video under %v bits/s", limit)
+			} else {
+				c.log.Infof("server lifted the video bitrate limit")
+			}
+			continue
+		}
+
+		if resp.GetKeyframeRequest() != nil {
+			c.video.RequestKeyframe()
+			continue
+		}
+
+		// the server only sends a status to mute this station from its admin service
+		if respStatus := resp.GetStatus(); respStatus != nil {
+			if respStatus.MicMuted && !c.isMicMuted {
+				c.log.Warnf("mic muted by the server")
+				c.toggleMicMute()
+			}
+			continue
+		}
+
+		respEvent := resp.GetEvent()
+		if respEvent != nil {
+			c.handleEvent(resp.Name, respEvent)
+			continue
+		}
+
 		c.lastInBroadcastTime = time.Now()
 
 		respImage := resp.GetImage()
@@ -146,22 +344,68 @@ func (c *intercomClient) handleGrpcStreamRec() {
 
 		respAudio := resp.GetAudio()
 		if respAudio != nil {
-			c.audioOutputCache = append(c.audioOutputCache, respAudio.Samples)
-			if !c.isPlayingAudio {
-				c.isPlayingAudio = true
-				go c.playAudio()
+			c.lastSpeaker = resp.Name
+			gain, muted := c.volume.gain(resp.Name)
+			if muted {
+				continue
 			}
+
+			samples, err := c.resampleIncoming(resp.Name, respAudio)
+			if err != nil {
+				c.log.Debugf("dropped audio from %v: %v", resp.Name, err)
+				continue
+			}
+			if gain != 0 {
+				audio.ApplyGain(samples, gain)
+			}
+
+			c.queueOutput(samples)
 		}
 	}
 }
 
+// queueOutput adds samples, already at the output rate and channels, to be played
+func (c *intercomClient) queueOutput(samples []int32) {
+	c.audioOutputMutex.Lock()
+	c.audioOutputCache = append(c.audioOutputCache, samples)
+	c.audioOutputMutex.Unlock()
+	if !c.isPlayingAudio {
+		c.isPlayingAudio = true
+		go c.playAudio()
+	}
+}
+
 func (c *intercomClient) processBroadcastImage(img proto.Image) {
-	serverImg, err := gocv.NewMatFromBytes(int(img.Height),
-		int(img.Width),
-		gocv.MatType(img.Type),
-		img.Bytes)
+	pix := img.Bytes
+	width, height := int(img.Width), int(img.Height)
+	var matType gocv.MatType
+	switch {
+	case img.TileSize > 0:
+		var ok bool
+		if pix, ok = c.decodeTiles(img); !ok {
+			return
+		}
+		matType = gocv.MatTypeCV8UC3
+	case len(pix) == 0:
+		// an empty image ends the broadcast
+		width, height = 0, 0
+	default:
+		// the type is taken from the pixels rather than trusted, so the
+		// Mat never reads past them
+		channels, err := video.CheckFrame(pix, width, height)
+		if err != nil {
+			c.log.Errorf("Error showing video: %v", err)
+			return
+		}
+		matType = matTypes[channels]
+	}
+
+	serverImg, err := gocv.NewMatFromBytes(height,
+		width,
+		matType,
+		pix)
 	if err != nil {
-		fmt.Printf("cannot create NewMatFromBytes %v\n", err)
+		c.log.Errorf("cannot create NewMatFromBytes %v", err)
 		c.ResetDisplayImg()
 		return
 	}
@@ -170,13 +414,13 @@ func (c *intercomClient) processBroadcastImage(img proto.Image) {
 	if serverImg.Empty() {
 		c.isReceivingBroadcast = false
 		c.ResetDisplayImg()
-		fmt.Println("incoming broadcast ended")
+		c.log.Infof("incoming broadcast ended")
 		return
 	}
 
 	if !c.isReceivingBroadcast {
 		c.isReceivingBroadcast = true
-		fmt.Println("receiving incoming broadcast")
+		c.log.Infof("receiving incoming broadcast")
 	}
 
 	screenCapRatio := float64(float64(serverImg.Size()[1]) / float64(serverImg.Size()[0]))
@@ -185,11 +429,74 @@ func (c *intercomClient) processBroadcastImage(img proto.Image) {
 	gocv.Resize(serverImg, &c.inBroadcastImg, image.Point{X: inBroadcastWidth, Y: scaledHeight}, 0, 0, gocv.InterpolationDefault)
 }
 
+// resampleIncoming converts samples received from station to the output
+// device's channel layout and rate
+func (c *intercomClient) resampleIncoming(station string, in *proto.Audio) ([]int32, error) {
+	rate := int(in.SampleRate)
+	if rate == 0 {
+		rate = defaultSampleRate
+	}
+
+	resampler, ok := c.resamplers[station]
+	if !ok || resampler.InRate() != rate {
+		var err error
+		resampler, err = audio.NewResampler(rate, c.config.OutputSampleRate, c.config.OutputChannels)
+		if err != nil {
+			return nil, err
+		}
+		c.resamplers[station] = resampler
+	}
+
+	samples := audio.Remix(in.Samples, int(in.Channels), c.config.OutputChannels)
+	return resampler.Process(samples), nil
+}
+
+// dropResamplers forgets the resamplers of stations no longer in the roster
+func (c *intercomClient) dropResamplers(roster []*proto.Station) {
+	connected := map[string]bool{}
+	for _, station := range roster {
+		connected[station.Name] = true
+	}
+	for name := range c.resamplers {
+		if !connected[name] {
+			delete(c.resamplers, name)
+		}
+	}
+}
+
+// nextOutputBuffer fills out from the queued chunks, which no longer line up with
+// the output buffer once resampled, padding with silence if the queue runs dry.
+// It returns false when nothing is queued.
+func (c *intercomClient) nextOutputBuffer(out []int32) bool {
+	c.audioOutputMutex.Lock()
+	defer c.audioOutputMutex.Unlock()
+
+	if len(c.audioOutputCache) == 0 {
+		return false
+	}
+
+	filled := 0
+	for filled < len(out) && len(c.audioOutputCache) > 0 {
+		n := copy(out[filled:], c.audioOutputCache[0])
+		filled += n
+		if n < len(c.audioOutputCache[0]) {
+			c.audioOutputCache[0] = c.audioOutputCache[0][n:]
+		} else {
+			c.audioOutputCache = c.audioOutputCache[1:]
+		}
+	}
+	for i := filled; i < len(out); i++ {
+		out[i] = 0
+	}
+	return true
+}
+
 func (c *intercomClient) playAudio() {
-	out := make([]int32, sampleRate*sampleSeconds)
+	frames := int(float64(c.config.OutputSampleRate) * sampleSeconds)
+	out := make([]int32, frames*c.config.OutputChannels)
 	var err error
 
-	c.audioOutputStream, err = portaudio.OpenDefaultStream(0, 1, sampleRate, len(out), &out)
+	c.audioOutputStream, err = portaudio.OpenDefaultStream(0, c.config.OutputChannels, float64(c.config.OutputSampleRate), frames, &out)
 	if err != nil {
 		panic("audio out err: " + err.Error())
 	}
@@ -200,16 +507,22 @@ func (c *intercomClient) playAudio() {
 
 	// audio playback loop
 	for {
-		cacheLength := len(c.audioOutputCache)
-		if cacheLength == 0 {
+		if !c.nextOutputBuffer(out) {
 			c.isPlayingAudio = false
 			break
 		}
 
 		c.isPlayingAudio = true
 
-		out = c.audioOutputCache[0]
-		c.audioOutputCache = c.audioOutputCache[1:]
+		copy(out, c.playbackPipeline.Process(out, c.outputFormat()))
+		c.speakerMeter.update(out)
+
+		if c.echoCanceller != nil {
+			// the echo canceller works at the mic's rate, on mono
+			mono := audio.Remix(out, c.config.OutputChannels, 1)
+			c.echoCanceller.Reference(c.referenceResampler.Process(mono))
+		}
+
 		err = c.audioOutputStream.Write()
 		if err != nil {
 			panic("playback err: " + err.Error())
@@ -219,16 +532,29 @@ func (c *intercomClient) playAudio() {
 
 func (c *intercomClient) startAudioBroadcast() {
 	c.hasMicOn = true
-	in := make([]int32, 44100*.1)
-	fmt.Println("OpenDefaultStream...")
-	audioInStream, err := portaudio.OpenDefaultStream(1, 0, 44100, len(in), &in)
+	rate, channels := c.config.InputSampleRate, c.config.InputChannels
+	frames := int(float64(rate) * sampleSeconds)
+	in := make([]int32, frames*channels)
+	c.log.Debugf("OpenDefaultStream...")
+	audioInStream, err := portaudio.OpenDefaultStream(channels, 0, float64(rate), frames, &in)
 	if err != nil {
 		panic(err)
 	}
+	// closed rather than only stopped so privacy mode releases the device
+	defer audioInStream.Close()
 	err = audioInStream.Start()
 	if err != nil {
 		panic(err)
 	}
+	if c.echoCanceller != nil {
+		// what played while the mic was closed has no input to line up with
+		c.echoCanceller.Reset()
+	}
+
+	var vad *audio.VAD
+	if c.config.VAD || c.config.VOX {
+		vad = audio.NewVAD(c.config.VADConfig)
+	}
 
 	// audio broadcast loop
 	for {
@@ -238,7 +564,7 @@ func (c *intercomClient) startAudioBroadcast() {
 		default:
 		}
 
-		if !c.wantToBroadcast {
+		if !c.wantsMic() {
 			break
 		}
 
@@ -247,82 +573,131 @@ func (c *intercomClient) startAudioBroadcast() {
 			panic(err)
 		}
 
-		go func(sendSamples []int32) {
-			req := proto.Broadcast{
-				BroadcastType: &proto.Broadcast_Audio{
-					Audio: &proto.Audio{
-						Samples: sendSamples,
-					},
-				},
-			}
+		if c.isMicMuted {
+			c.isVoiceActive = false
+			continue
+		}
 
-			if err := c.intercomServer.Send(&req); err != nil {
-				fmt.Printf("Send error: %v", err)
-				return
-			}
-			if err != nil {
-				panic(err)
+		// in is reused by the next Read, send a copy
+		sendSamples := make([]int32, len(in))
+		copy(sendSamples, in)
+
+		if c.echoCanceller != nil {
+			c.echoCanceller.Cancel(sendSamples, channels)
+		}
+		if c.config.Duck && c.isPlayingAudio {
+			audio.ApplyGain(sendSamples, duckAttenuation)
+		}
+		sendSamples = c.capturePipeline.Process(sendSamples, c.inputFormat())
+		// metered before voice detection so the mic can be seen working while nothing is sent
+		c.micMeter.update(sendSamples)
+
+		if vad != nil {
+			c.isVoiceActive = vad.Detect(sendSamples, rate, channels)
+			if !c.isVoiceActive {
+				continue
 			}
-		}(in)
+		}
+
+		c.sendAudio(sendSamples, rate, channels)
 	}
 	err = audioInStream.Stop()
 	if err != nil {
 		panic(err)
 	}
+	c.isVoiceActive = false
 	c.hasMicOn = false
 }
 
+// wantsMic is true while push-to-talk is on or in a doorbell call, or always with voice operated
+// transmit, unless in privacy mode
+func (c *intercomClient) wantsMic() bool {
+	return (c.wantToBroadcast || c.config.VOX || c.callWith != "") && !c.isAnnouncing && !c.isPrivate
+}
+
+// isTransmitting is true while push-to-talk is on, in a doorbell call, or voice operated transmit hears voice
+func (c *intercomClient) isTransmitting() bool {
+	return (c.wantToBroadcast || c.isVoiceActive || c.callWith != "") && !c.isAnnouncing
+}
+
+// wantsCamera is true while transmitting, ringing, or after motion is seen, with the camera on and
+// not in privacy mode
+func (c *intercomClient) wantsCamera() bool {
+	return (c.isTransmitting() || c.isRinging() || c.video.MotionSeen()) && !c.isCameraOff && !c.isPrivate
+}
+
 func (c *intercomClient) sendVideoCapture() {
 	if !c.hasWebcamOn {
 		var err error
 		c.webcam, err = gocv.OpenVideoCapture(c.deviceID)
 		if err != nil {
-			fmt.Printf("Error opening video capture device: %v\n", c.deviceID)
+			c.log.Errorf("Error opening video capture device: %v", c.deviceID)
 			return
 		}
 		c.hasWebcamOn = true
-		fmt.Println("outgoing broadcast starting")
+		c.log.Infof("outgoing broadcast starting")
+
+		// the camera picks its closest mode, frames are still scaled and paced when sent
+		if c.config.FrameWidth > 0 && c.config.FrameHeight > 0 {
+			c.webcam.Set(gocv.VideoCaptureFrameWidth, float64(c.config.FrameWidth))
+			c.webcam.Set(gocv.VideoCaptureFrameHeight, float64(c.config.FrameHeight))
+		}
+		c.webcam.Set(gocv.VideoCaptureFPS, float64(c.config.FrameRate))
 	}
 
 	videoCaptureImg := gocv.NewMat()
 	defer videoCaptureImg.Close()
 
 	if ok := c.webcam.Read(&videoCaptureImg); !ok {
-		fmt.Println("didn't read from cam")
+		c.log.Warnf("didn't read from cam")
 	}
 
 	if videoCaptureImg.Empty() {
 		if c.hasWebcamOn {
 			c.webcam.Close()
 			c.hasWebcamOn = false
-			fmt.Println("outgoing broadcast ended")
+			c.log.Infof("outgoing broadcast ended")
 		}
 		return
 	}
 
-	req := proto.Broadcast{
-		BroadcastType: &proto.Broadcast_Image{
-			Image: &proto.Image{
-				Height: int32(videoCaptureImg.Size()[0]),
-				Width:  int32(videoCaptureImg.Size()[1]),
-				Type:   int32(videoCaptureImg.Type()),
-				Bytes:  videoCaptureImg.ToBytes(),
-			},
-		},
+	// the webcam stays open while watching for motion, frames are only sent when wanted
+	if c.video.WatchesMotion() {
+		c.checkMotion(videoCaptureImg)
 	}
 
-	if err := c.intercomServer.Send(&req); err != nil {
-		fmt.Printf("Send error: %v", err)
-		return
+	// the preview is updated on every read, frames are only sent at the configured rate
+	if c.wantsCamera() && c.video.Due() {
+		c.sendFrame(videoCaptureImg)
 	}
 
-
 	screenCapRatio := float64(float64(videoCaptureImg.Size()[1]) / float64(videoCaptureImg.Size()[0]))
 	outPreviewScaledHeight := int(math.Floor(outPreviewWidth / screenCapRatio))
 
 	gocv.Resize(videoCaptureImg, &c.videoPreviewImg, image.Point{X: outPreviewWidth, Y: outPreviewScaledHeight}, 0, 0, gocv.InterpolationDefault)
 }
 
+// sendFrame sends a webcam frame, scaled down to fit the configured video size
+func (c *intercomClient) sendFrame(img gocv.Mat) {
+	width, height := img.Cols(), img.Rows()
+	if c.config.FrameWidth <= 0 || c.config.FrameHeight <= 0 || width == 0 || height == 0 {
+		c.sendVideoFrame(img)
+		return
+	}
+
+	scale := math.Min(float64(c.config.FrameWidth)/float64(width), float64(c.config.FrameHeight)/float64(height))
+	if scale >= 1 {
+		c.sendVideoFrame(img)
+		return
+	}
+
+	scaled := gocv.NewMat()
+	defer scaled.Close()
+	size := image.Point{X: int(float64(width) * scale), Y: int(float64(height) * scale)}
+	gocv.Resize(img, &scaled, size, 0, 0, gocv.InterpolationArea)
+	c.sendVideoFrame(scaled)
+}
+
 func (c *intercomClient) draw() {
 	if c.hasIncomingBroadcast() {
 		for x := 0; x < c.inBroadcastImg.Size()[0]; x++ {
@@ -340,7 +715,13 @@ func (c *intercomClient) draw() {
 		}
 	}
 
-	c.window.IMShow(c.displayImg)
+	frame := c.displayImg.Clone()
+	defer frame.Close()
+	c.drawRoster(&frame)
+	c.drawMeters(&frame)
+	c.drawEvent(&frame)
+
+	c.window.IMShow(frame)
 }
 
 func (c *intercomClient) hasIncomingBroadcast() bool {
@@ -362,6 +743,19 @@ func (c *intercomClient) Run() {
 	defer portaudio.Terminate()
 
 	go c.handleGrpcStreamRec()
+	c.sendStatus()
+
+	if c.config.ControlAddr != "" {
+		go c.serveControl(c.config.ControlAddr)
+	}
+
+	if c.config.AnnounceOnStart {
+		go c.playAnnouncement()
+	}
+
+	if c.config.RingFromStdin {
+		go c.readRings()
+	}
 
 	// main program loop
 	for {
@@ -377,6 +771,38 @@ func (c *intercomClient) Run() {
 			c.wantToQuit = true
 		case 32:
 			c.wantToBroadcast = !c.wantToBroadcast
+		case 'p':
+			if !c.isAnnouncing {
+				go c.playAnnouncement()
+			}
+		case 'r':
+			c.ring()
+		case 'a':
+			c.answer()
+		case 'x':
+			c.toggleMicMute()
+		case 'c':
+			c.toggleCamera()
+		case 'v':
+			c.togglePrivacy()
+		case '+', '=':
+			c.log.Infof("volume %+.0fdB", c.volume.adjustMaster(volumeStep))
+		case '-':
+			c.log.Infof("volume %+.0fdB", c.volume.adjustMaster(-volumeStep))
+		case 'm':
+			if c.volume.toggleMute() {
+				c.log.Infof("speaker muted")
+			} else {
+				c.log.Infof("speaker unmuted")
+			}
+		case ']':
+			if c.lastSpeaker != "" {
+				c.log.Infof("%v volume %+.0fdB", c.lastSpeaker, c.volume.adjustStation(c.lastSpeaker, volumeStep))
+			}
+		case '[':
+			if c.lastSpeaker != "" {
+				c.log.Infof("%v volume %+.0fdB", c.lastSpeaker, c.volume.adjustStation(c.lastSpeaker, -volumeStep))
+			}
 		default:
 		}
 
@@ -385,21 +811,18 @@ func (c *intercomClient) Run() {
 			break
 		}
 
-		if c.wantToBroadcast {
+		if c.wantsMic() && !c.hasMicOn {
+			c.log.Debugf("go c.startAudioBroadcast()...")
+			c.hasMicOn = true
+			go c.startAudioBroadcast()
+		}
+
+		if c.wantsCamera() || c.watchesForMotion() {
 			c.sendVideoCapture()
-			if !c.hasMicOn {
-				fmt.Println("go c.startAudioBroadcast()...")
-				go c.startAudioBroadcast()
-			}
-		} else {
-			if c.hasMicOn {
-				c.hasMicOn = false
-			}
-			if c.hasWebcamOn {
-				c.webcam.Close()
-				c.hasWebcamOn = false
-				c.ResetDisplayImg()
-			}
+		} else if c.hasWebcamOn {
+			c.webcam.Close()
+			c.hasWebcamOn = false
+			c.ResetDisplayImg()
 		}
 		c.draw()
 	}
@@ -0,0 +1,98 @@
+package intercom
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	meterWidth  = 8
+	meterHeight = 100
+	meterFloor  = -60
+
+	// meterExpiry drops a meter to zero once audio stops flowing through it
+	meterExpiry = 300 * time.Millisecond
+
+	// speakingLevel is the RMS, about -40dBFS, over which a station is shown as talking
+	speakingLevel = 0.01
+)
+
+var (
+	meterBackground = color.RGBA{R: 40, G: 40, B: 40}
+	meterGreen      = color.RGBA{G: 200}
+	meterYellow     = color.RGBA{R: 220, G: 200}
+	meterRed        = color.RGBA{R: 230}
+	speakingColor   = color.RGBA{R: 80, G: 230, B: 80}
+)
+
+// levelMeter holds the latest levels through one audio path
+type levelMeter struct {
+	rms     float64
+	peak    float64
+	updated time.Time
+}
+
+func (m *levelMeter) update(samples []int32) {
+	m.rms, m.peak = audio.Levels(samples)
+	m.updated = time.Now()
+}
+
+func (m levelMeter) current() (rms, peak float64) {
+	if time.Since(m.updated) > meterExpiry {
+		return 0, 0
+	}
+	return m.rms, m.peak
+}
+
+// meterFraction maps a level to how full its meter is, on a dB scale down to meterFloor
+func meterFraction(level float64) float64 {
+	db := audio.Decibels(level)
+	if math.IsInf(db, -1) || db < meterFloor {
+		return 0
+	}
+	return math.Min(1-db/meterFloor, 1)
+}
+
+func meterColor(peak float64) color.RGBA {
+	db := audio.Decibels(peak)
+	switch {
+	case db > -3:
+		return meterRed
+	case db > -12:
+		return meterYellow
+	}
+	return meterGreen
+}
+
+// drawMeter draws a vertical VU meter with its bottom left corner at x, y, filled
+// to the RMS level with a line at the peak
+func drawMeter(img *gocv.Mat, x, y int, label string, meter levelMeter) {
+	rms, peak := meter.current()
+
+	gocv.Rectangle(img, image.Rect(x, y-meterHeight, x+meterWidth, y), meterBackground, -1)
+
+	fill := int(meterFraction(rms) * meterHeight)
+	if fill > 0 {
+		gocv.Rectangle(img, image.Rect(x, y-fill, x+meterWidth, y), meterColor(peak), -1)
+	}
+
+	peakY := y - int(meterFraction(peak)*meterHeight)
+	if peakY < y {
+		gocv.Rectangle(img, image.Rect(x, peakY, x+meterWidth, peakY+1), meterColor(peak), -1)
+	}
+
+	gocv.PutText(img, label, image.Pt(x-2, y+12), gocv.FontHersheySimplex, 0.3, overlayTextColor, 1)
+}
+
+// drawMeters shows the outgoing mic and incoming speaker levels at the right edge of the screen
+func (c *intercomClient) drawMeters(img *gocv.Mat) {
+	bottom := screenHeight - 20
+	drawMeter(img, screenWidth-40, bottom, "mic", c.micMeter)
+	drawMeter(img, screenWidth-20, bottom, "spk", c.speakerMeter)
+}
@@ -0,0 +1,120 @@
+package intercom
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/3xcellent/intercom/proto"
+
+	"gocv.io/x/gocv"
+)
+
+var overlayTextColor = color.RGBA{R: 255, G: 255, B: 255}
+
+// toggleMicMute stops sending mic audio, the input stream stays open
+func (c *intercomClient) toggleMicMute() {
+	c.isMicMuted = !c.isMicMuted
+	if c.isMicMuted {
+		c.log.Infof("mic muted")
+	} else {
+		c.log.Infof("mic unmuted")
+	}
+	c.sendStatus()
+}
+
+// toggleCamera stops sending video, audio is still broadcast
+func (c *intercomClient) toggleCamera() {
+	c.isCameraOff = !c.isCameraOff
+	if c.isCameraOff {
+		c.log.Infof("camera off")
+	} else {
+		c.log.Infof("camera on")
+	}
+	c.sendStatus()
+}
+
+// togglePrivacy closes the webcam and mic devices entirely, the main loop and
+// startAudioBroadcast release them once they see isPrivate
+func (c *intercomClient) togglePrivacy() {
+	c.isPrivate = !c.isPrivate
+	if c.isPrivate {
+		c.log.Infof("privacy mode on")
+	} else {
+		c.log.Infof("privacy mode off")
+	}
+	c.sendStatus()
+}
+
+// sendStatus tells the other stations whether this station's mic and camera are on
+func (c *intercomClient) sendStatus() {
+	req := proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Status{
+			Status: &proto.Status{
+				MicMuted:  c.isMicMuted,
+				CameraOff: c.isCameraOff,
+				Privacy:   c.isPrivate,
+			},
+		},
+	}
+
+	if err := c.send(&req); err != nil {
+		c.log.With("type", "status").Errorf("Send error: %v", err)
+	}
+}
+
+func (c *intercomClient) setRoster(roster *proto.Roster) {
+	c.rosterMutex.Lock()
+	defer c.rosterMutex.Unlock()
+	c.roster = roster.Stations
+}
+
+// statusText describes a station's mic and camera, empty if both are on
+func statusText(status *proto.Status) string {
+	switch {
+	case status == nil:
+		return ""
+	case status.Privacy:
+		return "privacy"
+	case status.MicMuted && status.CameraOff:
+		return "muted, camera off"
+	case status.MicMuted:
+		return "muted"
+	case status.CameraOff:
+		return "audio only"
+	}
+	return ""
+}
+
+// drawRoster lists the other stations and their status, and this station's own status
+func (c *intercomClient) drawRoster(img *gocv.Mat) {
+	c.rosterMutex.Lock()
+	defer c.rosterMutex.Unlock()
+
+	line := 0
+	for _, station := range c.roster {
+		if station.Name == c.config.Name {
+			continue
+		}
+
+		text := station.Name
+		if status := statusText(station.Status); status != "" {
+			text += ": " + status
+		}
+
+		// the active speaker is highlighted, with a bar for how loud they are
+		textColor := overlayTextColor
+		y := 20 + line*18
+		if station.Level > speakingLevel {
+			textColor = speakingColor
+			height := 1 + int(meterFraction(float64(station.Level))*11)
+			gocv.Rectangle(img, image.Rect(2, y-height, 6, y), speakingColor, -1)
+		}
+		gocv.PutText(img, text, image.Pt(10, y), gocv.FontHersheySimplex, 0.5, textColor, 1)
+		line++
+	}
+
+	own := statusText(&proto.Status{MicMuted: c.isMicMuted, CameraOff: c.isCameraOff, Privacy: c.isPrivate})
+	if own != "" {
+		gocv.PutText(img, own, image.Pt(10, screenHeight-10), gocv.FontHersheySimplex, 0.5, overlayTextColor, 1)
+	}
+}
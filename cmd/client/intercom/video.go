@@ -0,0 +1,83 @@
+package intercom
+
+import (
+	"time"
+
+	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	// videoTileSize is the width and height in pixels of the tiles webcam video is sent in
+	videoTileSize = 32
+	// keyframeRequestHold is the least time between asking for a keyframe
+	keyframeRequestHold = time.Second
+)
+
+// matTypes is the gocv type of 8 bit pixels of each channel count up to video.MaxChannels
+var matTypes = map[int]gocv.MatType{
+	1: gocv.MatTypeCV8UC1,
+	2: gocv.MatTypeCV8UC2,
+	3: gocv.MatTypeCV8UC3,
+	4: gocv.MatTypeCV8UC4,
+}
+
+// sendVideoFrame sends a webcam frame as the tiles that changed since the last
+// one, falling back to raw pixels for frames the tile encoder can't take
+func (c *intercomClient) sendVideoFrame(img gocv.Mat) {
+	if img.Type() != gocv.MatTypeCV8UC3 {
+		c.sendImage(img)
+		return
+	}
+
+	frame, err := c.video.Frame(img.ToBytes(), img.Cols(), img.Rows())
+	if err != nil {
+		c.log.Errorf("Error encoding video: %v", err)
+		return
+	}
+
+	req := proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Image{Image: frame},
+	}
+	if err := c.send(&req); err != nil {
+		c.log.With("type", "image").Errorf("Send error: %v", err)
+	}
+}
+
+// decodeTiles rebuilds a tiled frame and returns its pixels, asking for a
+// keyframe if it is a delta this station does not have the frame for
+func (c *intercomClient) decodeTiles(img proto.Image) ([]byte, bool) {
+	tiles := make([]video.Tile, len(img.Tiles))
+	for i, tile := range img.Tiles {
+		tiles[i] = video.Tile{X: int(tile.X), Y: int(tile.Y), JPEG: tile.Jpeg}
+	}
+
+	pix, err := c.decoder.Decode(int(img.Width), int(img.Height), int(img.TileSize), tiles, img.Keyframe)
+	if err == video.ErrNoKeyframe {
+		c.requestKeyframe()
+		return nil, false
+	}
+	if err != nil {
+		c.log.Errorf("Error decoding video: %v", err)
+		c.decoder.Reset()
+		c.requestKeyframe()
+		return nil, false
+	}
+	return pix, true
+}
+
+func (c *intercomClient) requestKeyframe() {
+	if time.Since(c.lastKeyframeRequest) < keyframeRequestHold {
+		return
+	}
+	c.lastKeyframeRequest = time.Now()
+
+	req := proto.Broadcast{
+		BroadcastType: &proto.Broadcast_KeyframeRequest{KeyframeRequest: &proto.KeyframeRequest{}},
+	}
+	if err := c.send(&req); err != nil {
+		c.log.With("type", "keyframe_request").Errorf("Send error: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package intercom
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/3xcellent/intercom/logger"
+)
+
+const (
+	// volumeStep is how far each volume hotkey press moves, in dB
+	volumeStep = 3
+	// maxVolume bounds boosts, in dB
+	maxVolume = 12
+	// minVolume is the lowest level before it is as good as muted, in dB
+	minVolume = -60
+)
+
+// volumeSettings are the listening levels persisted between runs
+type volumeSettings struct {
+	// Master is the output volume in dB applied to all incoming audio
+	Master float64 `json:"master"`
+	// Muted silences all incoming audio
+	Muted bool `json:"muted"`
+	// Stations holds the gain in dB for each remote station by name
+	Stations map[string]float64 `json:"stations"`
+}
+
+// volumeControl guards the settings shared by the hotkeys, control API and playback
+type volumeControl struct {
+	mu       sync.Mutex
+	path     string
+	settings volumeSettings
+	log      *logger.Logger
+}
+
+// loadVolumeControl reads saved settings from path, starting at unity gain if there are none
+func loadVolumeControl(path string, log *logger.Logger) *volumeControl {
+	v := &volumeControl{
+		path:     path,
+		settings: volumeSettings{Stations: map[string]float64{}},
+		log:      log,
+	}
+	if path == "" {
+		return v
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return v
+	}
+	if err != nil {
+		v.log.Errorf("Error reading volume settings from: %v | %v", path, err)
+		return v
+	}
+	if err := json.Unmarshal(data, &v.settings); err != nil {
+		v.log.Errorf("Error reading volume settings from: %v | %v", path, err)
+	}
+	if v.settings.Stations == nil {
+		v.settings.Stations = map[string]float64{}
+	}
+	return v
+}
+
+// save writes the settings, the caller must hold mu
+func (v *volumeControl) save() {
+	if v.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(v.settings, "", "  ")
+	if err != nil {
+		v.log.Errorf("Error saving volume settings: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(v.path, data, 0644); err != nil {
+		v.log.Errorf("Error saving volume settings to: %v | %v", v.path, err)
+	}
+}
+
+// gain is the gain in dB for audio from station, and whether it is muted
+func (v *volumeControl) gain(station string) (float64, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.settings.Master + v.settings.Stations[station], v.settings.Muted
+}
+
+func (v *volumeControl) snapshot() volumeSettings {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	stations := make(map[string]float64, len(v.settings.Stations))
+	for name, db := range v.settings.Stations {
+		stations[name] = db
+	}
+	return volumeSettings{Master: v.settings.Master, Muted: v.settings.Muted, Stations: stations}
+}
+
+func (v *volumeControl) setMaster(db float64) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.settings.Master = clampVolume(db)
+	v.save()
+	return v.settings.Master
+}
+
+// adjustMaster moves the master volume by delta, reading and setting it
+// under one lock so a hotkey and the control API cannot lose each other's change
+func (v *volumeControl) adjustMaster(delta float64) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.settings.Master = clampVolume(v.settings.Master + delta)
+	v.save()
+	return v.settings.Master
+}
+
+func (v *volumeControl) setMuted(muted bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.settings.Muted = muted
+	v.save()
+}
+
+func (v *volumeControl) toggleMute() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.settings.Muted = !v.settings.Muted
+	v.save()
+	return v.settings.Muted
+}
+
+func (v *volumeControl) setStation(station string, db float64) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.settings.Stations[station] = clampVolume(db)
+	v.save()
+	return v.settings.Stations[station]
+}
+
+// adjustStation moves station's volume by delta, like adjustMaster
+func (v *volumeControl) adjustStation(station string, delta float64) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.settings.Stations[station] = clampVolume(v.settings.Stations[station] + delta)
+	v.save()
+	return v.settings.Stations[station]
+}
+
+func clampVolume(db float64) float64 {
+	return math.Max(math.Min(db, maxVolume), minVolume)
+}
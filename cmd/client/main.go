@@ -2,21 +2,85 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/3xcellent/intercom/cmd/client/intercom"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/video"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("How to run:\n\tintercom [camera ID] [path/to/background.img]")
+	hostname, _ := os.Hostname()
+	home, _ := os.UserHomeDir()
+
+	cfg := intercom.Config{}
+	flag.StringVar(&cfg.Name, "name", hostname, "station name shown to other stations")
+	flag.StringVar(&cfg.Room, "room", "", "room the station joins, empty for the server's default")
+	cfg.Server.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&cfg.ControlAddr, "control", "localhost:6001", "local control API address, empty to disable")
+	flag.StringVar(&cfg.SettingsFile, "settings", filepath.Join(home, ".intercom.json"), "file volume settings are saved to")
+	flag.IntVar(&cfg.FrameRate, "fps", 15, "most video frames sent per second")
+	flag.IntVar(&cfg.FrameWidth, "video-width", 640, "most video width sent, 0 for the camera's")
+	flag.IntVar(&cfg.FrameHeight, "video-height", 480, "most video height sent, 0 for the camera's")
+	flag.IntVar(&cfg.VideoQuality, "video-quality", 75, "JPEG quality of the video sent, 1 to 100")
+	flag.DurationVar(&cfg.KeyframeInterval, "keyframe-interval", 5*time.Second, "longest between sending the whole frame rather than only what changed")
+	flag.BoolVar(&cfg.Motion, "motion", false, "broadcast video whenever the camera sees motion")
+	motionRegion := flag.String("motion-region", "", "part of the frame watched for motion as x,y,width,height fractions, e.g. 0.25,0,0.5,1")
+	flag.Float64Var(&cfg.MotionConfig.Threshold, "motion-threshold", 0.02, "fraction of the region that has to change to be motion")
+	flag.DurationVar(&cfg.MotionCooldown, "motion-cooldown", 10*time.Second, "how long to keep broadcasting after motion stops")
+	flag.StringVar(&cfg.Chime, "chime", "", "WAV or AIFF file played when a doorbell rings")
+	flag.BoolVar(&cfg.RingFromStdin, "ring-stdin", false, "ring as a doorbell for every line read from stdin")
+	flag.IntVar(&cfg.InputSampleRate, "in-rate", 44100, "mic sample rate in Hz")
+	flag.IntVar(&cfg.OutputSampleRate, "out-rate", 44100, "speaker sample rate in Hz")
+	flag.IntVar(&cfg.InputChannels, "in-channels", 1, "mic channels")
+	flag.IntVar(&cfg.OutputChannels, "out-channels", 1, "speaker channels")
+	flag.BoolVar(&cfg.EchoCancel, "aec", false, "cancel the speaker's echo from the mic")
+	flag.IntVar(&cfg.EchoTaps, "aec-taps", 1024, "echo path length in mic samples the canceller models")
+	flag.BoolVar(&cfg.Duck, "duck", false, "turn the mic down while incoming audio plays (half duplex)")
+	flag.StringVar(&cfg.CapturePipeline, "capture", "", "mic processors, e.g. highpass:80,denoise,gate:-50,agc:-20,limit:-1")
+	flag.StringVar(&cfg.PlaybackPipeline, "playback", "", "speaker processors, e.g. eq:3000:4:1,gain:-6")
+	flag.BoolVar(&cfg.VAD, "vad", false, "skip sending silent audio while broadcasting")
+	flag.BoolVar(&cfg.VOX, "vox", false, "hands-free, broadcast whenever voice is detected")
+	flag.Float64Var(&cfg.VADConfig.Threshold, "vad-threshold", -45, "level in dBFS audio must exceed to count as voice")
+	flag.Float64Var(&cfg.VADConfig.MaxZeroCrossingRate, "vad-zcr", 0.25, "zero crossing rate above which quiet audio is treated as noise")
+	flag.DurationVar(&cfg.VADConfig.HangTime, "vad-hang", 500*time.Millisecond, "how long to keep sending after voice stops")
+	flag.StringVar(&cfg.AnnounceAudio, "announce", "", "WAV or AIFF file to broadcast instead of the mic, press [p] to play")
+	flag.StringVar(&cfg.AnnounceImages, "announce-image", "", "image or glob of images to broadcast during the announcement")
+	flag.BoolVar(&cfg.AnnounceOnStart, "announce-now", false, "play the announcement once connected")
+	logLevel := flag.String("log-level", "info", "least important messages logged: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Println("How to run:\n\tintercom [flags] [camera ID] [path/to/background.img]")
+		flag.PrintDefaults()
+		return
+	}
+	if cfg.FrameRate < 1 {
+		fmt.Fprintln(os.Stderr, "-fps must be at least 1")
+		os.Exit(2)
+	}
+	if cfg.EchoTaps < 1 {
+		fmt.Fprintln(os.Stderr, "-aec-taps must be at least 1")
+		os.Exit(2)
+	}
+	var err error
+	if cfg.Logger, err = logger.Parse(*logLevel, *logFormat); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if cfg.MotionConfig.Region, err = video.ParseRegion(*motionRegion); err != nil {
+		fmt.Printf("-motion-region: %v\n", err)
 		return
 	}
-	deviceID := os.Args[1]
-	filename := os.Args[2]
+	cfg.DeviceID = flag.Arg(0)
+	cfg.BackgroundImage = flag.Arg(1)
 
 	//TODO: handle os shutdown/break in context
-	client := intercom.CreateIntercomClient(context.Background(), deviceID, filename)
+	client := intercom.CreateIntercomClient(context.Background(), cfg)
 	client.Run()
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/3xcellent/intercom/dial"
+	"github.com/3xcellent/intercom/proto"
+	protobuf "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+const usage = `How to run:
+	intercomctl [flags] command [arguments]
+
+Commands:
+	stations                      list the connected streams and their stations
+	rooms                         list the rooms and the stations in them
+	move STATION ROOM             move a station to another room
+	kick STATION                  disconnect a station
+	mute STATION                  drop a station's audio on the server
+	unmute STATION                lift mute
+	announce TEXT...              show a message at every station
+	record start [NAME]           record the audio relayed by the server, NAME is the file on the server
+	record stop                   finish the recording
+	stats                         show the server's counters
+	settings [-fps N] [-log-level LEVEL]
+	                              show the server's runtime settings, changing those given
+
+Flags:`
+
+func main() {
+	var server dial.Config
+	server.RegisterFlags(flag.CommandLine)
+	token := flag.String("token", os.Getenv("INTERCOM_ADMIN_TOKEN"), "the server's -admin-token (default $INTERCOM_ADMIN_TOKEN)")
+	asJSON := flag.Bool("json", false, "print JSON instead of tables")
+	timeout := flag.Duration("timeout", 10*time.Second, "how long to wait for the server")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conn, err := server.Dial()
+	if err != nil {
+		fail(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+
+	reply, err := run(ctx, proto.NewIntercomAdminClient(conn), flag.Arg(0), flag.Args()[1:])
+	if err != nil {
+		fail(err)
+	}
+
+	if *asJSON {
+		err = printJSON(os.Stdout, reply)
+	} else {
+		err = printTable(os.Stdout, reply)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// run calls the admin service for a command and returns its reply
+func run(ctx context.Context, admin proto.IntercomAdminClient, command string, args []string) (protobuf.Message, error) {
+	switch command {
+	case "stations", "streams":
+		return admin.ListStreams(ctx, &proto.ListStreamsRequest{})
+
+	case "rooms":
+		return admin.ListRooms(ctx, &proto.ListRoomsRequest{})
+
+	case "move":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("move needs a station name and a room")
+		}
+		return admin.MoveStation(ctx, &proto.MoveRequest{Station: args[0], Room: args[1]})
+
+	case "kick":
+		station, err := stationArg(command, args)
+		if err != nil {
+			return nil, err
+		}
+		return admin.Kick(ctx, &proto.StationRequest{Station: station})
+
+	case "mute", "unmute":
+		station, err := stationArg(command, args)
+		if err != nil {
+			return nil, err
+		}
+		return admin.Mute(ctx, &proto.MuteRequest{Station: station, Muted: command == "mute"})
+
+	case "announce":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("announce needs the text to show")
+		}
+		return admin.Announce(ctx, &proto.Announcement{Text: strings.Join(args, " ")})
+
+	case "record":
+		switch {
+		case len(args) == 1 && args[0] == "start":
+			return admin.StartRecording(ctx, &proto.RecordRequest{})
+		case len(args) == 2 && args[0] == "start":
+			return admin.StartRecording(ctx, &proto.RecordRequest{Name: args[1]})
+		case len(args) == 1 && args[0] == "stop":
+			return admin.StopRecording(ctx, &proto.StopRecordingRequest{})
+		}
+		return nil, fmt.Errorf("record needs start [NAME] or stop")
+
+	case "stats":
+		return admin.GetStats(ctx, &proto.GetStatsRequest{})
+
+	case "settings":
+		flags := flag.NewFlagSet("settings", flag.ContinueOnError)
+		fps := flags.Int("fps", 0, "most video frames per second sent to each station")
+		logLevel := flags.String("log-level", "", "least important messages logged: debug, info, warn or error")
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if *fps == 0 && *logLevel == "" {
+			return admin.GetSettings(ctx, &proto.GetSettingsRequest{})
+		}
+		return admin.UpdateSettings(ctx, &proto.Settings{FrameRate: int32(*fps), LogLevel: *logLevel})
+	}
+	return nil, fmt.Errorf("unknown command %q, see intercomctl -h", command)
+}
+
+func stationArg(command string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%v needs a station name", command)
+	}
+	return args[0], nil
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "intercomctl: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/3xcellent/intercom/proto"
+	"github.com/golang/protobuf/jsonpb"
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+func printJSON(w io.Writer, reply protobuf.Message) error {
+	marshaler := jsonpb.Marshaler{EmitDefaults: true, Indent: "  "}
+	if err := marshaler.Marshal(w, reply); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// printTable prints a reply for people, replies with nothing in them print nothing
+func printTable(w io.Writer, reply protobuf.Message) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	switch reply := reply.(type) {
+	case *proto.StreamList:
+		fmt.Fprintln(tw, "ID\tSTATION\tROOM\tPEER\tCONNECTED\tSTATUS\tQUEUED")
+		for _, stream := range reply.Streams {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				stream.Id, stream.Station, orDash(stream.Room), stream.Peer, since(stream.ConnectedAt),
+				statusText(stream.Status, stream.ForceMuted), stream.Queued)
+		}
+
+	case *proto.RoomList:
+		fmt.Fprintln(tw, "ROOM\tSTATIONS\tBROADCASTING")
+		for _, room := range reply.Rooms {
+			fmt.Fprintf(tw, "%v\t%v\t%v\n", room.Name, strings.Join(room.Stations, ", "), orDash(room.Broadcasting))
+		}
+
+	case *proto.Recording:
+		fmt.Fprintf(tw, "PATH\t%v\n", reply.Path)
+		fmt.Fprintf(tw, "STARTED\t%v\n", time.Unix(reply.StartedAt, 0).Format(time.RFC3339))
+		fmt.Fprintf(tw, "LENGTH\t%v\n", time.Duration(reply.Seconds*float32(time.Second)).Round(time.Second))
+
+	case *proto.Stats:
+		fmt.Fprintf(tw, "STREAMS\t%v\n", reply.Streams)
+		fmt.Fprintf(tw, "STATIONS\t%v\n", reply.Stations)
+		fmt.Fprintf(tw, "UPTIME\t%v\n", time.Duration(reply.UptimeSeconds)*time.Second)
+		fmt.Fprintf(tw, "DROPPED FRAMES\t%v\n", reply.DroppedFrames)
+		fmt.Fprintf(tw, "SEND ERRORS\t%v\n", reply.SendErrors)
+		if reply.Recording != nil {
+			fmt.Fprintf(tw, "RECORDING\t%v\n", reply.Recording.Path)
+		}
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "TYPE\tRECEIVED\tBYTES\tSENT\tBYTES")
+		for _, m := range reply.Messages {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", m.Type, m.Received, m.ReceivedBytes, m.Sent, m.SentBytes)
+		}
+
+	case *proto.Settings:
+		fmt.Fprintf(tw, "FPS\t%v\n", reply.FrameRate)
+		fmt.Fprintf(tw, "LOG LEVEL\t%v\n", reply.LogLevel)
+	}
+	return tw.Flush()
+}
+
+// statusText describes a station's status the way the client's station list does
+func statusText(status *proto.Status, forceMuted bool) string {
+	if forceMuted && status != nil {
+		// the server reports a force muted station as muted, which is said below
+		unmuted := *status
+		unmuted.MicMuted = false
+		status = &unmuted
+	}
+
+	var parts []string
+	switch {
+	case status == nil:
+	case status.Privacy:
+		parts = append(parts, "privacy")
+	case status.MicMuted && status.CameraOff:
+		parts = append(parts, "muted, camera off")
+	case status.MicMuted:
+		parts = append(parts, "muted")
+	case status.CameraOff:
+		parts = append(parts, "audio only")
+	}
+	if forceMuted {
+		parts = append(parts, "muted by admin")
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// orDash shows an empty field as a dash, so the table's columns line up
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func since(unix int64) string {
+	return time.Since(time.Unix(unix, 0)).Round(time.Second).String()
+}
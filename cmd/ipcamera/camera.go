@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/media"
+)
+
+// rtpChannel is the interleaved channel asked for RTP, RTCP comes on the one after
+const rtpChannel = 0
+
+// watchCamera plays the camera's stream, passing each JPEG frame to handle,
+// and reconnects after retry whenever the stream ends, until ctx is done
+func watchCamera(ctx context.Context, u *url.URL, retry time.Duration, log *logger.Logger, handle func(jpeg []byte)) {
+	for {
+		err := playCamera(ctx, u, log, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("camera stream ended: %v, reconnecting in %v", err, retry)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// playCamera plays the camera's MJPEG stream until it fails or ctx is done
+func playCamera(ctx context.Context, u *url.URL, log *logger.Logger, handle func(jpeg []byte)) error {
+	c, err := dialRTSP(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	// the connection is closed to stop reading when ctx is done
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			c.teardown()
+			c.close()
+		}
+	}()
+
+	// requests carry the URL without the credentials in it
+	base := *u
+	base.User = nil
+	uri := base.String()
+
+	resp, err := c.do("DESCRIBE", uri, map[string]string{"Accept": "application/sdp"})
+	if err != nil {
+		return err
+	}
+	if resp.status != 200 {
+		return fmt.Errorf("DESCRIBE: %v %v", resp.status, resp.reason)
+	}
+	contentBase := resp.header.Get("Content-Base")
+	if contentBase == "" {
+		contentBase = resp.header.Get("Content-Location")
+	}
+	if contentBase == "" {
+		contentBase = uri
+	}
+	track, err := parseSDP(resp.body, contentBase)
+	if err != nil {
+		return err
+	}
+
+	transport := fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%v-%v", rtpChannel, rtpChannel+1)
+	resp, err = c.do("SETUP", track.control, map[string]string{"Transport": transport})
+	if err != nil {
+		return err
+	}
+	if resp.status != 200 {
+		return fmt.Errorf("SETUP: %v %v", resp.status, resp.reason)
+	}
+	session, timeout := sessionID(resp.header.Get("Session"))
+	channel := interleavedChannel(resp.header.Get("Transport"), rtpChannel)
+	c.mu.Lock()
+	c.session = session
+	c.mu.Unlock()
+
+	resp, err = c.do("PLAY", track.aggregate, map[string]string{"Range": "npt=0.000-"})
+	if err != nil {
+		return err
+	}
+	if resp.status != 200 {
+		return fmt.Errorf("PLAY: %v %v", resp.status, resp.reason)
+	}
+	c.mu.Lock()
+	c.playing = track.aggregate
+	c.mu.Unlock()
+	log.Infof("playing %v", track.control)
+
+	// the session ends if the camera hears nothing for its timeout
+	go func() {
+		keepAlive := time.NewTicker(timeout / 2)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-keepAlive.C:
+				if _, err := c.write("OPTIONS", uri, nil); err != nil {
+					log.Debugf("keep-alive error: %v", err)
+				}
+			}
+		}
+	}()
+
+	frames := &jpegAssembler{payloadType: track.payload}
+	warned := false
+	for {
+		packet, err := c.readPacket(channel)
+		if err != nil {
+			return err
+		}
+		rtp, ok := media.ParseRTP(packet)
+		if !ok {
+			continue
+		}
+		frame, err := frames.push(rtp)
+		if err != nil && !warned {
+			log.Warnf("cannot read camera video: %v", err)
+			warned = true
+		}
+		if frame != nil {
+			handle(frame)
+		}
+	}
+}
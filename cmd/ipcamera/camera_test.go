@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+)
+
+// rtspRequest is a request the stand-in camera received
+type rtspRequest struct {
+	method, uri string
+	header      textproto.MIMEHeader
+}
+
+// testCamera stands in for an IP camera serving MJPEG over RTSP, which
+// asks for digest credentials and sends its frames interleaved once played
+type testCamera struct {
+	t        *testing.T
+	listener net.Listener
+	// frames are the scans of the JPEG frames sent once played
+	frames [][]byte
+	// requests are those received, in order
+	requests chan rtspRequest
+	// done is closed once the connection is over
+	done chan struct{}
+}
+
+func newTestCamera(t *testing.T, frames [][]byte) *testCamera {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	c := &testCamera{t: t, listener: listener, frames: frames, requests: make(chan rtspRequest, 10), done: make(chan struct{})}
+	go c.serve()
+	return c
+}
+
+func (c *testCamera) url() string {
+	return "rtsp://" + c.listener.Addr().String() + "/stream"
+}
+
+func (c *testCamera) serve() {
+	defer close(c.done)
+	conn, err := c.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := textproto.NewReader(bufio.NewReader(conn))
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		start := strings.Fields(line)
+		header, err := r.ReadMIMEHeader()
+		if err != nil || len(start) != 3 {
+			c.t.Errorf("camera received %q", line)
+			return
+		}
+		req := rtspRequest{method: start[0], uri: start[1], header: header}
+		c.requests <- req
+
+		respond := func(status, body string, headers ...string) {
+			fmt.Fprintf(w, "RTSP/1.0 %v\r\nCSeq: %v\r\n", status, header.Get("CSeq"))
+			for _, h := range headers {
+				fmt.Fprintf(w, "%v\r\n", h)
+			}
+			if body != "" {
+				fmt.Fprintf(w, "Content-Length: %v\r\n", len(body))
+			}
+			fmt.Fprintf(w, "\r\n%v", body)
+			w.Flush()
+		}
+		if !c.authorized(req) {
+			respond("401 Unauthorized", "", `WWW-Authenticate: Basic realm="camera"`,
+				`WWW-Authenticate: Digest realm="camera", nonce="0a4f113b"`)
+			continue
+		}
+
+		switch req.method {
+		case "DESCRIBE":
+			sdp := "v=0\r\n" +
+				"s=camera\r\n" +
+				"a=control:*\r\n" +
+				"m=audio 0 RTP/AVP 0\r\n" +
+				"a=control:trackID=0\r\n" +
+				"m=video 0 RTP/AVP 96\r\n" +
+				"a=rtpmap:96 JPEG/90000\r\n" +
+				"a=control:trackID=1\r\n"
+			respond("200 OK", sdp, "Content-Base: "+c.url()+"/", "Content-Type: application/sdp")
+		case "SETUP":
+			respond("200 OK", "", "Session: 12345678;timeout=60", "Transport: RTP/AVP/TCP;unicast;interleaved=2-3")
+		case "PLAY":
+			respond("200 OK", "", "Session: 12345678")
+			for i, scan := range c.frames {
+				for _, packet := range packetize(scan, 1, 75, 0, nil, uint32(i*3000), 500) {
+					data := packet.Marshal()
+					w.Write([]byte{'$', 2, byte(len(data) >> 8), byte(len(data))})
+					w.Write(data)
+					// RTCP on the channel after, to be skipped
+					w.Write([]byte{'$', 3, 0, 4, 0x80, 200, 0, 0})
+				}
+			}
+			w.Flush()
+		case "TEARDOWN":
+			respond("200 OK", "")
+			return
+		default:
+			respond("200 OK", "")
+		}
+	}
+}
+
+// authorized checks a request's answer to the camera's digest challenge
+func (c *testCamera) authorized(req rtspRequest) bool {
+	auth := req.header.Get("Authorization")
+	if auth == "" {
+		return false
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(strings.TrimPrefix(auth, "Digest "), ", ") {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			params[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+	hash := func(s string) string { return fmt.Sprintf("%x", md5.Sum([]byte(s))) }
+	want := hash(hash("admin:camera:secret") + ":0a4f113b:" + hash(req.method+":"+req.uri))
+	if params["username"] != "admin" || params["uri"] != req.uri || params["response"] != want {
+		c.t.Errorf("%v answered the challenge with %q", req.method, auth)
+		return false
+	}
+	return true
+}
+
+func TestPlayCamera(t *testing.T) {
+	var want, scans [][]byte
+	for shade := 0; shade < 3; shade++ {
+		frame, _, scan := testJPEG(t, 75, uint8(shade*100))
+		want = append(want, frame)
+		scans = append(scans, scan)
+	}
+	camera := newTestCamera(t, scans)
+	u, err := url.Parse(strings.Replace(camera.url(), "rtsp://", "rtsp://admin:secret@", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	frames := make(chan []byte, len(want))
+	played := make(chan error, 1)
+	go func() {
+		played <- playCamera(ctx, u, logger.New(ioutil.Discard, logger.Error, logger.Text), func(jpeg []byte) {
+			frames <- jpeg
+		})
+	}()
+
+	for i := range want {
+		select {
+		case frame := <-frames:
+			samePicture(t, frame, want[i])
+		case err := <-played:
+			t.Fatalf("stopped playing after %v frames: %v", i, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %v frames arrived", i)
+		}
+	}
+	cancel()
+	select {
+	case <-played:
+	case <-time.After(5 * time.Second):
+		t.Fatal("still playing once cancelled")
+	}
+	<-camera.done
+	close(camera.requests)
+
+	// each request is challenged once, then the challenge is answered every time
+	base := camera.url()
+	wantRequests := []struct{ method, uri string }{
+		{"DESCRIBE", base},
+		{"DESCRIBE", base},
+		{"SETUP", base + "/trackID=1"},
+		{"PLAY", base + "/"},
+		{"TEARDOWN", base + "/"},
+	}
+	var got []rtspRequest
+	for req := range camera.requests {
+		got = append(got, req)
+	}
+	if len(got) != len(wantRequests) {
+		t.Fatalf("camera received %v requests, want %v", len(got), len(wantRequests))
+	}
+	for i, req := range got {
+		if req.method != wantRequests[i].method || req.uri != wantRequests[i].uri {
+			t.Errorf("request %v is %v %v, want %v %v", i, req.method, req.uri, wantRequests[i].method, wantRequests[i].uri)
+		}
+	}
+	if transport := got[2].header.Get("Transport"); !strings.Contains(transport, "interleaved=0-1") {
+		t.Errorf("SETUP asked for transport %q", transport)
+	}
+	for _, req := range got[3:] {
+		if session := req.header.Get("Session"); session != "12345678" {
+			t.Errorf("%v in session %q", req.method, session)
+		}
+	}
+}
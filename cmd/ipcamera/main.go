@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/3xcellent/intercom/broadcast"
+	"github.com/3xcellent/intercom/dial"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
+	"google.golang.org/grpc/metadata"
+)
+
+func main() {
+	var server dial.Config
+	server.RegisterFlags(flag.CommandLine)
+	name := flag.String("name", "camera", "station name the camera joins the intercom as")
+	room := flag.String("room", "", "room the camera joins, empty for the server's default")
+	frameRate := flag.Int("fps", 15, "most video frames sent per second")
+	frameWidth := flag.Int("video-width", 640, "most video width sent, 0 for the camera's")
+	frameHeight := flag.Int("video-height", 480, "most video height sent, 0 for the camera's")
+	quality := flag.Int("video-quality", 75, "JPEG quality of the video sent, 1 to 100")
+	keyframeInterval := flag.Duration("keyframe-interval", 5*time.Second, "longest between sending the whole frame rather than only what changed")
+	motion := flag.Bool("motion", false, "only broadcast video when the camera sees motion")
+	motionRegion := flag.String("motion-region", "", "part of the frame watched for motion as x,y,width,height fractions, e.g. 0.25,0,0.5,1")
+	motionThreshold := flag.Float64("motion-threshold", 0.02, "fraction of the region that has to change to be motion")
+	motionCooldown := flag.Duration("motion-cooldown", 10*time.Second, "how long to keep broadcasting after motion stops")
+	retry := flag.Duration("retry", 5*time.Second, "how long to wait before reconnecting to the camera")
+	logLevel := flag.String("log-level", "info", "least important messages logged: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Println("How to run:\n\tipcamera [flags] rtsp://[user:password@]camera[:port]/path")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if *frameRate < 1 {
+		fmt.Fprintln(os.Stderr, "-fps must be at least 1")
+		os.Exit(2)
+	}
+	log, err := logger.Parse(*logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	camera, err := url.Parse(flag.Arg(0))
+	if err != nil || camera.Scheme != "rtsp" || camera.Host == "" {
+		fmt.Fprintf(os.Stderr, "%q is not an rtsp:// URL\n", flag.Arg(0))
+		os.Exit(2)
+	}
+	region, err := video.ParseRegion(*motionRegion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-motion-region: %v\n", err)
+		os.Exit(2)
+	}
+
+	conn, err := server.Dial()
+	if err != nil {
+		log.Errorf("cannot connect to %v: %v", server.Addr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	if *room != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "room", *room)
+	}
+	stream, err := proto.NewIntercomClient(conn).Connect(ctx)
+	if err != nil {
+		log.Errorf("cannot connect to %v: %v", server.Addr, err)
+		os.Exit(1)
+	}
+
+	videoConfig := broadcast.Config{
+		FrameRate:        *frameRate,
+		TileSize:         videoTileSize,
+		Quality:          *quality,
+		KeyframeInterval: *keyframeInterval,
+		MotionCooldown:   *motionCooldown,
+	}
+	if *motion {
+		videoConfig.Motion = &video.MotionConfig{Region: region, Threshold: *motionThreshold}
+	}
+	s := &station{
+		log:         log,
+		name:        *name,
+		frameWidth:  *frameWidth,
+		frameHeight: *frameHeight,
+		video:       broadcast.NewVideo(videoConfig),
+		server:      stream,
+	}
+	s.sendStatus()
+	log.Infof("joined %v as %v", server.Addr, *name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watched := make(chan struct{})
+	go func() {
+		watchCamera(ctx, camera, *retry, log.With("camera", camera.Host), s.handleFrame)
+		close(watched)
+	}()
+
+	received := make(chan error, 1)
+	go func() { received <- s.receive() }()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case sig := <-signals:
+		log.Infof("%v, stopping", sig)
+	case err := <-received:
+		log.Errorf("intercom stream ended: %v", err)
+		exitCode = 1
+	}
+
+	cancel()
+	<-watched
+	os.Exit(exitCode)
+}
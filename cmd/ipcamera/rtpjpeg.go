@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/3xcellent/intercom/media"
+)
+
+const (
+	// payloadJPEG is JPEG's static RTP payload type, RFC 3551
+	payloadJPEG = 26
+
+	jpegHeaderSize = 8
+	// restartHeaderSize and tablesHeaderSize are the headers RFC 2435 adds for
+	// types 64 to 127 and for Q 128 to 255
+	restartHeaderSize = 4
+	tablesHeaderSize  = 4
+
+	// maxJPEGFrame is the most a rebuilt frame may grow to, a camera that
+	// never sends a marker bit would otherwise have it grow without end
+	maxJPEGFrame = 4 << 20
+)
+
+var errNoTables = errors.New("JPEG frame refers to quantization tables never sent")
+
+// jpegAssembler puts JPEG frames back together from their RTP packets.  RFC
+// 2435 strips the headers from the JPEG and sends only what varies between
+// cameras, the headers are rebuilt from that and the standard tables.
+type jpegAssembler struct {
+	// payloadType is the one the camera sends JPEG as
+	payloadType int
+
+	// frame is the JPEG being rebuilt, nil while waiting for the first packet of the next
+	frame     []byte
+	timestamp uint32
+	// scanStart is where the scan data starts in frame, fragment offsets count from it
+	scanStart int
+	// tables are the luma and chroma quantization tables the camera last sent
+	tables []byte
+}
+
+// push adds a packet and returns the JPEG it completes, if it does.  Frames
+// missing a packet, or growing past maxJPEGFrame, are dropped.
+func (a *jpegAssembler) push(packet media.Packet) ([]byte, error) {
+	p := packet.Payload
+	if packet.PayloadType != a.payloadType || len(p) < jpegHeaderSize {
+		return nil, nil
+	}
+	offset := int(p[1])<<16 | int(p[2])<<8 | int(p[3])
+	jpegType, q := int(p[4]), int(p[5])
+	width, height := int(p[6])*8, int(p[7])*8
+	p = p[jpegHeaderSize:]
+
+	restartInterval := 0
+	if jpegType >= 64 && jpegType < 128 {
+		if len(p) < restartHeaderSize {
+			return nil, nil
+		}
+		restartInterval = int(binary.BigEndian.Uint16(p))
+		p = p[restartHeaderSize:]
+		jpegType -= 64
+	}
+	if jpegType > 1 {
+		a.frame = nil
+		return nil, fmt.Errorf("unsupported JPEG type %v", jpegType)
+	}
+
+	if offset == 0 {
+		var luma, chroma []byte
+		if q >= 128 {
+			if len(p) < tablesHeaderSize {
+				return nil, nil
+			}
+			precision, length := p[1], int(binary.BigEndian.Uint16(p[2:]))
+			p = p[tablesHeaderSize:]
+			if len(p) < length {
+				return nil, nil
+			}
+			switch {
+			case precision != 0:
+				a.frame = nil
+				return nil, errors.New("unsupported 16 bit JPEG quantization tables")
+			case length >= 128:
+				a.tables = append([]byte(nil), p[:128]...)
+			case length >= 64:
+				// one table serves luma and chroma
+				a.tables = append(append([]byte(nil), p[:64]...), p[:64]...)
+			case length == 0 && a.tables != nil:
+				// the tables are the ones sent before
+			default:
+				a.frame = nil
+				return nil, errNoTables
+			}
+			p = p[length:]
+			luma, chroma = a.tables[:64], a.tables[64:]
+		} else {
+			luma, chroma = scaledTables(q)
+		}
+
+		a.frame = jpegHeaders(jpegType, width, height, luma, chroma, restartInterval)
+		a.timestamp = packet.Timestamp
+		a.scanStart = len(a.frame)
+	} else if a.frame == nil || packet.Timestamp != a.timestamp || offset != len(a.frame)-a.scanStart {
+		// a packet of the frame was lost, wait for the next frame
+		a.frame = nil
+		return nil, nil
+	}
+
+	if len(a.frame)+len(p) > maxJPEGFrame {
+		a.frame = nil
+		return nil, fmt.Errorf("JPEG frame larger than %v bytes", maxJPEGFrame)
+	}
+	a.frame = append(a.frame, p...)
+	if !packet.Marker {
+		return nil, nil
+	}
+	frame := a.frame
+	a.frame = nil
+	if !bytes.HasSuffix(frame, []byte{0xff, 0xd9}) {
+		frame = append(frame, 0xff, 0xd9)
+	}
+	return frame, nil
+}
+
+// lumaQuant and chromaQuant are the JPEG standard's quantization tables, in
+// zig-zag order, that Q 1 to 99 scale
+var (
+	lumaQuant = [64]byte{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	}
+	chromaQuant = [64]byte{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	}
+)
+
+// scaledTables are the quantization tables for Q 1 to 99, scaled as the IJG encoder does
+func scaledTables(q int) ([]byte, []byte) {
+	if q < 1 {
+		q = 1
+	} else if q > 99 {
+		q = 99
+	}
+	factor := 200 - 2*q
+	if q < 50 {
+		factor = 5000 / q
+	}
+
+	luma, chroma := make([]byte, 64), make([]byte, 64)
+	for i := range luma {
+		luma[i] = scaleQuant(lumaQuant[i], factor)
+		chroma[i] = scaleQuant(chromaQuant[i], factor)
+	}
+	return luma, chroma
+}
+
+func scaleQuant(v byte, factor int) byte {
+	n := (int(v)*factor + 50) / 100
+	switch {
+	case n < 1:
+		return 1
+	case n > 255:
+		return 255
+	}
+	return byte(n)
+}
+
+// huffmanTable is a Huffman table as it is written in a DHT segment
+type huffmanTable struct {
+	// class is the table class, DC 0 or AC 1, in the high nibble and its id in the low
+	class   byte
+	counts  [16]byte
+	symbols []byte
+}
+
+// huffmanTables are the JPEG standard's, section K.3, which RFC 2435 frames are coded with
+var huffmanTables = []huffmanTable{
+	// luminance DC
+	{0x00, [16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+	// luminance AC
+	{0x10, [16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125}, []byte{
+		0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+		0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+		0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+		0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+		0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+		0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+		0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+		0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+		0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+		0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+		0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+		0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+		0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+		0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+		0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+		0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+		0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+		0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+		0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+		0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+		0xf9, 0xfa,
+	}},
+	// chrominance DC
+	{0x01, [16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0}, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+	// chrominance AC
+	{0x11, [16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119}, []byte{
+		0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+		0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+		0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+		0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+		0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+		0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+		0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+		0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+		0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+		0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+		0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+		0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+		0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+		0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+		0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+		0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+		0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+		0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+		0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+		0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+		0xf9, 0xfa,
+	}},
+}
+
+// jpegHeaders rebuilds the headers of a JPEG frame up to its scan data.
+// Type 0 is 4:2:2 and type 1 4:2:0, both with the luma and two chroma
+// components RFC 2435 numbers 0, 1 and 2.
+func jpegHeaders(jpegType, width, height int, luma, chroma []byte, restartInterval int) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xff, 0xd8})
+	writeSegment(&b, 0xdb, append([]byte{0}, luma...))
+	writeSegment(&b, 0xdb, append([]byte{1}, chroma...))
+
+	lumaSampling := byte(0x21)
+	if jpegType == 1 {
+		lumaSampling = 0x22
+	}
+	writeSegment(&b, 0xc0, []byte{
+		8, byte(height >> 8), byte(height), byte(width >> 8), byte(width), 3,
+		0, lumaSampling, 0,
+		1, 0x11, 1,
+		2, 0x11, 1,
+	})
+
+	for _, t := range huffmanTables {
+		data := append([]byte{t.class}, t.counts[:]...)
+		writeSegment(&b, 0xc4, append(data, t.symbols...))
+	}
+	if restartInterval > 0 {
+		writeSegment(&b, 0xdd, []byte{byte(restartInterval >> 8), byte(restartInterval)})
+	}
+	writeSegment(&b, 0xda, []byte{3, 0, 0x00, 1, 0x11, 2, 0x11, 0, 63, 0})
+	return b.Bytes()
+}
+
+// writeSegment writes a JPEG marker segment, its length counting itself
+func writeSegment(b *bytes.Buffer, marker byte, data []byte) {
+	length := len(data) + 2
+	b.Write([]byte{0xff, marker, byte(length >> 8), byte(length)})
+	b.Write(data)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/3xcellent/intercom/media"
+)
+
+const (
+	testWidth  = 64
+	testHeight = 48
+)
+
+// testJPEG encodes a test image as a camera would, returning the JPEG with
+// its quantization tables and scan data, what RFC 2435 sends of it
+func testJPEG(t *testing.T, quality int, shade uint8) (frame, tables, scan []byte) {
+	img := image.NewRGBA(image.Rect(0, 0, testWidth, testHeight))
+	for y := 0; y < testHeight; y++ {
+		for x := 0; x < testWidth; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(x * y * 7), shade, 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	frame = b.Bytes()
+
+	for i := 2; i+4 <= len(frame); {
+		marker, length := frame[i+1], int(frame[i+2])<<8|int(frame[i+3])
+		segment := frame[i+4 : i+2+length]
+		switch marker {
+		case 0xdb:
+			// one or more 8 bit tables, each after its id
+			for ; len(segment) >= 65; segment = segment[65:] {
+				tables = append(tables, segment[1:65]...)
+			}
+		case 0xda:
+			scan = frame[i+2+length : len(frame)-2]
+			return frame, tables, scan
+		}
+		i += 2 + length
+	}
+	t.Fatal("no scan in the test JPEG")
+	return nil, nil, nil
+}
+
+// packetize splits scan data into RTP packets of at most size bytes of it,
+// with the RFC 2435 headers for jpegType and q, and tables in the first
+// packet if q is 128 or more
+func packetize(scan []byte, jpegType, q, restartInterval int, tables []byte, timestamp uint32, size int) []media.Packet {
+	var packets []media.Packet
+	for offset := 0; offset < len(scan); offset += size {
+		end := offset + size
+		if end > len(scan) {
+			end = len(scan)
+		}
+		p := []byte{0, byte(offset >> 16), byte(offset >> 8), byte(offset), byte(jpegType), byte(q), testWidth / 8, testHeight / 8}
+		if jpegType >= 64 {
+			p = append(p, byte(restartInterval>>8), byte(restartInterval), 0xff, 0xff)
+		}
+		if offset == 0 && q >= 128 {
+			p = append(p, 0, 0, byte(len(tables)>>8), byte(len(tables)))
+			p = append(p, tables...)
+		}
+		packets = append(packets, media.Packet{
+			PayloadType: 96,
+			Marker:      end == len(scan),
+			Timestamp:   timestamp,
+			Payload:     append(p, scan[offset:end]...),
+		})
+	}
+	return packets
+}
+
+// samePicture fails unless got decodes to the picture want does
+func samePicture(t *testing.T, got, want []byte) {
+	t.Helper()
+	gotImage, err := jpeg.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("rebuilt JPEG does not decode: %v", err)
+	}
+	wantImage, err := jpeg.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotImage.Bounds() != wantImage.Bounds() {
+		t.Fatalf("rebuilt JPEG is %v, want %v", gotImage.Bounds(), wantImage.Bounds())
+	}
+	bounds := wantImage.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if gotImage.At(x, y) != wantImage.At(x, y) {
+				t.Fatalf("pixel %v,%v is %v, want %v", x, y, gotImage.At(x, y), wantImage.At(x, y))
+			}
+		}
+	}
+}
+
+// pushAll pushes packets and returns the frames they complete
+func pushAll(t *testing.T, a *jpegAssembler, packets []media.Packet) [][]byte {
+	t.Helper()
+	var frames [][]byte
+	for _, packet := range packets {
+		frame, err := a.push(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame != nil {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+func TestJPEGAssembler(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality int
+		// q is sent instead of the quality when it is 128 or more, with the tables
+		q               int
+		restartInterval int
+	}{
+		{name: "standard tables", quality: 75, q: 75},
+		{name: "low quality", quality: 20, q: 20},
+		{name: "tables in band", quality: 90, q: 255},
+		{name: "restart interval", quality: 50, q: 50, restartInterval: 100},
+		{name: "restart interval and tables in band", quality: 60, q: 200, restartInterval: 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, tables, scan := testJPEG(t, test.quality, 0)
+			jpegType := 1
+			if test.restartInterval > 0 {
+				jpegType += 64
+			}
+			a := &jpegAssembler{payloadType: 96}
+			frames := pushAll(t, a, packetize(scan, jpegType, test.q, test.restartInterval, tables, 1, 200))
+			if len(frames) != 1 {
+				t.Fatalf("%v frames, want 1", len(frames))
+			}
+			samePicture(t, frames[0], want)
+
+			dri := []byte{0xff, 0xdd, 0, 4, byte(test.restartInterval >> 8), byte(test.restartInterval)}
+			if got := bytes.Contains(frames[0], dri); got != (test.restartInterval > 0) {
+				t.Errorf("restart interval segment written %v, want %v", got, test.restartInterval > 0)
+			}
+		})
+	}
+}
+
+func TestJPEGAssemblerTablesSentBefore(t *testing.T) {
+	first, tables, scan := testJPEG(t, 90, 0)
+	second, _, secondScan := testJPEG(t, 90, 200)
+	a := &jpegAssembler{payloadType: 96}
+
+	// a frame that refers to tables before any were sent can't be rebuilt
+	if _, err := a.push(packetize(scan, 1, 255, 0, nil, 1, 200)[0]); err != errNoTables {
+		t.Errorf("tables never sent: %v, want %v", err, errNoTables)
+	}
+
+	frames := pushAll(t, a, packetize(scan, 1, 255, 0, tables, 2, 200))
+	frames = append(frames, pushAll(t, a, packetize(secondScan, 1, 255, 0, nil, 3, 200))...)
+	if len(frames) != 2 {
+		t.Fatalf("%v frames, want 2", len(frames))
+	}
+	samePicture(t, frames[0], first)
+	samePicture(t, frames[1], second)
+}
+
+func TestJPEGAssemblerLostPacket(t *testing.T) {
+	want, _, scan := testJPEG(t, 75, 0)
+	next, _, nextScan := testJPEG(t, 75, 200)
+	tests := []struct {
+		name string
+		lose int
+	}{
+		{"first", 0},
+		{"middle", 2},
+		{"last", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := &jpegAssembler{payloadType: 96}
+			lossy := packetize(scan, 1, 75, 0, nil, 1, 200)
+			lose := test.lose
+			if lose < 0 {
+				lose = len(lossy) - 1
+			}
+			lossy = append(lossy[:lose], lossy[lose+1:]...)
+			if frames := pushAll(t, a, lossy); len(frames) != 0 {
+				t.Fatalf("frame missing its %v packet was rebuilt", test.name)
+			}
+
+			// the next frame comes through whole
+			frames := pushAll(t, a, packetize(nextScan, 1, 75, 0, nil, 2, 200))
+			if len(frames) != 1 {
+				t.Fatalf("%v frames after the lossy one, want 1", len(frames))
+			}
+			samePicture(t, frames[0], next)
+		})
+	}
+
+	t.Run("end of one frame and start of the next", func(t *testing.T) {
+		a := &jpegAssembler{payloadType: 96}
+		first := packetize(scan, 1, 75, 0, nil, 1, 200)
+		second := packetize(nextScan, 1, 75, 0, nil, 2, 200)
+		// the first frame's marker packet and the second's first packet are lost
+		packets := append(first[:len(first)-1:len(first)-1], second[1:]...)
+		packets = append(packets, packetize(scan, 1, 75, 0, nil, 3, 200)...)
+		frames := pushAll(t, a, packets)
+		if len(frames) != 1 {
+			t.Fatalf("%v frames, want only the last", len(frames))
+		}
+		samePicture(t, frames[0], want)
+	})
+}
+
+func TestJPEGAssemblerTooLarge(t *testing.T) {
+	want, _, scan := testJPEG(t, 75, 0)
+	a := &jpegAssembler{payloadType: 96}
+	var err error
+	for _, packet := range packetize(make([]byte, maxJPEGFrame), 1, 75, 0, nil, 1, 60000) {
+		var frame []byte
+		if frame, err = a.push(packet); frame != nil {
+			t.Fatal("rebuilt a frame larger than the limit")
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatal("no error for a frame larger than the limit")
+	}
+	if a.frame != nil {
+		t.Fatalf("kept %v bytes of the frame larger than the limit", len(a.frame))
+	}
+
+	// the next frame comes through whole
+	frames := pushAll(t, a, packetize(scan, 1, 75, 0, nil, 2, 200))
+	if len(frames) != 1 {
+		t.Fatalf("%v frames after the one too large, want 1", len(frames))
+	}
+	samePicture(t, frames[0], want)
+}
+
+func TestJPEGAssemblerRejected(t *testing.T) {
+	_, _, scan := testJPEG(t, 75, 0)
+	tests := []struct {
+		name   string
+		packet media.Packet
+		err    bool
+	}{
+		{"other payload type", media.Packet{PayloadType: 97, Marker: true, Payload: packetize(scan, 1, 75, 0, nil, 1, len(scan))[0].Payload}, false},
+		{"short header", media.Packet{PayloadType: 96, Marker: true, Payload: []byte{0, 0, 0, 0, 1}}, false},
+		{"unsupported type", packetize(scan, 2, 75, 0, nil, 1, len(scan))[0], true},
+		{"short restart header", media.Packet{PayloadType: 96, Marker: true, Payload: []byte{0, 0, 0, 0, 65, 75, 8, 6, 0}}, false},
+		{"16 bit tables", media.Packet{PayloadType: 96, Marker: true, Payload: append([]byte{0, 0, 0, 0, 1, 255, 8, 6, 0, 1, 0, 128}, make([]byte, 128)...)}, true},
+		{"tables cut short", media.Packet{PayloadType: 96, Marker: true, Payload: append([]byte{0, 0, 0, 0, 1, 255, 8, 6, 0, 0, 0, 128}, make([]byte, 64)...)}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := &jpegAssembler{payloadType: 96}
+			frame, err := a.push(test.packet)
+			if frame != nil || (err != nil) != test.err {
+				t.Errorf("got a frame %v and error %v, want no frame and an error %v", frame != nil, err, test.err)
+			}
+		})
+	}
+}
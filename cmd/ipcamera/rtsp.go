@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/media"
+)
+
+const (
+	rtspVersion     = "RTSP/1.0"
+	defaultRTSPPort = "554"
+	agentName       = "intercom-ipcamera"
+	// rtspTimeout bounds connecting, each request, and the wait for each packet
+	rtspTimeout = 10 * time.Second
+	// defaultSessionTimeout is how long a camera keeps a session without a
+	// request when it does not say, RFC 2326's default
+	defaultSessionTimeout = 60 * time.Second
+)
+
+var errBadResponse = errors.New("malformed RTSP response")
+
+type rtspResponse struct {
+	status int
+	reason string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// rtspClient plays a camera's stream over RTSP, with RTP interleaved on the
+// same TCP connection so it gets through NAT and firewalls
+type rtspClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	user     string
+	password string
+
+	// mu serializes writes, keep-alives are sent while packets are read
+	mu      sync.Mutex
+	cseq    int
+	session string
+	// playing is the URL the session was played at, empty until it is
+	playing string
+	// challenge is the camera's WWW-Authenticate, answered in every request once it asks
+	challenge string
+	nc        int
+}
+
+// dialRTSP connects to the camera at u, whose user info, if any, is the
+// user and password it is asked for
+func dialRTSP(ctx context.Context, u *url.URL) (*rtspClient, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), defaultRTSPPort)
+	}
+	dialer := net.Dialer{Timeout: rtspTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &rtspClient{conn: conn, r: bufio.NewReader(conn)}
+	if u.User != nil {
+		c.user = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	return c, nil
+}
+
+// teardown ends the session being played, if there is one
+func (c *rtspClient) teardown() {
+	c.mu.Lock()
+	uri := c.playing
+	c.mu.Unlock()
+	if uri != "" {
+		c.write("TEARDOWN", uri, nil)
+	}
+}
+
+func (c *rtspClient) close() {
+	c.conn.Close()
+}
+
+// do sends a request and waits for its response, sending it again with
+// credentials if the camera asks for them
+func (c *rtspClient) do(method, uri string, header map[string]string) (*rtspResponse, error) {
+	resp, err := c.roundTrip(method, uri, header)
+	if err != nil || resp.status != 401 || c.user == "" {
+		return resp, err
+	}
+	challenge := pickChallenge(resp.header["Www-Authenticate"])
+	if challenge == "" {
+		return resp, nil
+	}
+	c.mu.Lock()
+	c.challenge, c.nc = challenge, 0
+	c.mu.Unlock()
+	return c.roundTrip(method, uri, header)
+}
+
+func (c *rtspClient) roundTrip(method, uri string, header map[string]string) (*rtspResponse, error) {
+	cseq, err := c.write(method, uri, header)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(rtspTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		channel, _, resp, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		// packets that arrive before the response are dropped
+		if channel >= 0 {
+			continue
+		}
+		if resp.header.Get("CSeq") == strconv.Itoa(cseq) {
+			return resp, nil
+		}
+	}
+}
+
+// write sends a request and returns its CSeq
+func (c *rtspClient) write(method, uri string, header map[string]string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cseq++
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%v %v %v\r\n", method, uri, rtspVersion)
+	fmt.Fprintf(&b, "CSeq: %v\r\n", c.cseq)
+	fmt.Fprintf(&b, "User-Agent: %v\r\n", agentName)
+	if c.session != "" {
+		fmt.Fprintf(&b, "Session: %v\r\n", c.session)
+	}
+	if auth, err := c.authorization(method, uri); err != nil {
+		return 0, err
+	} else if auth != "" {
+		fmt.Fprintf(&b, "Authorization: %v\r\n", auth)
+	}
+	for name, value := range header {
+		fmt.Fprintf(&b, "%v: %v\r\n", name, value)
+	}
+	b.WriteString("\r\n")
+
+	c.conn.SetWriteDeadline(time.Now().Add(rtspTimeout))
+	_, err := c.conn.Write(b.Bytes())
+	return c.cseq, err
+}
+
+// read reads what the camera sends next: an interleaved packet and the channel
+// it is on, or a response and a channel of -1
+func (c *rtspClient) read() (int, []byte, *rtspResponse, error) {
+	first, err := c.r.Peek(1)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if first[0] == '$' {
+		var head [4]byte
+		if _, err := io.ReadFull(c.r, head[:]); err != nil {
+			return 0, nil, nil, err
+		}
+		packet := make([]byte, binary.BigEndian.Uint16(head[2:]))
+		if _, err := io.ReadFull(c.r, packet); err != nil {
+			return 0, nil, nil, err
+		}
+		return int(head[1]), packet, nil, nil
+	}
+
+	tp := textproto.NewReader(c.r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	start := strings.SplitN(line, " ", 3)
+	if len(start) < 2 || start[0] != rtspVersion {
+		return 0, nil, nil, errBadResponse
+	}
+	resp := &rtspResponse{}
+	if resp.status, err = strconv.Atoi(start[1]); err != nil {
+		return 0, nil, nil, errBadResponse
+	}
+	if len(start) == 3 {
+		resp.reason = start[2]
+	}
+	if resp.header, err = tp.ReadMIMEHeader(); err != nil {
+		return 0, nil, nil, err
+	}
+	if length := resp.header.Get("Content-Length"); length != "" {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 {
+			return 0, nil, nil, errBadResponse
+		}
+		resp.body = make([]byte, n)
+		if _, err := io.ReadFull(c.r, resp.body); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	return -1, nil, resp, nil
+}
+
+// readPacket returns the next packet on channel, skipping the responses to
+// keep-alives and packets on other channels, like RTCP
+func (c *rtspClient) readPacket(channel int) ([]byte, error) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(rtspTimeout))
+		ch, packet, _, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if ch == channel {
+			return packet, nil
+		}
+	}
+}
+
+// authorization answers the camera's challenge for a request, empty if it hasn't asked
+func (c *rtspClient) authorization(method, uri string) (string, error) {
+	switch {
+	case c.challenge == "":
+		return "", nil
+	case strings.HasPrefix(strings.ToLower(c.challenge), "basic"):
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.user+":"+c.password)), nil
+	}
+	c.nc++
+	return media.DigestAuthorization(c.challenge, method, uri, c.user, c.password, c.nc)
+}
+
+// pickChallenge picks the challenge to answer, digest over basic
+func pickChallenge(challenges []string) string {
+	chosen := ""
+	for _, challenge := range challenges {
+		scheme := strings.Fields(challenge)
+		if len(scheme) == 0 {
+			continue
+		}
+		switch strings.ToLower(scheme[0]) {
+		case "digest":
+			return challenge
+		case "basic":
+			chosen = challenge
+		}
+	}
+	return chosen
+}
+
+// videoTrack is the JPEG stream a camera describes
+type videoTrack struct {
+	// control is the URL the track is set up at, and aggregate the URL the session is played at
+	control   string
+	aggregate string
+	payload   int
+}
+
+// parseSDP finds the first JPEG video stream in a DESCRIBE response, its
+// control URLs resolved against base
+func parseSDP(body []byte, base string) (*videoTrack, error) {
+	var session, control string
+	payload := -1
+	// section is where the line is: the session, the JPEG stream or other media
+	const (
+		inSession = iota
+		inVideo
+		inOther
+	)
+	section := inSession
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "m="):
+			section = inOther
+			fields := strings.Fields(line[2:])
+			if payload >= 0 || len(fields) < 4 || fields[0] != "video" {
+				continue
+			}
+			// a dynamic payload type is JPEG if its rtpmap says so, which comes later
+			section, control = inVideo, ""
+			for _, f := range fields[3:] {
+				if f == strconv.Itoa(payloadJPEG) {
+					payload = payloadJPEG
+				}
+			}
+
+		case strings.HasPrefix(line, "a=rtpmap:") && section == inVideo && payload < 0:
+			fields := strings.Fields(line[len("a=rtpmap:"):])
+			if len(fields) == 2 && strings.HasPrefix(strings.ToUpper(fields[1]), "JPEG/") {
+				payload, _ = strconv.Atoi(fields[0])
+			}
+
+		case strings.HasPrefix(line, "a=control:"):
+			value := strings.TrimSpace(line[len("a=control:"):])
+			switch section {
+			case inSession:
+				session = value
+			case inVideo:
+				control = value
+			}
+		}
+	}
+
+	if payload < 0 {
+		return nil, errors.New("camera has no MJPEG stream")
+	}
+	return &videoTrack{
+		control:   resolveControl(base, control),
+		aggregate: resolveControl(base, session),
+		payload:   payload,
+	}, nil
+}
+
+// resolveControl resolves an SDP control attribute against the session's base URL
+func resolveControl(base, control string) string {
+	switch {
+	case control == "" || control == "*":
+		return base
+	case strings.HasPrefix(strings.ToLower(control), "rtsp://"):
+		return control
+	case strings.HasSuffix(base, "/"):
+		return base + control
+	}
+	return base + "/" + control
+}
+
+// sessionID splits the Session header of a SETUP response into the session
+// and how long it lasts without a request
+func sessionID(header string) (string, time.Duration) {
+	parts := strings.Split(header, ";")
+	timeout := defaultSessionTimeout
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(kv[0], "timeout") {
+			if n, err := strconv.Atoi(kv[1]); err == nil && n > 0 {
+				timeout = time.Duration(n) * time.Second
+			}
+		}
+	}
+	return strings.TrimSpace(parts[0]), timeout
+}
+
+// interleavedChannel is the channel a SETUP response says RTP arrives on
+func interleavedChannel(transport string, asked int) int {
+	for _, param := range strings.Split(transport, ";") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 && kv[0] == "interleaved" {
+			if n, err := strconv.Atoi(strings.SplitN(kv[1], "-", 2)[0]); err == nil {
+				return n
+			}
+		}
+	}
+	return asked
+}
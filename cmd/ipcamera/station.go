@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/3xcellent/intercom/broadcast"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
+)
+
+const (
+	// videoTileSize is the width and height in pixels of the tiles video is sent in, as the client's
+	videoTileSize = 32
+	// channels is the layout frames are decoded to, 8 bit BGR as the client's webcam captures
+	channels = 3
+)
+
+// station is the camera on the intercom, a station that only sends video
+type station struct {
+	log  *logger.Logger
+	name string
+
+	// frameWidth and frameHeight are the most video size sent, zero to send
+	// frames at the camera's
+	frameWidth  int
+	frameHeight int
+	video       *broadcast.Video
+
+	server    proto.Intercom_ConnectClient
+	sendMutex sync.Mutex
+}
+
+// send serializes writes to the server stream, which is not safe for concurrent Send calls,
+// and tags them with the station's name
+func (s *station) send(req *proto.Broadcast) {
+	req.Name = s.name
+
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+	if err := s.server.Send(req); err != nil {
+		s.log.Errorf("send error: %v", err)
+	}
+}
+
+// sendStatus tells the stations the camera has no mic
+func (s *station) sendStatus() {
+	s.send(&proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Status{Status: &proto.Status{MicMuted: true}},
+	})
+}
+
+// receive handles what the server sends until the stream ends, the camera
+// only takes keyframe requests and bitrate limits
+func (s *station) receive() error {
+	for {
+		resp, err := s.server.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.GetKeyframeRequest() != nil:
+			s.video.RequestKeyframe()
+
+		case resp.GetBitrate() != nil:
+			limit := int(resp.GetBitrate().MaxVideoBitrate)
+			s.video.SetMaxBitrate(limit)
+			if limit > 0 {
+				s.log.Infof("server asked to keep video under %v bits/s", limit)
+			} else {
+				s.log.Infof("server lifted the video bitrate limit")
+			}
+		}
+	}
+}
+
+// handleFrame sends a JPEG frame from the camera if one is due, watching it
+// for motion first when only motion is sent
+func (s *station) handleFrame(jpeg []byte) {
+	due := s.video.Due()
+	if !due && !s.video.WatchesMotion() {
+		return
+	}
+
+	pix, width, height, err := video.DecodeJPEG(jpeg)
+	if err != nil {
+		s.log.Debugf("cannot decode camera frame: %v", err)
+		return
+	}
+	if s.video.WatchesMotion() {
+		if fraction, started := s.video.CheckMotion(pix, width, height); started {
+			s.log.Infof("motion detected (%.0f%% changed), broadcasting", fraction*100)
+		}
+		if !s.video.MotionSeen() {
+			return
+		}
+	}
+	if !due {
+		return
+	}
+
+	pix, width, height = s.scale(pix, width, height)
+	s.sendVideoFrame(pix, width, height)
+}
+
+// scale shrinks a frame to fit the most video size, by a whole factor as video.Downscale does
+func (s *station) scale(pix []byte, width, height int) ([]byte, int, int) {
+	if s.frameWidth <= 0 || s.frameHeight <= 0 {
+		return pix, width, height
+	}
+	factor := math.Max(float64(width)/float64(s.frameWidth), float64(height)/float64(s.frameHeight))
+	return video.Downscale(pix, width, height, channels, int(math.Ceil(factor)))
+}
+
+// sendVideoFrame sends a BGR frame as the tiles that changed since the last one
+func (s *station) sendVideoFrame(pix []byte, width, height int) {
+	frame, err := s.video.Frame(pix, width, height)
+	if err != nil {
+		s.log.Errorf("cannot encode video: %v", err)
+		return
+	}
+	s.send(&proto.Broadcast{BroadcastType: &proto.Broadcast_Image{Image: frame}})
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// adminServicePrefix starts the full method name of every IntercomAdmin call
+const adminServicePrefix = "/IntercomAdmin/"
+
+// adminServer implements the IntercomAdmin service on top of the intercom server
+type adminServer struct {
+	server *intercomServer
+	token  string
+}
+
+// authorize is a unary interceptor refusing IntercomAdmin calls without the
+// admin token, other services pass through
+func (a *adminServer) authorize(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, adminServicePrefix) {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "admin token required")
+	}
+	token := strings.TrimPrefix(values[0], "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		a.server.log.With("method", info.FullMethod).Warnf("admin call refused, wrong token")
+		return nil, status.Error(codes.PermissionDenied, "wrong admin token")
+	}
+	return handler(ctx, req)
+}
+
+func (a *adminServer) ListStreams(ctx context.Context, req *proto.ListStreamsRequest) (*proto.StreamList, error) {
+	s := a.server
+	list := &proto.StreamList{}
+
+	s.subscribersMutex.Lock()
+	for _, sub := range s.streams {
+		info := &proto.StreamInfo{
+			Id:          sub.id,
+			Station:     sub.station,
+			Peer:        sub.peer,
+			ConnectedAt: sub.connected.Unix(),
+			Queued:      int32(sub.backlog()),
+		}
+		if sub.room != nil {
+			info.Room = sub.room.name
+		}
+		list.Streams = append(list.Streams, info)
+	}
+	s.subscribersMutex.Unlock()
+
+	s.rosterMutex.Lock()
+	for _, info := range list.Streams {
+		if station, ok := s.stations[info.Station]; ok {
+			info.Status = s.stationStatus(station)
+		}
+		info.ForceMuted = s.forceMuted[info.Station]
+	}
+	s.rosterMutex.Unlock()
+
+	sort.Slice(list.Streams, func(i, j int) bool {
+		return list.Streams[i].Id < list.Streams[j].Id
+	})
+	return list, nil
+}
+
+func (a *adminServer) Kick(ctx context.Context, req *proto.StationRequest) (*proto.AdminReply, error) {
+	s := a.server
+	s.subscribersMutex.Lock()
+	sub := s.subscriberNamed(req.Station)
+	s.subscribersMutex.Unlock()
+	if sub == nil {
+		return nil, status.Errorf(codes.NotFound, "station %q is not connected", req.Station)
+	}
+
+	s.log.With("station", req.Station).Infof("kicked by admin")
+	sub.kick("kicked by the server's operator")
+	return &proto.AdminReply{}, nil
+}
+
+func (a *adminServer) Mute(ctx context.Context, req *proto.MuteRequest) (*proto.AdminReply, error) {
+	if req.Station == "" {
+		return nil, status.Error(codes.InvalidArgument, "station required")
+	}
+
+	s := a.server
+	s.setForceMuted(req.Station, req.Muted)
+	if req.Muted {
+		s.log.With("station", req.Station).Infof("muted by admin")
+		// the station is told so it shows its mic as muted, the server drops its audio either way
+		s.sendToStation(req.Station, &proto.Broadcast{
+			BroadcastType: &proto.Broadcast_Status{Status: &proto.Status{MicMuted: true}},
+		})
+	} else {
+		s.log.With("station", req.Station).Infof("unmuted by admin")
+	}
+	return &proto.AdminReply{}, nil
+}
+
+func (a *adminServer) Announce(ctx context.Context, req *proto.Announcement) (*proto.AdminReply, error) {
+	if req.Text == "" {
+		return nil, status.Error(codes.InvalidArgument, "text required")
+	}
+
+	a.server.log.Infof("announcing %q", req.Text)
+	a.server.relayAll(&proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Event{Event: &proto.Event{Type: proto.EventType_ANNOUNCEMENT, Text: req.Text}},
+	})
+	return &proto.AdminReply{}, nil
+}
+
+func (a *adminServer) GetSettings(ctx context.Context, req *proto.GetSettingsRequest) (*proto.Settings, error) {
+	return a.settings(), nil
+}
+
+func (a *adminServer) UpdateSettings(ctx context.Context, req *proto.Settings) (*proto.Settings, error) {
+	s := a.server
+	if req.LogLevel != "" {
+		level, err := logger.ParseLevel(req.LogLevel)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		s.log.SetLevel(level)
+		s.log.Infof("log level set to %v by admin", level)
+	}
+	if req.FrameRate < 0 {
+		return nil, status.Error(codes.InvalidArgument, "frame rate must be at least 1")
+	}
+	if req.FrameRate > 0 {
+		s.setFrameInterval(time.Second / time.Duration(req.FrameRate))
+		s.log.Infof("frame rate set to %v by admin", req.FrameRate)
+	}
+	return a.settings(), nil
+}
+
+func (a *adminServer) StartRecording(ctx context.Context, req *proto.RecordRequest) (*proto.Recording, error) {
+	recording, err := a.server.recorder.start(req.Name)
+	switch {
+	case err == errRecording:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case os.IsExist(err):
+		return nil, status.Errorf(codes.AlreadyExists, "%v already exists", req.Name)
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return recording, nil
+}
+
+func (a *adminServer) StopRecording(ctx context.Context, req *proto.StopRecordingRequest) (*proto.Recording, error) {
+	recording, err := a.server.recorder.stop()
+	switch {
+	case err == errNotRecording:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return recording, nil
+}
+
+func (a *adminServer) GetStats(ctx context.Context, req *proto.GetStatsRequest) (*proto.Stats, error) {
+	s := a.server
+	stats := s.metrics.stats()
+	s.rosterMutex.Lock()
+	stats.Stations = int32(len(s.stations))
+	s.rosterMutex.Unlock()
+	stats.UptimeSeconds = int64(time.Since(s.started).Seconds())
+	stats.Recording = s.recorder.current()
+	return stats, nil
+}
+
+func (a *adminServer) ListRooms(ctx context.Context, req *proto.ListRoomsRequest) (*proto.RoomList, error) {
+	s := a.server
+	broadcasting := s.broadcasting()
+	rooms := map[string]*proto.Room{}
+
+	s.rosterMutex.Lock()
+	for _, station := range s.stations {
+		// a station is only in a room once it has joined one
+		if station.Room == "" {
+			continue
+		}
+		room, ok := rooms[station.Room]
+		if !ok {
+			room = &proto.Room{Name: station.Room, Broadcasting: broadcasting[station.Room]}
+			rooms[station.Room] = room
+		}
+		room.Stations = append(room.Stations, station.Name)
+	}
+	s.rosterMutex.Unlock()
+
+	list := &proto.RoomList{}
+	for _, room := range rooms {
+		sort.Strings(room.Stations)
+		list.Rooms = append(list.Rooms, room)
+	}
+	sort.Slice(list.Rooms, func(i, j int) bool {
+		return list.Rooms[i].Name < list.Rooms[j].Name
+	})
+	return list, nil
+}
+
+func (a *adminServer) MoveStation(ctx context.Context, req *proto.MoveRequest) (*proto.AdminReply, error) {
+	if req.Station == "" || req.Room == "" {
+		return nil, status.Error(codes.InvalidArgument, "station and room required")
+	}
+
+	s := a.server
+	if !s.moveStation(req.Station, req.Room) {
+		return nil, status.Errorf(codes.NotFound, "station %q is not connected", req.Station)
+	}
+	s.log.With("station", req.Station).With("room", req.Room).Infof("moved by admin")
+	return &proto.AdminReply{}, nil
+}
+
+func (a *adminServer) settings() *proto.Settings {
+	return &proto.Settings{
+		FrameRate: int32(time.Second / a.server.getFrameInterval()),
+		LogLevel:  a.server.log.Level().String(),
+	}
+}
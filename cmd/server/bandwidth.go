@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+const (
+	// priorityQueueSize holds audio, events and roster updates, which go ahead of
+	// video.  Roster updates wait for room, relayed messages are dropped once it is full.
+	priorityQueueSize = 64
+	// videoQueueSize holds frames, a full queue drops new frames
+	videoQueueSize = 2
+
+	// estimateWindow is how long sends are measured over for each bandwidth sample
+	estimateWindow = time.Second
+	// congestedBusy is the fraction of a window spent blocked in Send at which the
+	// stream is taken to be limited by its link, and idleBusy where it no longer is
+	congestedBusy = 0.5
+	idleBusy      = 0.1
+	// estimateSmoothing weights older samples of a stream's capacity
+	estimateSmoothing = 0.7
+	// videoHeadroom is the share of a congested stream's capacity left to video and audio together
+	videoHeadroom = 0.8
+	// minVideoBitrate is the least a sender is asked to cut its video to, in bits per second
+	minVideoBitrate = 64000
+
+	// hintChange is how far a sender's bitrate limit has to move before it is told again
+	hintChange = 0.2
+	// hintHold is the least time between hints, so a sender is not flapped between limits
+	hintHold = 2 * time.Second
+)
+
+// bandwidthEstimator measures what a stream's link can carry from how long
+// stream.Send blocks.  Send only blocks once gRPC's flow control window is full,
+// so a stream that spends most of its time in Send is sending as fast as its
+// link allows and bytes over time blocked is its capacity.
+type bandwidthEstimator struct {
+	mu sync.Mutex
+
+	windowStart      time.Time
+	windowBytes      int
+	windowAudioBytes int
+	windowBusy       time.Duration
+
+	// capacity is in bits per second, zero while the link is not what limits the stream
+	capacity float64
+	// audioRate is the audio sent in bits per second, video gets what is left
+	audioRate float64
+}
+
+// sent records a message of bytes that took took to send
+func (e *bandwidthEstimator) sent(bytes int, isAudio bool, took time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if e.windowStart.IsZero() {
+		e.windowStart = now
+	}
+
+	e.windowBytes += bytes
+	if isAudio {
+		e.windowAudioBytes += bytes
+	}
+	e.windowBusy += took
+
+	elapsed := now.Sub(e.windowStart)
+	if elapsed < estimateWindow {
+		return
+	}
+
+	e.audioRate = float64(e.windowAudioBytes*8) / elapsed.Seconds()
+	busy := float64(e.windowBusy) / float64(elapsed)
+	switch {
+	case busy >= congestedBusy:
+		sample := float64(e.windowBytes*8) / e.windowBusy.Seconds()
+		if e.capacity == 0 {
+			e.capacity = sample
+		} else {
+			e.capacity = estimateSmoothing*e.capacity + (1-estimateSmoothing)*sample
+		}
+	case busy < idleBusy:
+		e.capacity = 0
+	}
+
+	e.windowStart = now
+	e.windowBytes = 0
+	e.windowAudioBytes = 0
+	e.windowBusy = 0
+}
+
+// videoBudget is the bits per second of video the stream can take, zero when it is not congested
+func (e *bandwidthEstimator) videoBudget() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.capacity == 0 {
+		return 0
+	}
+	budget := int(e.capacity*videoHeadroom - e.audioRate)
+	if budget < minVideoBitrate {
+		budget = minVideoBitrate
+	}
+	return budget
+}
+
+// subscriber is a stream's outgoing queues.  Audio and roster updates go
+// through priority and are always sent ahead of video, which is dropped
+// rather than queued once its own small queue is full.
+type subscriber struct {
+	priority  chan *proto.Broadcast
+	video     chan *proto.Broadcast
+	estimator bandwidthEstimator
+	metrics   *serverMetrics
+	log       *logger.Logger
+
+	// id, peer and connected describe the stream for the admin service
+	id        int64
+	peer      string
+	connected time.Time
+	// station is the name the stream joined as and room the room it is in,
+	// guarded by the server's subscribersMutex
+	station string
+	room    *room
+	// cancel ends the stream, kicked holds why when the server did
+	cancel context.CancelFunc
+	kicked atomic.Value
+	// sent counts the bytes sent, for the dashboard
+	sent int64
+}
+
+func newSubscriber(metrics *serverMetrics, log *logger.Logger) *subscriber {
+	return &subscriber{
+		priority: make(chan *proto.Broadcast, priorityQueueSize),
+		video:    make(chan *proto.Broadcast, videoQueueSize),
+		metrics:  metrics,
+		log:      log,
+	}
+}
+
+// send queues a message that must not be dropped, blocking while the queue is full
+func (sub *subscriber) send(ctx context.Context, broadcast *proto.Broadcast) {
+	select {
+	case sub.priority <- broadcast:
+	case <-ctx.Done():
+	}
+}
+
+// sendVideo queues a frame, reporting false if it was dropped
+func (sub *subscriber) sendVideo(broadcast *proto.Broadcast) bool {
+	select {
+	case sub.video <- broadcast:
+		return true
+	default:
+		return false
+	}
+}
+
+// kick disconnects the stream, which fails with reason
+func (sub *subscriber) kick(reason string) {
+	sub.kicked.Store(reason)
+	if sub.cancel != nil {
+		sub.cancel()
+	}
+}
+
+// kickReason is why the stream was kicked, empty if it was not
+func (sub *subscriber) kickReason() string {
+	reason, _ := sub.kicked.Load().(string)
+	return reason
+}
+
+// bytesSent is how many bytes have been sent to the stream
+func (sub *subscriber) bytesSent() int64 {
+	return atomic.LoadInt64(&sub.sent)
+}
+
+// backlog is how many messages are waiting to be sent
+func (sub *subscriber) backlog() int {
+	return len(sub.priority) + len(sub.video)
+}
+
+// write sends queued messages to stream until ctx is done, timing each send for the estimator
+func (sub *subscriber) write(ctx context.Context, stream proto.Intercom_ConnectServer) {
+	for {
+		var broadcast *proto.Broadcast
+		select {
+		case broadcast = <-sub.priority:
+		default:
+			select {
+			case broadcast = <-sub.priority:
+			case broadcast = <-sub.video:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		start := time.Now()
+		if err := stream.Send(broadcast); err != nil {
+			sub.log.With("type", messageType(broadcast)).Errorf("send error: %v", err)
+			sub.metrics.sendError()
+			continue
+		}
+		took := time.Since(start)
+		size := protobuf.Size(broadcast)
+		sub.estimator.sent(size, broadcast.GetAudio() != nil, took)
+		sub.metrics.sentMessage(broadcast, size, took)
+		atomic.AddInt64(&sub.sent, int64(size))
+	}
+}
+
+// bitrateHinter tells the station sending video the most its slowest stream can take
+type bitrateHinter struct {
+	mu       sync.Mutex
+	budgets  map[*subscriber]int
+	lastHint int
+	lastSent time.Time
+}
+
+// update records sub's video budget and returns a hint for the sender if the
+// lowest budget has moved enough to tell it, otherwise nil
+func (h *bitrateHinter) update(sub *subscriber, budget int) *proto.BitrateHint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.budgets == nil {
+		h.budgets = map[*subscriber]int{}
+	}
+	if budget == 0 {
+		delete(h.budgets, sub)
+	} else {
+		h.budgets[sub] = budget
+	}
+
+	lowest := 0
+	for _, b := range h.budgets {
+		if lowest == 0 || b < lowest {
+			lowest = b
+		}
+	}
+
+	if time.Since(h.lastSent) < hintHold || !hintChanged(h.lastHint, lowest) {
+		return nil
+	}
+	h.lastHint = lowest
+	h.lastSent = time.Now()
+	return &proto.BitrateHint{MaxVideoBitrate: int32(lowest)}
+}
+
+func (h *bitrateHinter) remove(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.budgets, sub)
+}
+
+func hintChanged(last, next int) bool {
+	if last == 0 || next == 0 {
+		return last != next
+	}
+	change := float64(next-last) / float64(last)
+	return change > hintChange || change < -hintChange
+}
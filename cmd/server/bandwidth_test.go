@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+)
+
+// throttledConn reads no faster than rate bytes per second, like a slow link
+type throttledConn struct {
+	net.Conn
+	rate  int
+	start time.Time
+	read  int
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	if len(p) > 1024 {
+		p = p[:1024]
+	}
+	n, err := c.Conn.Read(p)
+	if c.start.IsZero() {
+		c.start = time.Now()
+	}
+	c.read += n
+	due := time.Duration(float64(c.read) / float64(c.rate) * float64(time.Second))
+	if wait := due - time.Since(c.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
+
+// received is what a station's stream got, by type, with when each frame came
+// and the error the stream ended with
+type received struct {
+	mu      sync.Mutex
+	audio   []int32
+	frames  []time.Time
+	hints   []int32
+	rosters []*proto.Roster
+	err     error
+}
+
+func (r *received) counts() (int, int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.audio), len(r.frames), len(r.hints)
+}
+
+// connectStation joins the server on lis as name, reading the stream at rate
+// bytes per second, or as fast as it comes if rate is 0
+func connectStation(ctx context.Context, t *testing.T, lis *bufconn.Listener, name string, rate int) (proto.Intercom_ConnectClient, *received) {
+	conn, err := grpc.DialContext(ctx, "bufconn", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			conn, err := lis.Dial()
+			if err != nil || rate == 0 {
+				return conn, err
+			}
+			return &throttledConn{Conn: conn, rate: rate}, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	stream, err := proto.NewIntercomClient(conn).Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&proto.Broadcast{Name: name, BroadcastType: &proto.Broadcast_Status{Status: &proto.Status{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := &received{}
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				got.mu.Lock()
+				got.err = err
+				got.mu.Unlock()
+				return
+			}
+			got.mu.Lock()
+			switch {
+			case msg.GetAudio() != nil:
+				got.audio = append(got.audio, msg.GetAudio().Samples[0])
+			case msg.GetImage() != nil:
+				got.frames = append(got.frames, time.Now())
+			case msg.GetBitrate() != nil:
+				got.hints = append(got.hints, msg.GetBitrate().MaxVideoBitrate)
+			case msg.GetRoster() != nil:
+				got.rosters = append(got.rosters, msg.GetRoster())
+			}
+			got.mu.Unlock()
+		}
+	}()
+	return stream, got
+}
+
+// TestCongestedStream has a camera send raw frames and audio faster than a
+// station's link can take them, alongside a station with a fast link
+func TestCongestedStream(t *testing.T) {
+	const fps = 10
+	_, grpcServer := newTestServer(fps)
+	lis := bufconn.Listen(32 << 10)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	camera, cameraGot := connectStation(ctx, t, lis, "camera", 0)
+	_, fastGot := connectStation(ctx, t, lis, "fast", 0)
+	_, slowGot := connectStation(ctx, t, lis, "slow", 100<<10)
+	// let the stations join before anything is sent
+	time.Sleep(200 * time.Millisecond)
+
+	// 160x120 raw frames at 50 a second are far more than 100KB/s, and more
+	// frames than the server's fps
+	const width, height = 160, 120
+	pixels := make([]byte, width*height*3)
+	sentAudio := 0
+	sendAudio := time.NewTicker(50 * time.Millisecond)
+	sendFrame := time.NewTicker(20 * time.Millisecond)
+	defer sendAudio.Stop()
+	defer sendFrame.Stop()
+	deadline := time.After(4 * time.Second)
+sending:
+	for {
+		select {
+		case <-deadline:
+			break sending
+		case <-sendAudio.C:
+			err := camera.Send(&proto.Broadcast{Name: "camera", BroadcastType: &proto.Broadcast_Audio{Audio: &proto.Audio{
+				SampleRate: 8000,
+				Length:     1,
+				Samples:    []int32{int32(sentAudio)},
+			}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sentAudio++
+		case <-sendFrame.C:
+			pixels[0]++
+			err := camera.Send(&proto.Broadcast{Name: "camera", BroadcastType: &proto.Broadcast_Image{Image: &proto.Image{
+				Width:  width,
+				Height: height,
+				Type:   16,
+				Bytes:  pixels,
+			}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// what was queued drains at the slow link's pace
+	time.Sleep(time.Second)
+
+	t.Run("video is dropped before audio", func(t *testing.T) {
+		slowGot.mu.Lock()
+		defer slowGot.mu.Unlock()
+		if len(slowGot.audio) != sentAudio {
+			t.Fatalf("slow station got %v of %v audio messages", len(slowGot.audio), sentAudio)
+		}
+		for i, sample := range slowGot.audio {
+			if sample != int32(i) {
+				t.Fatalf("audio message %v is %v, want them all in order", i, sample)
+			}
+		}
+		fastAudio, fastFrames, _ := fastGot.counts()
+		if fastAudio != sentAudio {
+			t.Errorf("fast station got %v of %v audio messages", fastAudio, sentAudio)
+		}
+		if len(slowGot.frames) >= fastFrames {
+			t.Errorf("slow station got %v frames, the fast one %v, want frames dropped for the slow one", len(slowGot.frames), fastFrames)
+		}
+	})
+
+	t.Run("pacer stretches frame intervals", func(t *testing.T) {
+		fastGot.mu.Lock()
+		defer fastGot.mu.Unlock()
+		if len(fastGot.frames) < 2 {
+			t.Fatalf("only %v frames", len(fastGot.frames))
+		}
+		frameInterval := time.Second / fps
+		fast := fastGot.frames[len(fastGot.frames)-1].Sub(fastGot.frames[0]) / time.Duration(len(fastGot.frames)-1)
+		if fast < frameInterval*8/10 || fast > frameInterval*3/2 {
+			t.Errorf("fast station's frames came every %v, want about %v", fast, frameInterval)
+		}
+
+		// once the pacer scales the frames down they fit the link again, so
+		// it is the gap a full size frame takes that shows, over half a second
+		slowGot.mu.Lock()
+		defer slowGot.mu.Unlock()
+		var longest time.Duration
+		for i := 1; i < len(slowGot.frames); i++ {
+			if gap := slowGot.frames[i].Sub(slowGot.frames[i-1]); gap > longest {
+				longest = gap
+			}
+		}
+		if longest < frameInterval*3 {
+			t.Errorf("slow station's frames came at most %v apart, want them stretched past %v", longest, frameInterval*3)
+		}
+	})
+
+	t.Run("bitrate hint reaches the sender", func(t *testing.T) {
+		cameraGot.mu.Lock()
+		defer cameraGot.mu.Unlock()
+		if len(cameraGot.hints) == 0 {
+			t.Fatal("camera got no bitrate hint")
+		}
+		// the slow link carries 800kbit/s while the camera sends some 23Mbit/s.
+		// An estimate whose first window took in what gRPC's flow control
+		// windows buffer before Send blocks overshoots the link severalfold.
+		const link = 800 << 10
+		hint := cameraGot.hints[0]
+		if hint < link/2 || hint > 5*link {
+			t.Errorf("hint of %v bits/s, want near the slow link's %v", hint, link)
+		}
+	})
+}
+
+func TestFramePacer(t *testing.T) {
+	log := logger.New(ioutil.Discard, logger.Error, logger.Text)
+
+	t.Run("keeps cadence when woken late", func(t *testing.T) {
+		p := newFramePacer(50*time.Millisecond, log)
+		if !p.ready(0, false) {
+			t.Fatal("first frame not ready")
+		}
+		first := p.lastSent
+		time.Sleep(60 * time.Millisecond)
+		if !p.ready(0, false) {
+			t.Fatal("frame not ready after its interval")
+		}
+		if got := p.lastSent.Sub(first); got != 50*time.Millisecond {
+			t.Errorf("frame counted %v after the last, want when it was due", got)
+		}
+		if p.ready(0, false) {
+			t.Error("frame ready before its interval")
+		}
+	})
+
+	t.Run("a backed up queue drops frames and scales down", func(t *testing.T) {
+		p := newFramePacer(time.Millisecond, log)
+		if p.ready(queueBackedUp, false) {
+			t.Error("frame ready for a backed up stream")
+		}
+		if p.scale != 2 {
+			t.Errorf("scale 1/%v, want 1/2", p.scale)
+		}
+		if !p.ready(0, false) {
+			t.Error("frame not ready once the queue drained")
+		}
+	})
+
+	t.Run("a congested stream gets frames scaled down", func(t *testing.T) {
+		p := newFramePacer(time.Millisecond, log)
+		if !p.ready(0, true) {
+			t.Error("frame not ready for a congested stream")
+		}
+		if p.scale != 2 {
+			t.Errorf("scale 1/%v, want 1/2", p.scale)
+		}
+		image := p.apply(&proto.Image{Width: 4, Height: 4, Bytes: make([]byte, 4*4*3)})
+		if image.Width != 2 || image.Height != 2 || len(image.Bytes) != 2*2*3 {
+			t.Errorf("applied image %vx%v of %v bytes, want 2x2", image.Width, image.Height, len(image.Bytes))
+		}
+	})
+
+	t.Run("frames that do not fill their size are dropped", func(t *testing.T) {
+		for _, scale := range []int{1, 2} {
+			p := newFramePacer(time.Millisecond, log)
+			p.scale = scale
+			for _, image := range []*proto.Image{
+				{Width: 0, Height: 4, Bytes: make([]byte, 48)},
+				{Width: 4, Height: 0, Bytes: make([]byte, 48)},
+				{Width: 4, Height: 4, Bytes: make([]byte, 47)},
+				{Width: 1 << 16, Height: 1 << 16, Bytes: make([]byte, 48)},
+				{Width: -4, Height: -4, Bytes: make([]byte, 48)},
+			} {
+				if got := p.apply(image); got != nil {
+					t.Errorf("at 1/%v applied %vx%v image of %v bytes", scale, image.Width, image.Height, len(image.Bytes))
+				}
+			}
+		}
+	})
+}
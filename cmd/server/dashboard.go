@@ -0,0 +1,390 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
+)
+
+const (
+	// dashboardInterval is how often the dashboard's state is pushed to each browser
+	dashboardInterval = 500 * time.Millisecond
+	// thumbnailInterval is the least time between updates of a station's thumbnail
+	thumbnailInterval = time.Second
+	// thumbnailWidth is the widest thumbnails are, frames are scaled down to about it
+	thumbnailWidth   = 160
+	thumbnailQuality = 70
+)
+
+// thumbnail is a small JPEG of a station's latest frame, version counts its updates
+type thumbnail struct {
+	jpeg    []byte
+	version int
+	updated time.Time
+}
+
+// dashboardState is what the dashboard shows, sent to the browser as JSON
+type dashboardState struct {
+	Stations  []dashboardStation `json:"stations"`
+	Recording string             `json:"recording"`
+}
+
+type dashboardStation struct {
+	Stream int64  `json:"stream"`
+	Name   string `json:"name"`
+	Peer   string `json:"peer"`
+	// Room is empty until the station joins one
+	Room string `json:"room"`
+	// Broadcasting is set while the station's video is being relayed in its room
+	Broadcasting bool `json:"broadcasting"`
+
+	MicMuted   bool `json:"micMuted"`
+	CameraOff  bool `json:"cameraOff"`
+	Privacy    bool `json:"privacy"`
+	ForceMuted bool `json:"forceMuted"`
+
+	Talking bool `json:"talking"`
+	// Level is the station's latest audio level in dBFS while Talking
+	Level float64 `json:"level"`
+	// Bitrate is the bits per second sent to the stream
+	Bitrate int `json:"bitrate"`
+	Queued  int `json:"queued"`
+	// Thumbnail is the version of the station's thumbnail, 0 if it has none
+	Thumbnail int `json:"thumbnail"`
+
+	sentBytes int64
+}
+
+// updateThumbnail refreshes station's thumbnail from image, at most every
+// thumbnailInterval.  The caller must hold imgMutex, and tiled video must
+// already be applied to the tile cache of v, the video of station's room.
+func (s *intercomServer) updateThumbnail(v *roomVideo, station string, image *proto.Image) {
+	// frames are only decoded for a dashboard to show them on
+	if s.thumbnails == nil {
+		return
+	}
+	if t, ok := s.thumbnails[station]; ok && time.Since(t.updated) < thumbnailInterval {
+		return
+	}
+
+	width, height := int(image.Width), int(image.Height)
+	var pix []byte
+	if image.TileSize > 0 {
+		if v.tiles.tiles == nil || v.tiles.from != station {
+			return
+		}
+		frame := v.tiles.since(0)
+		tiles := make([]video.Tile, len(frame.Tiles))
+		for i, tile := range frame.Tiles {
+			tiles[i] = video.Tile{X: int(tile.X), Y: int(tile.Y), JPEG: tile.Jpeg}
+		}
+		var decoder video.Decoder
+		var err error
+		if pix, err = decoder.Decode(width, height, int(frame.TileSize), tiles, true); err != nil {
+			return
+		}
+	} else {
+		// only BGR frames, the webcam's, are shown
+		if width == 0 || len(image.Bytes) != width*height*3 {
+			return
+		}
+		pix = image.Bytes
+	}
+
+	pix, width, height = video.Downscale(pix, width, height, 3, width/thumbnailWidth)
+	jpeg, err := video.EncodeJPEG(pix, width, height, thumbnailQuality)
+	if err != nil {
+		return
+	}
+
+	version := 1
+	if t, ok := s.thumbnails[station]; ok {
+		version = t.version + 1
+	}
+	s.thumbnails[station] = &thumbnail{jpeg: jpeg, version: version, updated: time.Now()}
+}
+
+func (s *intercomServer) removeThumbnail(station string) {
+	s.imgMutex.Lock()
+	defer s.imgMutex.Unlock()
+	delete(s.thumbnails, station)
+}
+
+// dashboardState gathers what the dashboard shows
+func (s *intercomServer) dashboardState() dashboardState {
+	var state dashboardState
+
+	broadcasting := s.broadcasting()
+
+	s.subscribersMutex.Lock()
+	for _, sub := range s.streams {
+		station := dashboardStation{
+			Stream:    sub.id,
+			Name:      sub.station,
+			Peer:      sub.peer,
+			Queued:    sub.backlog(),
+			sentBytes: sub.bytesSent(),
+		}
+		if sub.room != nil {
+			station.Room = sub.room.name
+			station.Broadcasting = station.Name != "" && broadcasting[station.Room] == station.Name
+		}
+		state.Stations = append(state.Stations, station)
+	}
+	s.subscribersMutex.Unlock()
+
+	s.rosterMutex.Lock()
+	s.expireLevels()
+	for i := range state.Stations {
+		info := &state.Stations[i]
+		if station, ok := s.stations[info.Name]; ok {
+			status := s.stationStatus(station)
+			info.MicMuted, info.CameraOff, info.Privacy = status.MicMuted, status.CameraOff, status.Privacy
+			// a silent station's level is -Inf dBFS, which JSON has no number for
+			if info.Talking = station.Level > 0; info.Talking {
+				info.Level = audio.Decibels(float64(station.Level))
+			}
+		}
+		info.ForceMuted = s.forceMuted[info.Name]
+	}
+	s.rosterMutex.Unlock()
+
+	s.imgMutex.Lock()
+	for i := range state.Stations {
+		if t, ok := s.thumbnails[state.Stations[i].Name]; ok {
+			state.Stations[i].Thumbnail = t.version
+		}
+	}
+	s.imgMutex.Unlock()
+
+	if recording := s.recorder.current(); recording != nil {
+		state.Recording = recording.Path
+	}
+
+	sort.Slice(state.Stations, func(i, j int) bool {
+		return state.Stations[i].Stream < state.Stations[j].Stream
+	})
+	return state
+}
+
+// registerDashboard adds the dashboard to mux, behind HTTP basic auth with the
+// admin token as the password
+func (s *intercomServer) registerDashboard(mux *http.ServeMux, token string) {
+	s.imgMutex.Lock()
+	s.thumbnails = map[string]*thumbnail{}
+	s.imgMutex.Unlock()
+
+	auth := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_, password, _ := r.BasicAuth()
+			if subtle.ConstantTimeCompare([]byte(password), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="intercom"`)
+				http.Error(w, "admin token required", http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		}
+	}
+
+	mux.HandleFunc("/", auth(s.handleDashboard))
+	mux.HandleFunc("/events", auth(s.handleDashboardEvents))
+	mux.HandleFunc("/thumbnail", auth(s.handleThumbnail))
+}
+
+func (s *intercomServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardHTML)
+}
+
+// handleDashboardEvents streams the dashboard's state as server-sent events
+func (s *intercomServer) handleDashboardEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	// bitrates are worked out from the bytes sent since the last update
+	lastSent := map[int64]int64{}
+	lastUpdate := time.Now()
+	for {
+		state := s.dashboardState()
+		elapsed := time.Since(lastUpdate).Seconds()
+		lastUpdate = time.Now()
+		sent := make(map[int64]int64, len(state.Stations))
+		for i := range state.Stations {
+			station := &state.Stations[i]
+			if last, ok := lastSent[station.Stream]; ok && elapsed > 0 {
+				station.Bitrate = int(float64(station.sentBytes-last) * 8 / elapsed)
+			}
+			sent[station.Stream] = station.sentBytes
+		}
+		lastSent = sent
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			s.log.Errorf("dashboard error: %v", err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *intercomServer) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	s.imgMutex.Lock()
+	t, ok := s.thumbnails[r.FormValue("station")]
+	s.imgMutex.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(t.jpeg)
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Intercom</title>
+<style>
+body { font-family: sans-serif; margin: 1em; background: #f4f4f4; }
+.room h2 { font-size: 1.1em; margin: 1em 0 .5em; }
+.stations { display: flex; flex-wrap: wrap; gap: 1em; }
+.station { background: white; border: 3px solid #ddd; border-radius: 6px; padding: .5em; width: 180px; }
+.station.talking { border-color: #2a2; }
+.station.broadcasting h3::after { content: " \25cf"; color: #c22; }
+.station h3 { font-size: 1em; margin: 0 0 .3em; }
+.station img, .station .blank { width: 160px; height: 120px; background: #333; display: block; object-fit: contain; }
+.meter { height: 6px; background: #eee; margin: .4em 0; }
+.meter div { height: 100%; background: #2a2; width: 0; }
+.detail { font-size: .8em; color: #555; }
+#status { color: #555; }
+</style>
+</head>
+<body>
+<h1>Intercom</h1>
+<p id="status">connecting...</p>
+<div id="rooms"></div>
+<script>
+var thumbnails = {};
+
+function statusText(s) {
+	var parts = [];
+	if (s.privacy) parts.push("privacy");
+	else {
+		if (s.micMuted && !s.forceMuted) parts.push("muted");
+		if (s.cameraOff) parts.push(s.micMuted ? "camera off" : "audio only");
+	}
+	if (s.forceMuted) parts.push("muted by admin");
+	return parts.join(", ");
+}
+
+function bitrateText(bps) {
+	if (bps >= 1000000) return (bps / 1000000).toFixed(1) + " Mbit/s";
+	return Math.round(bps / 1000) + " kbit/s";
+}
+
+// roomStations returns the list of a room's stations, adding the room in
+// order of the room names if it is new
+function roomStations(name) {
+	var rooms = document.getElementById("rooms");
+	var room = document.getElementById("room-" + name);
+	if (!room) {
+		room = document.createElement("section");
+		room.id = "room-" + name;
+		room.className = "room";
+		room.dataset.name = name;
+		room.innerHTML = '<h2></h2><div class="stations"></div>';
+		room.querySelector("h2").textContent = name || "(joining)";
+		var next = Array.prototype.filter.call(rooms.children, function (r) { return r.dataset.name > name; })[0];
+		rooms.insertBefore(room, next || null);
+	}
+	return room.querySelector(".stations");
+}
+
+function render(state) {
+	var rooms = {};
+	state.stations.forEach(function (s) { if (s.room) rooms[s.room] = true; });
+	var roomCount = Object.keys(rooms).length;
+	var status = state.stations.length + " connected in " + roomCount + (roomCount === 1 ? " room" : " rooms");
+	if (state.recording) status += ", recording to " + state.recording;
+	document.getElementById("status").textContent = status;
+
+	var seen = {};
+	state.stations.forEach(function (s) {
+		var id = "stream-" + s.stream;
+		seen[id] = true;
+		var list = roomStations(s.room);
+		var el = document.getElementById(id);
+		if (!el) {
+			el = document.createElement("div");
+			el.id = id;
+			el.className = "station";
+			el.innerHTML = '<h3></h3><div class="blank"></div><div class="meter"><div></div></div><div class="detail"></div>';
+		}
+		// a station moved to another room moves with it
+		if (el.parentNode !== list) list.appendChild(el);
+		el.classList.toggle("talking", s.talking);
+		el.classList.toggle("broadcasting", s.broadcasting);
+		el.querySelector("h3").textContent = s.name || "(joining)";
+
+		if (s.thumbnail && thumbnails[id] !== s.thumbnail) {
+			thumbnails[id] = s.thumbnail;
+			var img = document.createElement("img");
+			img.src = "thumbnail?station=" + encodeURIComponent(s.name) + "&v=" + s.thumbnail;
+			el.replaceChild(img, el.children[1]);
+		}
+
+		var level = s.talking ? Math.max(0, Math.min(100, (s.level + 60) / 60 * 100)) : 0;
+		el.querySelector(".meter div").style.width = level + "%";
+		el.querySelector(".detail").textContent = [statusText(s), s.peer, bitrateText(s.bitrate), s.queued + " queued"]
+			.filter(function (t) { return t; }).join(" · ");
+	});
+
+	Array.prototype.slice.call(document.querySelectorAll(".station")).forEach(function (el) {
+		if (!seen[el.id]) {
+			delete thumbnails[el.id];
+			el.parentNode.removeChild(el);
+		}
+	});
+	Array.prototype.slice.call(document.querySelectorAll(".room")).forEach(function (room) {
+		if (!room.querySelector(".station")) room.parentNode.removeChild(room);
+	});
+}
+
+var events = new EventSource("events");
+events.onmessage = function (e) { render(JSON.parse(e.data)); };
+events.onerror = function () { document.getElementById("status").textContent = "reconnecting..."; };
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+)
+
+// dashboardReady reports whether the dashboard has kitchen and porch, with porch talking
+func dashboardReady(s *intercomServer) bool {
+	named, talking := 0, false
+	for _, station := range s.dashboardState().Stations {
+		if station.Name != "" {
+			named++
+		}
+		talking = talking || station.Talking
+	}
+	return named == 2 && talking
+}
+
+func TestDashboardEvents(t *testing.T) {
+	server, grpcServer := newTestServer(10)
+	lis := bufconn.Listen(32 << 10)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	mux := http.NewServeMux()
+	server.registerDashboard(mux, testAdminToken)
+	web := httptest.NewServer(mux)
+	defer web.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// kitchen only joins, porch keeps talking so its level does not expire
+	connectStation(ctx, t, lis, "kitchen", 0)
+	porch, _ := connectStation(ctx, t, lis, "porch", 0)
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			err := porch.Send(&proto.Broadcast{Name: "porch", BroadcastType: &proto.Broadcast_Audio{Audio: &proto.Audio{
+				SampleRate: 8000,
+				Length:     1,
+				Samples:    []int32{1},
+				Level:      0.25,
+				Peak:       0.5,
+			}}})
+			if err != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !dashboardReady(server) {
+		if time.Now().After(deadline) {
+			t.Fatal("stations did not join and talk")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Run("needs the admin token", func(t *testing.T) {
+		resp, err := http.Get(web.URL + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("status %v without the token", resp.Status)
+		}
+	})
+
+	t.Run("streams the state with an idle station", func(t *testing.T) {
+		reqCtx, cancelReq := context.WithCancel(ctx)
+		defer cancelReq()
+		req, _ := http.NewRequest("GET", web.URL+"/events", nil)
+		req.SetBasicAuth("admin", testAdminToken)
+		resp, err := http.DefaultClient.Do(req.WithContext(reqCtx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		// a state that fails to marshal ends the stream before its first event
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err != nil {
+			t.Fatalf("no event: %v", err)
+		}
+		var state struct {
+			Stations []struct {
+				Name    string
+				Room    string
+				Talking bool
+				Level   float64
+			}
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &state); err != nil {
+			t.Fatalf("event %q: %v", line, err)
+		}
+		if len(state.Stations) != 2 {
+			t.Fatalf("%v stations, want kitchen and porch", len(state.Stations))
+		}
+		for _, station := range state.Stations {
+			talking := station.Name == "porch"
+			if station.Talking != talking || station.Room != defaultRoom {
+				t.Errorf("%v talking %v in room %q", station.Name, station.Talking, station.Room)
+			}
+			// a quarter of full scale is about -12dBFS
+			if talking && math.Abs(station.Level+12) > 0.1 {
+				t.Errorf("%v talking at %vdBFS", station.Name, station.Level)
+			}
+		}
+	})
+}
+
+func TestReceiveImage(t *testing.T) {
+	server := &intercomServer{log: logger.New(ioutil.Discard, logger.Error, logger.Text)}
+	r := &room{name: defaultRoom}
+	frame := &proto.Image{Width: 4, Height: 4, Type: 16, Bytes: make([]byte, 4*4*3)}
+
+	for _, image := range []*proto.Image{
+		{Width: 1 << 20, Height: 1 << 20, TileSize: 16, Keyframe: true},
+		{Width: 64, Height: 48, TileSize: -16, Keyframe: true},
+		{Width: 0, Height: 4, Bytes: make([]byte, 48)},
+		{Width: 4, Height: 4, Bytes: make([]byte, 47)},
+	} {
+		if err := server.receiveImage(r, "camera", image); err == nil {
+			t.Errorf("received %vx%v image in %v tiles", image.Width, image.Height, image.TileSize)
+		}
+	}
+	if r.video.seq != 0 {
+		t.Errorf("room's video at seq %v after only bad images", r.video.seq)
+	}
+
+	if err := server.receiveImage(r, "camera", frame); err != nil {
+		t.Fatal(err)
+	}
+	if server.thumbnails != nil {
+		t.Error("thumbnail made without a dashboard")
+	}
+
+	server.registerDashboard(http.NewServeMux(), testAdminToken)
+	if err := server.receiveImage(r, "camera", frame); err != nil {
+		t.Fatal(err)
+	}
+	if thumbnail, ok := server.thumbnails["camera"]; !ok || len(thumbnail.jpeg) == 0 {
+		t.Error("no thumbnail with the dashboard served")
+	}
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/proto"
+	"github.com/golang/protobuf/jsonpb"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// gatewayMaxMessage bounds the messages read from a browser
+	gatewayMaxMessage = 1 << 20
+	// audioFrameHeader is the bytes before the name in a binary audio frame
+	audioFrameHeader = 6
+)
+
+var errBadAudioFrame = errors.New("short audio frame")
+
+// wsFrame is a websocket message, binary frames carry audio and text frames
+// every other message as the JSON form of a Broadcast
+type wsFrame struct {
+	binary bool
+	data   []byte
+}
+
+var frameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		frame := v.(wsFrame)
+		if frame.binary {
+			return frame.data, websocket.BinaryFrame, nil
+		}
+		return frame.data, websocket.TextFrame, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*wsFrame)
+		frame.data = data
+		frame.binary = payloadType == websocket.BinaryFrame
+		return nil
+	},
+}
+
+// wsStream adapts a browser's websocket to the Intercom Connect stream, so the
+// browser joins through intercomServer.Connect like any other station
+type wsStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	conn   *websocket.Conn
+	// name is the station name the browser joined as, set on everything it sends
+	name   string
+	sendMu sync.Mutex
+}
+
+// wsAddr is the browser's address, as the peer of its stream
+type wsAddr string
+
+func (a wsAddr) Network() string { return "websocket" }
+func (a wsAddr) String() string  { return string(a) }
+
+// serveGateway serves the browser page and its websocket on addr, over TLS if
+// cert is set, which browsers need before they allow the mic
+func (s *intercomServer) serveGateway(addr, cert, key string) {
+	mux := http.NewServeMux()
+	s.registerGateway(mux)
+
+	s.log.Infof("browser gateway listening on %v", addr)
+	var err error
+	if cert != "" {
+		err = http.ListenAndServeTLS(addr, cert, key, mux)
+	} else {
+		err = http.ListenAndServe(addr, mux)
+	}
+	if err != nil {
+		s.log.Errorf("gateway error: %v", err)
+	}
+}
+
+func (s *intercomServer) registerGateway(mux *http.ServeMux) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(gatewayHTML))
+	})
+	mux.Handle("/connect", websocket.Handler(s.handleGateway))
+}
+
+// handleGateway joins a browser as the station named in the name query
+// parameter, in the room named in room or the default one.  The gateway has
+// no login, so a name already connected is refused rather than taken over.
+func (s *intercomServer) handleGateway(conn *websocket.Conn) {
+	conn.MaxPayloadBytes = gatewayMaxMessage
+	r := conn.Request()
+	name := r.FormValue("name")
+	if name == "" {
+		sendGatewayError(conn, "name required")
+		return
+	}
+	s.subscribersMutex.Lock()
+	inUse := s.subscriberNamed(name) != nil
+	s.subscribersMutex.Unlock()
+	if inUse {
+		sendGatewayError(conn, "a station named "+name+" is already connected")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: wsAddr(r.RemoteAddr)})
+	if room := r.FormValue("room"); room != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(roomMetadata, room))
+	}
+
+	stream := &wsStream{ctx: ctx, cancel: cancel, conn: conn, name: name}
+	if err := s.Connect(stream); err != nil {
+		sendGatewayError(conn, err.Error())
+	}
+}
+
+// sendGatewayError tells the browser why its stream is ending
+func sendGatewayError(conn *websocket.Conn, message string) {
+	data, _ := json.Marshal(map[string]string{"error": message})
+	websocket.Message.Send(conn, string(data))
+}
+
+func (w *wsStream) Context() context.Context {
+	return w.ctx
+}
+
+// Send writes audio as a binary frame and other messages as JSON.  Raw frames
+// are not sent, browsers only draw tiled video.
+func (w *wsStream) Send(broadcast *proto.Broadcast) error {
+	var frame wsFrame
+	switch {
+	case broadcast.GetAudio() != nil:
+		frame = wsFrame{binary: true, data: encodeAudioFrame(broadcast.Name, broadcast.GetAudio())}
+	case broadcast.GetImage() != nil && broadcast.GetImage().TileSize == 0:
+		return nil
+	default:
+		var buf bytes.Buffer
+		if err := (&jsonpb.Marshaler{}).Marshal(&buf, broadcast); err != nil {
+			return err
+		}
+		frame = wsFrame{data: buf.Bytes()}
+	}
+
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	return frameCodec.Send(w.conn, frame)
+}
+
+// Recv reads the browser's next message.  Browsers may send audio, status,
+// events and keyframe requests, anything else is skipped.  The stream's
+// context is cancelled once the websocket fails, which ends Connect.
+func (w *wsStream) Recv() (*proto.Broadcast, error) {
+	for {
+		var frame wsFrame
+		if err := frameCodec.Receive(w.conn, &frame); err != nil {
+			w.cancel()
+			return nil, err
+		}
+
+		broadcast := &proto.Broadcast{}
+		if frame.binary {
+			a, err := decodeAudioFrame(frame.data)
+			if err != nil {
+				continue
+			}
+			broadcast.BroadcastType = &proto.Broadcast_Audio{Audio: a}
+		} else {
+			unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+			if err := unmarshaler.Unmarshal(bytes.NewReader(frame.data), broadcast); err != nil {
+				continue
+			}
+			switch broadcast.BroadcastType.(type) {
+			case *proto.Broadcast_Status, *proto.Broadcast_Event, *proto.Broadcast_KeyframeRequest:
+			default:
+				continue
+			}
+		}
+		broadcast.Name = w.name
+		return broadcast, nil
+	}
+}
+
+func (w *wsStream) SetHeader(metadata.MD) error  { return nil }
+func (w *wsStream) SendHeader(metadata.MD) error { return nil }
+func (w *wsStream) SetTrailer(metadata.MD)       {}
+
+func (w *wsStream) SendMsg(m interface{}) error {
+	return w.Send(m.(*proto.Broadcast))
+}
+
+func (w *wsStream) RecvMsg(m interface{}) error {
+	broadcast, err := w.Recv()
+	if err != nil {
+		return err
+	}
+	*m.(*proto.Broadcast) = *broadcast
+	return nil
+}
+
+// encodeAudioFrame packs audio for a browser: sample rate as a uint32, channels
+// and the length of the station name as bytes, the name, then 16 bit samples,
+// all little endian
+func encodeAudioFrame(name string, a *proto.Audio) []byte {
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	channels := a.Channels
+	if channels < 1 {
+		channels = 1
+	}
+
+	data := make([]byte, audioFrameHeader+len(name)+len(a.Samples)*2)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(a.SampleRate))
+	data[4] = byte(channels)
+	data[5] = byte(len(name))
+	copy(data[audioFrameHeader:], name)
+	samples := data[audioFrameHeader+len(name):]
+	for i, sample := range a.Samples {
+		binary.LittleEndian.PutUint16(samples[i*2:], uint16(sample>>16))
+	}
+	return data
+}
+
+// decodeAudioFrame unpacks audio from a browser, in the same layout with the name left empty
+func decodeAudioFrame(data []byte) (*proto.Audio, error) {
+	if len(data) < audioFrameHeader || len(data) < audioFrameHeader+int(data[5]) {
+		return nil, errBadAudioFrame
+	}
+	rate := binary.LittleEndian.Uint32(data[0:4])
+	channels := int(data[4])
+	if rate == 0 || channels == 0 {
+		return nil, errBadAudioFrame
+	}
+
+	pcm := data[audioFrameHeader+int(data[5]):]
+	samples := make([]int32, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int32(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) << 16
+	}
+
+	level, peak := audio.Levels(samples)
+	return &proto.Audio{
+		SampleRate: int32(rate),
+		Channels:   int32(channels),
+		Length:     int32(len(samples) / channels),
+		Samples:    samples,
+		Level:      float32(level),
+		Peak:       float32(peak),
+	}, nil
+}
+
+const gatewayHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Intercom</title>
+<style>
+body { font-family: sans-serif; margin: 1em; max-width: 40em; }
+button { font-size: 1.1em; padding: .5em 1em; margin: .2em 0; }
+#talk { width: 100%; padding: 1.2em; user-select: none; -webkit-user-select: none; touch-action: none; }
+#talk.on { background: #2a2; color: white; }
+#video { width: 100%; background: #333; display: none; }
+#event { font-weight: bold; min-height: 1.3em; }
+#stations { padding-left: 1.2em; }
+#stations .talking { color: #2a2; font-weight: bold; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>Intercom</h1>
+<div id="join">
+	<input id="name" placeholder="your name">
+	<input id="roomName" placeholder="room (optional)">
+	<button id="joinButton">Join</button>
+</div>
+<div id="room" class="hidden">
+	<p id="status"></p>
+	<p id="event"></p>
+	<button id="answer" class="hidden">Answer</button>
+	<button id="hangup" class="hidden">Hang up</button>
+	<canvas id="video"></canvas>
+	<button id="talk">Hold to talk</button>
+	<ul id="stations"></ul>
+</div>
+<script>
+var ws, ctx, myName, myRoom;
+var playTime = 0, holding = false, ringFrom = "", callWith = "";
+var haveKeyframe = false, lastKeyframeRequest = 0, lastFrame = 0;
+var canvas = document.getElementById("video"), g = canvas.getContext("2d");
+
+function $(id) { return document.getElementById(id); }
+function send(msg) { if (ws && ws.readyState === 1) ws.send(JSON.stringify(msg)); }
+function talking() { return holding || callWith !== ""; }
+
+$("name").value = localStorage.getItem("intercomName") || "";
+$("roomName").value = localStorage.getItem("intercomRoom") || "";
+$("joinButton").onclick = function () {
+	myName = $("name").value.trim();
+	myRoom = $("roomName").value.trim();
+	if (!myName) return;
+	localStorage.setItem("intercomName", myName);
+	localStorage.setItem("intercomRoom", myRoom);
+	ctx = new (window.AudioContext || window.webkitAudioContext)();
+	$("join").classList.add("hidden");
+	$("room").classList.remove("hidden");
+	connect();
+};
+
+function connect() {
+	$("status").textContent = "connecting...";
+	var scheme = location.protocol === "https:" ? "wss:" : "ws:";
+	var url = scheme + "//" + location.host + "/connect?name=" + encodeURIComponent(myName);
+	if (myRoom) url += "&room=" + encodeURIComponent(myRoom);
+	ws = new WebSocket(url);
+	ws.binaryType = "arraybuffer";
+	ws.onopen = function () {
+		$("status").textContent = "connected as " + myName;
+		haveKeyframe = false;
+		send({status: {}});
+		startMic();
+	};
+	ws.onmessage = function (e) {
+		if (typeof e.data === "string") handle(JSON.parse(e.data));
+		else play(e.data);
+	};
+	ws.onclose = function () {
+		$("status").textContent = "disconnected, reconnecting...";
+		setTimeout(connect, 2000);
+	};
+}
+
+var micStarted = false;
+function startMic() {
+	if (micStarted || !navigator.mediaDevices) return;
+	micStarted = true;
+	navigator.mediaDevices.getUserMedia({audio: true}).then(function (stream) {
+		var source = ctx.createMediaStreamSource(stream);
+		var processor = ctx.createScriptProcessor(4096, 1, 1);
+		processor.onaudioprocess = function (e) {
+			if (!talking()) return;
+			var input = e.inputBuffer.getChannelData(0);
+			var buf = new ArrayBuffer(6 + input.length * 2), view = new DataView(buf);
+			view.setUint32(0, ctx.sampleRate, true);
+			view.setUint8(4, 1);
+			view.setUint8(5, 0);
+			for (var i = 0; i < input.length; i++) {
+				view.setInt16(6 + i * 2, Math.max(-1, Math.min(1, input[i])) * 32767, true);
+			}
+			if (ws.readyState === 1) ws.send(buf);
+		};
+		source.connect(processor);
+		processor.connect(ctx.destination);
+	}).catch(function (err) {
+		$("talk").disabled = true;
+		$("talk").textContent = "no mic: " + err.message;
+	});
+}
+
+function play(buf) {
+	var view = new DataView(buf);
+	var rate = view.getUint32(0, true), channels = view.getUint8(4), nameLength = view.getUint8(5);
+	var offset = 6 + nameLength, frames = Math.floor((buf.byteLength - offset) / 2 / channels);
+	if (frames === 0) return;
+	var audio = ctx.createBuffer(channels, frames, rate);
+	for (var ch = 0; ch < channels; ch++) {
+		var out = audio.getChannelData(ch);
+		for (var i = 0; i < frames; i++) out[i] = view.getInt16(offset + (i * channels + ch) * 2, true) / 32768;
+	}
+	var source = ctx.createBufferSource();
+	source.buffer = audio;
+	source.connect(ctx.destination);
+	playTime = Math.max(playTime, ctx.currentTime + 0.05);
+	source.start(playTime);
+	playTime += audio.duration;
+}
+
+function handle(msg) {
+	if (msg.error) $("status").textContent = msg.error;
+	if (msg.roster) {
+		// the roster names the room, which the admin service may have moved the station to
+		$("status").textContent = "connected as " + myName + " in " + msg.roster.room;
+		showRoster(msg.roster.stations || []);
+	}
+	if (msg.event) handleEvent(msg.name || "", msg.event);
+	if (msg.image) drawFrame(msg.image);
+}
+
+function showRoster(stations) {
+	var list = $("stations");
+	list.innerHTML = "";
+	stations.forEach(function (s) {
+		var li = document.createElement("li");
+		var status = s.status || {};
+		li.textContent = s.name + (status.privacy ? " (privacy)" : status.micMuted ? " (muted)" : status.cameraOff ? " (audio only)" : "");
+		if (s.level > 0) li.className = "talking";
+		list.appendChild(li);
+	});
+}
+
+function handleEvent(from, event) {
+	var text = "";
+	switch (event.type) {
+	case "MOTION": text = "motion at " + from; break;
+	case "RING": ringFrom = from; text = "ring at " + from; break;
+	case "ANSWER":
+		if (event.station === ringFrom) ringFrom = "";
+		text = from + " answered " + event.station;
+		break;
+	case "HANGUP":
+		if (from === callWith) { callWith = ""; text = from + " hung up"; }
+		break;
+	case "ANNOUNCEMENT": text = event.text; break;
+	}
+	if (text) $("event").textContent = text;
+	updateCall();
+}
+
+function updateCall() {
+	$("answer").classList.toggle("hidden", ringFrom === "" || callWith !== "");
+	$("hangup").classList.toggle("hidden", callWith === "");
+	$("talk").classList.toggle("on", talking());
+	if (callWith) $("event").textContent = "talking to " + callWith;
+}
+
+$("answer").onclick = function () {
+	callWith = ringFrom;
+	ringFrom = "";
+	send({event: {type: "ANSWER", station: callWith}});
+	updateCall();
+};
+$("hangup").onclick = function () {
+	send({event: {type: "HANGUP", station: callWith}});
+	callWith = "";
+	$("event").textContent = "";
+	updateCall();
+};
+
+function holdTalk(on) { return function (e) { e.preventDefault(); holding = on; ctx.resume(); updateCall(); }; }
+$("talk").onpointerdown = holdTalk(true);
+$("talk").onpointerup = holdTalk(false);
+$("talk").onpointerleave = holdTalk(false);
+
+function drawFrame(image) {
+	if (!image.tileSize) return;
+	if (image.keyframe) haveKeyframe = true;
+	if (!haveKeyframe) {
+		if (Date.now() - lastKeyframeRequest > 1000) {
+			lastKeyframeRequest = Date.now();
+			send({keyframeRequest: {}});
+		}
+		return;
+	}
+	if (canvas.width !== image.width || canvas.height !== image.height) {
+		canvas.width = image.width;
+		canvas.height = image.height;
+	}
+	canvas.style.display = "block";
+	lastFrame = Date.now();
+	(image.tiles || []).forEach(function (tile) {
+		var img = new Image();
+		img.onload = function () { g.drawImage(img, (tile.x || 0) * image.tileSize, (tile.y || 0) * image.tileSize); };
+		img.src = "data:image/jpeg;base64," + tile.jpeg;
+	});
+}
+setInterval(function () {
+	if (Date.now() - lastFrame > 1000) {
+		canvas.style.display = "none";
+		haveKeyframe = false;
+	}
+}, 500);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"golang.org/x/net/websocket"
+
+	"github.com/3xcellent/intercom/proto"
+)
+
+func TestGatewayRoom(t *testing.T) {
+	server, _ := newTestServer(10)
+	mux := http.NewServeMux()
+	server.registerGateway(mux)
+	web := httptest.NewServer(mux)
+	defer web.Close()
+
+	tests := []struct {
+		query string
+		room  string
+	}{
+		{"name=phone&room=upstairs", "upstairs"},
+		{"name=tablet", defaultRoom},
+	}
+	for _, test := range tests {
+		t.Run(test.room, func(t *testing.T) {
+			url := strings.Replace(web.URL, "http://", "ws://", 1) + "/connect?" + test.query
+			conn, err := websocket.Dial(url, "", web.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if err := websocket.Message.Send(conn, `{"status": {}}`); err != nil {
+				t.Fatal(err)
+			}
+
+			// the first roster after joining is of the station's room
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				var frame wsFrame
+				if err := frameCodec.Receive(conn, &frame); err != nil {
+					t.Fatal(err)
+				}
+				broadcast := &proto.Broadcast{}
+				if frame.binary || jsonpb.Unmarshal(bytes.NewReader(frame.data), broadcast) != nil {
+					continue
+				}
+				roster := broadcast.GetRoster()
+				if roster == nil || len(roster.Stations) == 0 {
+					continue
+				}
+				if roster.Room != test.room {
+					t.Errorf("joined room %q, want %q", roster.Room, test.room)
+				}
+				return
+			}
+		})
+	}
+}
+
+func TestGatewayNameInUse(t *testing.T) {
+	server, _ := newTestServer(10)
+	mux := http.NewServeMux()
+	server.registerGateway(mux)
+	web := httptest.NewServer(mux)
+	defer web.Close()
+	server.addSubscriber("kitchen", defaultRoom, newSubscriber(&server.metrics, server.log))
+
+	url := strings.Replace(web.URL, "http://", "ws://", 1) + "/connect?name=kitchen"
+	conn, err := websocket.Dial(url, "", web.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var reply struct{ Error string }
+	if err := websocket.JSON.Receive(conn, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply.Error, "already connected") {
+		t.Errorf("joined as a connected station's name, got %+v", reply)
+	}
+
+	server.subscribersMutex.Lock()
+	defer server.subscribersMutex.Unlock()
+	if sub := server.subscriberNamed("kitchen"); sub == nil || sub.kickReason() != "" {
+		t.Error("the connected station lost its name")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+const (
+	grpcContentType    = "application/grpc"
+	grpcWebContentType = "application/grpc-web"
+	grpcWebTextType    = "application/grpc-web-text"
+	// grpcWebTrailerFlag marks the frame that carries the trailers at the end of
+	// a gRPC-Web response, where gRPC has HTTP/2 trailers
+	grpcWebTrailerFlag = 0x80
+	// connectMethod is the Intercom Connect stream's path
+	connectMethod = "/Intercom/Connect"
+)
+
+// grpcWebHandler serves gRPC and gRPC-Web from one listener: gRPC calls go
+// straight to grpcServer and gRPC-Web calls are translated to gRPC first, so
+// browsers and other clients without HTTP/2 trailers can use intercom.proto
+// as is.  Without TLS, HTTP/2 is spoken in the clear (h2c), as gRPC is.
+func grpcWebHandler(grpcServer *grpc.Server) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, grpcContentType) && !strings.HasPrefix(contentType, grpcWebContentType) {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+
+		allowCORS(w, r)
+		switch {
+		case r.Method == http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method != http.MethodPost:
+			http.Error(w, "gRPC-Web calls are POSTs", http.StatusMethodNotAllowed)
+		case strings.HasPrefix(contentType, grpcWebContentType):
+			serveGRPCWeb(grpcServer, w, r)
+		default:
+			http.Error(w, "not a gRPC or gRPC-Web call", http.StatusUnsupportedMediaType)
+		}
+	})
+	return h2c.NewHandler(handler, &http2.Server{})
+}
+
+// allowCORS lets pages from any origin make gRPC-Web calls, the admin service
+// still needs its token
+func allowCORS(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+	h := w.Header()
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+	h.Set("Access-Control-Expose-Headers", "grpc-status, grpc-message, grpc-status-details-bin")
+	h.Set("Access-Control-Max-Age", "600")
+	h.Add("Vary", "Origin")
+}
+
+// serveGRPCWeb translates a gRPC-Web call to gRPC.  The messages are framed the
+// same way in both, so only the content type changes on the way in and on
+// the way out the trailers become a last frame in the body.  The -text
+// content types are the same base64 encoded.
+func serveGRPCWeb(grpcServer *grpc.Server, w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, grpcWebTextType)
+
+	// grpcServer only takes HTTP/2, which the framing is the same over
+	r.ProtoMajor, r.ProtoMinor, r.Proto = 2, 0, "HTTP/2.0"
+	if text {
+		r.Header.Set("Content-Type", grpcContentType+strings.TrimPrefix(contentType, grpcWebTextType))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{base64.NewDecoder(base64.StdEncoding, r.Body), r.Body}
+	} else {
+		r.Header.Set("Content-Type", grpcContentType+strings.TrimPrefix(contentType, grpcWebContentType))
+	}
+	if r.URL.Path == connectMethod {
+		// over HTTP/1.1 a gRPC-Web client sends all of its messages up front, so the
+		// end of its request is not the end of its stream, it hangs up by closing
+		r.Body = &holdOpenBody{ReadCloser: r.Body, ctx: r.Context(), closed: make(chan struct{})}
+	}
+
+	resp := &grpcWebResponse{w: w, ctx: r.Context(), header: http.Header{}, contentType: contentType, text: text}
+	grpcServer.ServeHTTP(resp, r)
+	resp.writeTrailers()
+}
+
+// holdOpenBody is a request body whose end is only reported once the request
+// is over
+type holdOpenBody struct {
+	io.ReadCloser
+	ctx       context.Context
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (b *holdOpenBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		if n > 0 {
+			return n, nil
+		}
+		select {
+		case <-b.ctx.Done():
+		case <-b.closed:
+		}
+	}
+	return n, err
+}
+
+func (b *holdOpenBody) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+	return b.ReadCloser.Close()
+}
+
+// grpcWebResponse is the ResponseWriter grpcServer writes a gRPC response to,
+// passing it on as gRPC-Web
+type grpcWebResponse struct {
+	w http.ResponseWriter
+	// ctx is the request's, done once the client hangs up
+	ctx context.Context
+	// header is grpcServer's, trailers included, and is copied to w's
+	// headers when they are written
+	header      http.Header
+	wroteHeader bool
+	contentType string
+	text        bool
+	// pending is what is to be base64 encoded on the next flush, so each
+	// message is encoded in one piece
+	pending []byte
+}
+
+func (g *grpcWebResponse) Header() http.Header {
+	return g.header
+}
+
+func (g *grpcWebResponse) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	h := g.w.Header()
+	trailers := g.trailerNames()
+	for k, v := range g.header {
+		if k == "Trailer" || trailers[k] || strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+		h[k] = v
+	}
+	h.Set("Content-Type", g.contentType)
+	g.w.WriteHeader(code)
+}
+
+func (g *grpcWebResponse) Write(p []byte) (int, error) {
+	g.WriteHeader(http.StatusOK)
+	if !g.text {
+		return g.w.Write(p)
+	}
+	g.pending = append(g.pending, p...)
+	return len(p), nil
+}
+
+func (g *grpcWebResponse) Flush() {
+	g.WriteHeader(http.StatusOK)
+	if len(g.pending) > 0 {
+		g.w.Write([]byte(base64.StdEncoding.EncodeToString(g.pending)))
+		g.pending = g.pending[:0]
+	}
+	if flusher, ok := g.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify reports the client hanging up, which grpcServer watches for
+// to end the call
+func (g *grpcWebResponse) CloseNotify() <-chan bool {
+	closed := make(chan bool, 1)
+	go func() {
+		<-g.ctx.Done()
+		closed <- true
+	}()
+	return closed
+}
+
+// trailerNames are the headers grpcServer declared it sends as trailers
+func (g *grpcWebResponse) trailerNames() map[string]bool {
+	names := map[string]bool{}
+	for _, value := range g.header["Trailer"] {
+		for _, name := range strings.Split(value, ",") {
+			names[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	return names
+}
+
+// writeTrailers ends the response with the trailer frame, once grpcServer is
+// done with it
+func (g *grpcWebResponse) writeTrailers() {
+	var lines []string
+	trailers := g.trailerNames()
+	for k, values := range g.header {
+		name := strings.TrimPrefix(k, http2.TrailerPrefix)
+		if !trailers[k] && name == k {
+			continue
+		}
+		for _, v := range values {
+			lines = append(lines, fmt.Sprintf("%v: %v\r\n", strings.ToLower(name), v))
+		}
+	}
+	sort.Strings(lines)
+	block := strings.Join(lines, "")
+
+	frame := make([]byte, 5, 5+len(block))
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(block)))
+	frame = append(frame, block...)
+	g.Write(frame)
+	g.Flush()
+}
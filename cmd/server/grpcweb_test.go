@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+)
+
+const testAdminToken = "secret"
+
+// grpcServer's handler transport takes a CloseNotifier in older gRPC releases
+var _ http.CloseNotifier = (*grpcWebResponse)(nil)
+
+// newTestServer is an intercom server at fps with the admin service on
+// testAdminToken, as main sets it up
+func newTestServer(fps int) (*intercomServer, *grpc.Server) {
+	server := &intercomServer{
+		log:           logger.New(ioutil.Discard, logger.Error, logger.Text),
+		frameInterval: time.Second / time.Duration(fps),
+		started:       time.Now(),
+	}
+	admin := &adminServer{server: server, token: testAdminToken}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(admin.authorize))
+	proto.RegisterIntercomServer(grpcServer, server)
+	proto.RegisterIntercomAdminServer(grpcServer, admin)
+	return server, grpcServer
+}
+
+func streamCount(s *intercomServer) int {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+	return len(s.streams)
+}
+
+// grpcWebFrame frames a message as gRPC and gRPC-Web both do
+func grpcWebFrame(t *testing.T, msg protobuf.Message) []byte {
+	data, err := protobuf.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// readGRPCWebFrame reads the next frame of a gRPC-Web response, returning its
+// flags and payload
+func readGRPCWebFrame(r io.Reader) (byte, []byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return prefix[0], payload, nil
+}
+
+// decodeGRPCWebText decodes a -text response, which is base64 encoded a
+// flush at a time, each piece padded
+func decodeGRPCWebText(data []byte) ([]byte, error) {
+	var out []byte
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '=')
+		if end < 0 {
+			end = len(data)
+		}
+		for end < len(data) && data[end] == '=' {
+			end++
+		}
+		piece, err := base64.StdEncoding.DecodeString(string(data[:end]))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, piece...)
+		data = data[end:]
+	}
+	return out, nil
+}
+
+func TestGRPCWebUnary(t *testing.T) {
+	_, grpcServer := newTestServer(10)
+	defer grpcServer.Stop()
+	web := httptest.NewServer(grpcWebHandler(grpcServer))
+	defer web.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		token       string
+		wantStatus  string
+	}{
+		{"binary", "application/grpc-web+proto", testAdminToken, "0"},
+		{"text", "application/grpc-web-text+proto", testAdminToken, "0"},
+		{"wrong token", "application/grpc-web+proto", "wrong", "7"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := grpcWebFrame(t, &proto.GetSettingsRequest{})
+			text := strings.HasPrefix(test.contentType, grpcWebTextType)
+			if text {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+			req, err := http.NewRequest(http.MethodPost, web.URL+"/IntercomAdmin/GetSettings", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", test.contentType)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %v, want 200", resp.Status)
+			}
+			if got := resp.Header.Get("Content-Type"); got != test.contentType {
+				t.Errorf("content type %q, want %q", got, test.contentType)
+			}
+
+			data, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text {
+				if data, err = decodeGRPCWebText(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := bytes.NewReader(data)
+
+			if test.wantStatus == "0" {
+				flags, payload, err := readGRPCWebFrame(r)
+				if err != nil || flags != 0 {
+					t.Fatalf("first frame flags %#x, %v, want a message", flags, err)
+				}
+				settings := &proto.Settings{}
+				if err := protobuf.Unmarshal(payload, settings); err != nil {
+					t.Fatal(err)
+				}
+				if settings.FrameRate != 10 {
+					t.Errorf("frame rate %v, want 10", settings.FrameRate)
+				}
+			}
+
+			flags, payload, err := readGRPCWebFrame(r)
+			if err != nil || flags != grpcWebTrailerFlag {
+				t.Fatalf("frame flags %#x, %v, want the trailers", flags, err)
+			}
+			if want := "grpc-status: " + test.wantStatus + "\r\n"; !strings.Contains(string(payload), want) {
+				t.Errorf("trailers %q, want %q", payload, want)
+			}
+		})
+	}
+}
+
+func TestGRPCWebConnect(t *testing.T) {
+	server, grpcServer := newTestServer(10)
+	defer grpcServer.Stop()
+	web := httptest.NewServer(grpcWebHandler(grpcServer))
+	defer web.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	body := grpcWebFrame(t, &proto.Broadcast{Name: "browser", BroadcastType: &proto.Broadcast_Status{Status: &proto.Status{}}})
+	req, err := http.NewRequest(http.MethodPost, web.URL+connectMethod, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %v, want 200", resp.Status)
+	}
+
+	// the stream stays open after the request body ends, sending the roster
+	// with the station in it
+	r := bufio.NewReader(resp.Body)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		flags, payload, err := readGRPCWebFrame(r)
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		if flags != 0 {
+			t.Fatalf("stream ended with trailers %q", payload)
+		}
+		msg := &proto.Broadcast{}
+		if err := protobuf.Unmarshal(payload, msg); err != nil {
+			t.Fatal(err)
+		}
+		if roster := msg.GetRoster(); roster != nil && len(roster.Stations) == 1 && roster.Stations[0].Name == "browser" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no roster with the station in it")
+		}
+	}
+
+	// hanging up ends the stream on the server
+	cancel()
+	for time.Now().Before(deadline) {
+		if streamCount(server) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("stream still open after the client hung up")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/3xcellent/intercom/dial"
+	"github.com/3xcellent/intercom/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+const (
+	// intercomService is the Intercom service's full name, as health checks ask for it
+	intercomService = "Intercom"
+	// drainTimeout is how long streams get to finish once the server is stopping,
+	// they are long lived so any still open after it are cut off
+	drainTimeout = 10 * time.Second
+	// healthcheckTimeout bounds a -healthcheck run
+	healthcheckTimeout = 3 * time.Second
+)
+
+// registerHealth adds the standard health service, reporting not serving until
+// markServing, and reflection for tools like grpcurl
+func registerHealth(grpcServer *grpc.Server) *health.Server {
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(intercomService, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	reflection.Register(grpcServer)
+	return healthServer
+}
+
+// markServing reports the server ready, once its listener is up
+func markServing(healthServer *health.Server) {
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(intercomService, healthpb.HealthCheckResponse_SERVING)
+}
+
+// drainOnSignal stops the server on SIGINT or SIGTERM, reporting not serving
+// first so probes take it out of rotation while its streams finish.
+// httpServer is the one serving gRPC-Web, if it is served.
+func drainOnSignal(grpcServer *grpc.Server, httpServer *http.Server, healthServer *health.Server, log *logger.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+
+	log.Infof("%v, draining", sig)
+	healthServer.Shutdown()
+
+	if httpServer != nil {
+		// grpcServer cannot drain the streams it is handed by an HTTP server, the
+		// HTTP server waits for them instead
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Warnf("streams still open after draining, stopping")
+			httpServer.Close()
+		}
+		grpcServer.Stop()
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(drainTimeout):
+		log.Warnf("streams still open after draining, stopping")
+		grpcServer.Stop()
+	}
+}
+
+// runHealthcheck asks the server at addr whether it is serving, for container
+// probes, and returns the exit code.  Over TLS the certificate is not checked,
+// the probe only asks whether the server is up.
+func runHealthcheck(addr string, useTLS bool, log *logger.Logger) int {
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+
+	config := dial.Config{Addr: addr, TLS: useTLS, InsecureSkipVerify: true}
+	options, err := config.Options()
+	if err != nil {
+		log.Errorf("healthcheck: %v", err)
+		return 1
+	}
+	conn, err := grpc.DialContext(ctx, addr, append(options, grpc.WithBlock())...)
+	if err != nil {
+		log.Errorf("healthcheck: cannot connect to %v: %v", addr, err)
+		return 1
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: intercomService})
+	if err != nil {
+		log.Errorf("healthcheck: %v", err)
+		return 1
+	}
+
+	log.Infof("healthcheck: %v", resp.Status)
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return 1
+	}
+	return 0
+}
@@ -1,106 +1,604 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"net"
+	"net/http"
+	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/logger"
 	"github.com/3xcellent/intercom/proto"
+	"github.com/3xcellent/intercom/video"
+	protobuf "github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// levelExpiry clears a station's level once its audio stops arriving
+	levelExpiry = 300 * time.Millisecond
+	// levelStep is how far a station's level has to move, in dB, before the roster is resent
+	levelStep = 3
+	// levelFloor is where levels bottom out, in dBFS
+	levelFloor = -60
+
+	// queueBackedUp is the queue length at which a stream's frames are dropped and scaled down
+	queueBackedUp = 8
+	// maxFrameScale is the most a stream's frames are scaled down, in each direction
+	maxFrameScale = 4
+	// keyframeHold is the least time between keyframe requests passed on to the station sending video
+	keyframeHold = time.Second
+
+	// scaleDownHold and scaleUpHold are the least time between quality changes, recovering
+	// slower than backing off so a stream does not flap between the two
+	scaleDownHold = time.Second
+	scaleUpHold   = 5 * time.Second
 )
 
 type intercomServer struct {
-	imgMutex                   sync.Mutex
-	audioMutex                 sync.Mutex
-	lastBroadcastImageReceived time.Time
-	lastBroadcastAudioReceived time.Time
-	hasIncomingBroadcastImage  bool
-	currentBroadcastImage      proto.Image
-	currentBroadcastAudioCache []proto.Audio
+	log *logger.Logger
+	// nextStreamID numbers streams for the logs
+	nextStreamID int64
+
+	// imgMutex guards each room's video and the thumbnails
+	imgMutex sync.Mutex
+	// thumbnails holds a small JPEG of each station's latest frame for the
+	// dashboard, nil unless the dashboard is served
+	thumbnails map[string]*thumbnail
+
+	settingsMutex sync.Mutex
+	// frameInterval is the least time between frames sent to each stream
+	frameInterval time.Duration
+
+	subscribersMutex sync.Mutex
+	// rooms holds each connected station's outgoing queues by room, then by name
+	rooms map[string]*room
+	// streams holds every connected stream by id, including those not named yet
+	streams map[int64]*subscriber
+
+	metrics serverMetrics
+	// started is when the server started, for its uptime
+	started  time.Time
+	recorder recorder
+
+	rosterMutex sync.Mutex
+	stations    map[string]*proto.Station
+	// rosterVersion is bumped on every roster change so each stream knows when to resend it
+	rosterVersion int
+	// levelUpdated is when each talking station's level was last set
+	levelUpdated map[string]time.Time
+	// forceMuted holds the stations whose audio is dropped, set by the admin service
+	forceMuted map[string]bool
 }
 
-func (s *intercomServer) isCurrentlyBroadcasting() bool {
-	if !s.hasIncomingBroadcastImage {
-		return false
+// updateStation adds or updates a connected station, status may be nil if it has not sent one
+func (s *intercomServer) updateStation(name string, status *proto.Status) {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+
+	if s.stations == nil {
+		s.stations = map[string]*proto.Station{}
+	}
+
+	station, ok := s.stations[name]
+	if ok && status == nil {
+		return
+	}
+	if !ok {
+		station = &proto.Station{Name: name, Status: &proto.Status{}}
+		s.stations[name] = station
+	}
+	if status != nil {
+		station.Status = status
+	}
+	s.rosterVersion++
+}
+
+func (s *intercomServer) removeStation(name string) {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+
+	if _, ok := s.stations[name]; !ok {
+		return
+	}
+	delete(s.stations, name)
+	delete(s.levelUpdated, name)
+	s.rosterVersion++
+}
+
+// updateLevel records the level of a station's latest audio, the roster is
+// only resent when it moves by a levelStep so talking does not flood it
+func (s *intercomServer) updateLevel(name string, level, peak float32) {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+
+	station, ok := s.stations[name]
+	if !ok {
+		return
+	}
+
+	if s.levelUpdated == nil {
+		s.levelUpdated = map[string]time.Time{}
+	}
+	s.levelUpdated[name] = time.Now()
+
+	if levelBucket(station.Level) != levelBucket(level) {
+		s.rosterVersion++
+	}
+	station.Level = level
+	station.Peak = peak
+}
+
+func levelBucket(level float32) int {
+	db := audio.Decibels(float64(level))
+	if db < levelFloor {
+		db = levelFloor
+	}
+	return int(db / levelStep)
+}
+
+// expireLevels zeroes the level of stations that stopped talking, the caller must hold rosterMutex
+func (s *intercomServer) expireLevels() {
+	for name, updated := range s.levelUpdated {
+		if time.Since(updated) < levelExpiry {
+			continue
+		}
+		if station, ok := s.stations[name]; ok {
+			station.Level = 0
+			station.Peak = 0
+		}
+		delete(s.levelUpdated, name)
+		s.rosterVersion++
+	}
+}
+
+// rosterSince returns the roster of the room named roomName and its version
+// if it changed after version, otherwise nil
+func (s *intercomServer) rosterSince(version int, roomName string) (*proto.Roster, int) {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+
+	s.expireLevels()
+	if version == s.rosterVersion {
+		return nil, version
+	}
+
+	roster := &proto.Roster{Room: roomName}
+	for _, station := range s.stations {
+		if station.Room != roomName {
+			continue
+		}
+		roster.Stations = append(roster.Stations, &proto.Station{
+			Name:   station.Name,
+			Status: s.stationStatus(station),
+			Level:  station.Level,
+			Peak:   station.Peak,
+			Room:   station.Room,
+		})
+	}
+	sort.Slice(roster.Stations, func(i, j int) bool {
+		return roster.Stations[i].Name < roster.Stations[j].Name
+	})
+	return roster, s.rosterVersion
+}
+
+// stationStatus is the status shown for station, a force muted station shows
+// as muted whatever it sent.  The caller must hold rosterMutex.
+func (s *intercomServer) stationStatus(station *proto.Station) *proto.Status {
+	if !s.forceMuted[station.Name] || station.Status.MicMuted {
+		return station.Status
+	}
+	status := *station.Status
+	status.MicMuted = true
+	return &status
+}
+
+func (s *intercomServer) setForceMuted(name string, muted bool) {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+
+	if s.forceMuted == nil {
+		s.forceMuted = map[string]bool{}
+	}
+	if s.forceMuted[name] == muted {
+		return
+	}
+	if muted {
+		s.forceMuted[name] = true
+	} else {
+		delete(s.forceMuted, name)
+	}
+	s.rosterVersion++
+}
+
+func (s *intercomServer) isForceMuted(name string) bool {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+	return s.forceMuted[name]
+}
+
+func (s *intercomServer) getFrameInterval() time.Duration {
+	s.settingsMutex.Lock()
+	defer s.settingsMutex.Unlock()
+	return s.frameInterval
+}
+
+func (s *intercomServer) setFrameInterval(interval time.Duration) {
+	s.settingsMutex.Lock()
+	defer s.settingsMutex.Unlock()
+	s.frameInterval = interval
+}
+
+// imageSince returns the room's current image if it is newer than seq and
+// frames are still arriving, otherwise nil.  Tiled video is returned as the
+// tiles that changed after seq, or a keyframe.
+func (s *intercomServer) imageSince(r *room, seq int) (*proto.Image, int) {
+	s.imgMutex.Lock()
+	defer s.imgMutex.Unlock()
+
+	v := &r.video
+	if seq == v.seq || !v.live() {
+		return nil, seq
+	}
+	if v.image.TileSize > 0 {
+		if v.tiles.tiles == nil {
+			return nil, seq
+		}
+		return v.tiles.since(seq), v.seq
+	}
+	image := v.image
+	return &image, v.seq
+}
+
+// receiveImage makes image from station the current one in its room.  It
+// returns an error, and drops the image, if its size is one stations could not
+// allocate for or its raw pixels do not fill it.
+func (s *intercomServer) receiveImage(r *room, station string, image *proto.Image) error {
+	if image.TileSize != 0 {
+		if err := video.CheckTiled(int(image.Width), int(image.Height), int(image.TileSize)); err != nil {
+			return err
+		}
+	} else if _, err := video.CheckFrame(image.Bytes, int(image.Width), int(image.Height)); err != nil {
+		return err
+	}
+
+	s.imgMutex.Lock()
+	defer s.imgMutex.Unlock()
+
+	v := &r.video
+	v.image = *image
+	v.seq++
+	v.from = station
+	v.lastReceived = time.Now()
+
+	if image.TileSize == 0 {
+		v.tiles.reset()
+		s.updateThumbnail(v, station, image)
+		return nil
+	}
+	if !v.tiles.apply(station, image, v.seq) {
+		s.requestKeyframe(v)
+		return nil
+	}
+	s.updateThumbnail(v, station, image)
+	return nil
+}
+
+// requestKeyframe asks the station sending video for every tile, the caller must hold imgMutex
+func (s *intercomServer) requestKeyframe(v *roomVideo) {
+	if time.Since(v.lastKeyframeRequest) < keyframeHold {
+		return
+	}
+	v.lastKeyframeRequest = time.Now()
+	s.sendToStation(v.from, &proto.Broadcast{
+		BroadcastType: &proto.Broadcast_KeyframeRequest{KeyframeRequest: &proto.KeyframeRequest{}},
+	})
+}
+
+func (s *intercomServer) addStream(sub *subscriber) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	if s.streams == nil {
+		s.streams = map[int64]*subscriber{}
+	}
+	s.streams[sub.id] = sub
+}
+
+func (s *intercomServer) removeStream(sub *subscriber) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+	delete(s.streams, sub.id)
+}
+
+// sendHint asks the station sending video in a room to keep under a bitrate
+func (s *intercomServer) sendHint(r *room, hint *proto.BitrateHint) {
+	s.imgMutex.Lock()
+	name := r.video.from
+	s.imgMutex.Unlock()
+
+	s.log.With("station", name).With("room", r.name).Infof("asking to keep video under %v bits/s", hint.MaxVideoBitrate)
+	s.sendToStation(name, &proto.Broadcast{BroadcastType: &proto.Broadcast_Bitrate{Bitrate: hint}})
+}
+
+// sendToStation queues a message for one station, it is dropped rather than
+// waited on if that station's queue is full
+func (s *intercomServer) sendToStation(name string, broadcast *proto.Broadcast) {
+	s.subscribersMutex.Lock()
+	sub := s.subscriberNamed(name)
+	s.subscribersMutex.Unlock()
+	if sub == nil {
+		return
+	}
+
+	select {
+	case sub.priority <- broadcast:
+	default:
+	}
+}
+
+// relay queues a message for every other station in from's room, dropping it for any that are full
+func (s *intercomServer) relay(from *subscriber, broadcast *proto.Broadcast) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	if from.room == nil {
+		return
+	}
+	for _, sub := range from.room.subscribers {
+		if sub == from {
+			continue
+		}
+		select {
+		case sub.priority <- broadcast:
+		default:
+		}
+	}
+}
+
+// relayAll queues a message for every station in every room, dropping it for any that are full
+func (s *intercomServer) relayAll(broadcast *proto.Broadcast) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	for _, r := range s.rooms {
+		for _, sub := range r.subscribers {
+			select {
+			case sub.priority <- broadcast:
+			default:
+			}
+		}
 	}
+}
+
+// framePacer paces the frames sent to one stream, and scales them down while
+// the stream's queue is backed up
+type framePacer struct {
+	log        *logger.Logger
+	interval   time.Duration
+	lastSent   time.Time
+	scale      int
+	lastScaled time.Time
+}
 
-	if len(s.currentBroadcastAudioCache) == 0 {
+func newFramePacer(interval time.Duration, log *logger.Logger) *framePacer {
+	return &framePacer{log: log, interval: interval, scale: 1}
+}
+
+// ready reports whether a frame should be sent now given the stream's queue
+// length, a backed up queue drops the frame and scales the next ones down.  A
+// congested stream, one whose link is full, keeps getting frames but scaled down.
+func (p *framePacer) ready(backlog int, congested bool) bool {
+	now := time.Now()
+	// a frame is due an interval after the last one was, so a send loop woken
+	// a little late each interval still keeps to the frame rate
+	due := p.lastSent.Add(p.interval)
+	if now.Before(due) {
 		return false
 	}
 
-	if time.Now().After(s.lastBroadcastImageReceived.Add(200*time.Millisecond)) {
-		s.hasIncomingBroadcastImage = false
+	switch {
+	case backlog >= queueBackedUp:
+		if p.scale < maxFrameScale && now.Sub(p.lastScaled) > scaleDownHold {
+			p.scale *= 2
+			p.lastScaled = now
+			p.log.Warnf("stream backed up, scaling video down to 1/%v", p.scale)
+		}
 		return false
+	case congested:
+		if p.scale < maxFrameScale && now.Sub(p.lastScaled) > scaleDownHold {
+			p.scale *= 2
+			p.lastScaled = now
+			p.log.Warnf("stream congested, scaling video down to 1/%v", p.scale)
+		}
+	case backlog == 0 && p.scale > 1 && now.Sub(p.lastScaled) > scaleUpHold:
+		p.scale /= 2
+		p.lastScaled = now
+		p.log.Infof("stream caught up, scaling video up to 1/%v", p.scale)
 	}
 
+	if now.Sub(due) < p.interval {
+		p.lastSent = due
+	} else {
+		p.lastSent = now
+	}
 	return true
 }
 
+// apply scales image down to the pacer's current scale.  It returns nil for
+// raw pixels that do not fill the image's size, which are dropped.
+func (p *framePacer) apply(image *proto.Image) *proto.Image {
+	// tiles are JPEG, which the server does not decode, so they are only paced
+	if image.TileSize > 0 || len(image.Bytes) == 0 {
+		return image
+	}
+	channels, err := video.CheckFrame(image.Bytes, int(image.Width), int(image.Height))
+	if err != nil {
+		p.log.Debugf("dropped frame: %v", err)
+		return nil
+	}
+	if p.scale == 1 {
+		return image
+	}
+
+	bytes, width, height := video.Downscale(image.Bytes, int(image.Width), int(image.Height), channels, p.scale)
+	return &proto.Image{
+		Width:  int32(width),
+		Height: int32(height),
+		Type:   image.Type,
+		Bytes:  bytes,
+	}
+}
+
 func (s *intercomServer) Connect(stream proto.Intercom_ConnectServer) error {
-	log.Println("new stream connection established")
-	ctx := stream.Context()
+	id := atomic.AddInt64(&s.nextStreamID, 1)
+	streamLog := s.log.With("stream", id)
+	streamLog.Infof("new stream connection established")
+	// ctx is cancelled by the admin service to kick the station
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var streamRosterVersion int
+	var streamImageSeq int
+	// joinRoom is the room the stream's station joins unless its first message names another
+	joinRoom := roomFromContext(ctx)
+	if joinRoom == "" {
+		joinRoom = defaultRoom
+	}
+	var lastEstimate time.Time
+	var videoBudget int
+	pacer := newFramePacer(s.getFrameInterval(), streamLog)
+	// keyframeRequests has the send loop start this stream's video over from every tile
+	keyframeRequests := make(chan struct{}, 1)
 
-	var streamLastImageSent time.Time
+	// messages are queued for their own goroutine to send, so a slow stream backs up
+	// its own queues, which the pacer watches, rather than holding up the send loop
+	sub := newSubscriber(&s.metrics, streamLog)
+	sub.id = id
+	sub.connected = time.Now()
+	sub.cancel = cancel
+	if p, ok := peer.FromContext(ctx); ok {
+		sub.peer = p.Addr.String()
+	}
+	go sub.write(ctx, stream)
+
+	s.addStream(sub)
+	defer s.removeStream(sub)
+
+	s.metrics.streamOpened(1)
+	defer s.metrics.streamOpened(-1)
 
 	go func() {
+		// streamRoom is the room the stream's station was in as of the last wake
+		var streamRoom *room
+		defer func() {
+			if streamRoom != nil {
+				streamRoom.hinter.remove(sub)
+			}
+		}()
+
 		// SEND LOOP
+		// wakes once a frame interval, or when a keyframe is asked for
+		ticker := time.NewTicker(pacer.interval)
+		defer func() { ticker.Stop() }()
 		for {
-			// exit if context is done
-			// or continue
 			select {
 			case <-ctx.Done():
-				fmt.Println("outgoing stream closed: " + ctx.Err().Error())
+				streamLog.Infof("outgoing stream closed: %v", ctx.Err())
 				return
-			default:
+			case <-keyframeRequests:
+				streamImageSeq = 0
+			case <-ticker.C:
 			}
 
-			if !s.isCurrentlyBroadcasting() {
-				continue
+			if r := s.roomOf(sub); r != streamRoom {
+				if streamRoom != nil {
+					streamRoom.hinter.remove(sub)
+				}
+				// a station that moved starts the new room's video from a keyframe
+				streamRoom = r
+				streamImageSeq = 0
+			}
+			roomName := joinRoom
+			if streamRoom != nil {
+				roomName = streamRoom.name
 			}
 
-			broadcast := proto.Broadcast{}
+			roster, version := s.rosterSince(streamRosterVersion, roomName)
+			if roster != nil {
+				streamRosterVersion = version
+				sub.send(ctx, &proto.Broadcast{
+					BroadcastType: &proto.Broadcast_Roster{Roster: roster},
+				})
+			}
 
-			if time.Now().After(streamLastImageSent.Add(1 / 30 * time.Millisecond)) {
-				broadcast.BroadcastType = &proto.Broadcast_Image{
-					Image: &s.currentBroadcastImage,
+			if time.Since(lastEstimate) >= estimateWindow {
+				lastEstimate = time.Now()
+				if interval := s.getFrameInterval(); interval != pacer.interval {
+					pacer.interval = interval
+					ticker.Stop()
+					ticker = time.NewTicker(interval)
 				}
-
-				s.imgMutex.Lock()
-				if err := stream.Send(&broadcast); err != nil {
-					fmt.Printf("send error %v", err)
+				videoBudget = sub.estimator.videoBudget()
+				if streamRoom != nil {
+					if hint := streamRoom.hinter.update(sub, videoBudget); hint != nil {
+						s.sendHint(streamRoom, hint)
+					}
 				}
-				s.imgMutex.Unlock()
 			}
 
-			if len(s.currentBroadcastAudioCache) > 0 {
-				s.audioMutex.Lock()
-
-				sendAudio := s.currentBroadcastAudioCache[0]
-				s.currentBroadcastAudioCache = s.currentBroadcastAudioCache[1:]
-
-				broadcast.BroadcastType = &proto.Broadcast_Audio{
-					Audio: &sendAudio,
-				}
-
-				if err := stream.Send(&broadcast); err != nil {
-					fmt.Printf("send error %v", err)
+			// audio and video are each relayed on their own, for a station with its camera off or one sending on motion
+			if streamRoom != nil {
+				image, seq := s.imageSince(streamRoom, streamImageSeq)
+				if image != nil && pacer.ready(sub.backlog(), videoBudget > 0) {
+					// video is dropped before audio, frames that don't fit the queue are skipped
+					// and their tiles go out with the next one
+					if scaled := pacer.apply(image); scaled == nil {
+						streamImageSeq = seq
+						s.metrics.droppedFrame()
+					} else if sub.sendVideo(&proto.Broadcast{
+						BroadcastType: &proto.Broadcast_Image{Image: scaled},
+					}) {
+						streamImageSeq = seq
+					} else {
+						s.metrics.droppedFrame()
+					}
 				}
-				s.audioMutex.Unlock()
 			}
 		}
 	}()
 
 	// RECEIVE LOOP
 	go func() {
+		var stationName string
+		// logRoom is the room in the log's fields
+		var logRoom string
+		var timer broadcastTimer
+		log := streamLog
+		defer func() {
+			timer.end(&s.metrics)
+			if stationName != "" {
+				s.removeSubscriber(stationName, sub)
+			}
+		}()
+
 		for {
 			// exit if context is done
 			// or continue
 			select {
 			case <-ctx.Done():
-				fmt.Println("incoming stream closed: " + ctx.Err().Error())
+				log.Infof("incoming stream closed: %v", ctx.Err())
 				return
 			default:
 			}
@@ -108,58 +606,194 @@ func (s *intercomServer) Connect(stream proto.Intercom_ConnectServer) error {
 			broadcast, err := stream.Recv()
 			if err == io.EOF {
 				// return will close stream from server side
-				fmt.Printf("connection closed: %v\n", err)
+				log.Infof("connection closed: %v", err)
 				break
 			}
 			if err != nil {
-				fmt.Printf("receive error %v\n", err)
+				log.Errorf("receive error: %v", err)
 				break
 			}
+			size := protobuf.Size(broadcast)
+			s.metrics.receivedMessage(broadcast, size)
+			if log.Enabled(logger.Debug) {
+				log.With("type", messageType(broadcast)).Debugf("received %v bytes", size)
+			}
+
+			if broadcast.Name != "" && broadcast.Name != stationName {
+				if stationName != "" {
+					s.removeSubscriber(stationName, sub)
+				}
+				stationName = broadcast.Name
+				logRoom = broadcast.Room
+				if logRoom == "" {
+					logRoom = joinRoom
+				}
+				log = streamLog.With("station", stationName).With("room", logRoom)
+				log.Infof("station joined")
+				if s.addSubscriber(stationName, logRoom, sub) {
+					log.Warnf("took the name over from another stream")
+				}
+			} else if broadcast.Room != "" && stationName != "" && broadcast.Room != logRoom {
+				s.moveStation(stationName, broadcast.Room)
+			}
+
+			// the admin service moves stations too, the log follows the station's room
+			r := s.roomOf(sub)
+			if r != nil && r.name != logRoom {
+				logRoom = r.name
+				log = streamLog.With("station", stationName).With("room", logRoom)
+				log.Infof("station moved")
+			}
+
+			status := broadcast.GetStatus()
+			if status != nil {
+				s.updateStation(stationName, status)
+				continue
+			}
+
+			event := broadcast.GetEvent()
+			if event != nil {
+				log.With("type", "event").Infof("%v event", event.Type)
+				s.relay(sub, broadcast)
+				continue
+			}
+
+			if broadcast.GetKeyframeRequest() != nil {
+				select {
+				case keyframeRequests <- struct{}{}:
+				default:
+				}
+				continue
+			}
 
 			image := broadcast.GetImage()
 			if image != nil {
-				s.imgMutex.Lock()
-				s.currentBroadcastImage = *image
-				s.imgMutex.Unlock()
-
-				s.hasIncomingBroadcastImage = true
-				s.lastBroadcastImageReceived = time.Now()
+				timer.media(&s.metrics)
+				// video from a stream that has not named itself has no room to go to
+				if r != nil {
+					if err := s.receiveImage(r, stationName, image); err != nil {
+						log.Debugf("dropped image: %v", err)
+					}
+				}
 				continue
 			}
 
 			audio := broadcast.GetAudio()
 			if audio != nil {
-				s.audioMutex.Lock()
-				s.currentBroadcastAudioCache = append(s.currentBroadcastAudioCache, *audio)
-				s.audioMutex.Unlock()
+				if s.isForceMuted(stationName) {
+					continue
+				}
+				s.recorder.record(stationName, audio)
+				timer.media(&s.metrics)
+				s.updateLevel(stationName, audio.Level, audio.Peak)
+
+				// every other stream gets its own copy, queued ahead of video
+				s.relay(sub, broadcast)
 				continue
 			}
 		}
 	}()
 
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("stream closed: " + ctx.Err().Error())
-			return nil
-		default:
-		}
+	<-ctx.Done()
+	streamLog.Infof("stream closed: %v", ctx.Err())
+	if reason := sub.kickReason(); reason != "" {
+		return status.Error(codes.Aborted, reason)
 	}
+	return nil
 }
 
 func main() {
+	addr := flag.String("addr", ":6000", "address to listen on, or to check with -healthcheck")
+	fps := flag.Int("fps", 30, "most video frames per second sent to each station")
+	metricsAddr := flag.String("metrics", ":6002", "address /metrics for Prometheus, and the dashboard with -admin-token, are served on, empty to disable")
+	healthcheck := flag.Bool("healthcheck", false, "check whether the server on -addr is serving and exit, for container probes")
+	logLevel := flag.String("log-level", "info", "least important messages logged: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	recordDir := flag.String("record-dir", "recordings", "directory the admin service's recordings are written to")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate file to serve TLS with, plaintext if empty")
+	tlsKey := flag.String("tls-key", "", "PEM key file for -tls-cert")
+	gatewayAddr := flag.String("gateway", ":6003", "address the browser page and its websocket are served on, over TLS with -tls-cert, empty to disable")
+	grpcWeb := flag.Bool("grpc-web", false, "also serve gRPC-Web on -addr, for browsers and other clients without HTTP/2 trailers")
+	adminToken := flag.String("admin-token", os.Getenv("INTERCOM_ADMIN_TOKEN"), "token the IntercomAdmin service requires, empty to disable it (default $INTERCOM_ADMIN_TOKEN)")
+	flag.Parse()
+
+	log, err := logger.Parse(*logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *healthcheck {
+		os.Exit(runHealthcheck(*addr, *tlsCert != "", log))
+	}
+	if *fps < 1 {
+		fmt.Fprintln(os.Stderr, "-fps must be at least 1")
+		os.Exit(2)
+	}
+
 	// create listener
-	l, err := net.Listen("tcp", ":6000")
+	l, err := net.Listen("tcp", *addr)
 	if err != nil {
 		panic(err)
 	}
 
-	grpcServer := grpc.NewServer()
-	proto.RegisterIntercomServer(grpcServer, &intercomServer{})
+	server := &intercomServer{
+		log:           log,
+		frameInterval: time.Second / time.Duration(*fps),
+		started:       time.Now(),
+		recorder:      recorder{dir: *recordDir, log: log},
+	}
+	if *gatewayAddr != "" {
+		go server.serveGateway(*gatewayAddr, *tlsCert, *tlsKey)
+	}
+	if *metricsAddr != "" {
+		go server.serveMetrics(*metricsAddr, *adminToken)
+	}
+
+	var options []grpc.ServerOption
+	if *tlsCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+		if err != nil {
+			panic(err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	admin := &adminServer{server: server, token: *adminToken}
+	if admin.token != "" {
+		options = append(options, grpc.UnaryInterceptor(admin.authorize))
+	}
 
-	fmt.Println("Listening on tcp://localhost:6000")
+	grpcServer := grpc.NewServer(options...)
+	proto.RegisterIntercomServer(grpcServer, server)
+	if admin.token != "" {
+		proto.RegisterIntercomAdminServer(grpcServer, admin)
+	}
+	healthServer := registerHealth(grpcServer)
 
-	if err := grpcServer.Serve(l); err != nil {
+	// with gRPC-Web, an HTTP server takes the listener and hands gRPC calls to grpcServer
+	var httpServer *http.Server
+	if *grpcWeb {
+		httpServer = &http.Server{Handler: grpcWebHandler(grpcServer)}
+	}
+	go drainOnSignal(grpcServer, httpServer, healthServer, log)
+
+	markServing(healthServer)
+	log.Infof("Listening on tcp://%v", l.Addr())
+
+	switch {
+	case httpServer == nil:
+		err = grpcServer.Serve(l)
+	case *tlsCert != "":
+		log.Infof("serving gRPC-Web over TLS")
+		err = httpServer.ServeTLS(l, *tlsCert, *tlsKey)
+	default:
+		log.Infof("serving gRPC-Web")
+		err = httpServer.Serve(l)
+	}
+	if err != nil && err != http.ErrServerClosed {
 		panic(err)
 	}
+	if server.recorder.current() != nil {
+		server.recorder.stop()
+	}
 }
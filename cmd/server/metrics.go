@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/proto"
+)
+
+// broadcastGap is how long a station has to stop sending audio and video for its broadcast to count as over
+const broadcastGap = time.Second
+
+var (
+	sendDurationBuckets      = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+	broadcastDurationBuckets = []float64{1, 2, 5, 10, 30, 60, 120, 300, 600}
+
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// serverMetrics counts what the server relays, written out in the Prometheus
+// text format by serveMetrics
+type serverMetrics struct {
+	mu sync.Mutex
+
+	streams       int
+	received      map[string]float64
+	receivedBytes map[string]float64
+	sent          map[string]float64
+	sentBytes     map[string]float64
+	droppedFrames float64
+	sendErrors    float64
+
+	sendDuration      histogram
+	broadcastDuration histogram
+}
+
+type histogram struct {
+	counts []float64
+	sum    float64
+	count  float64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]float64, len(buckets))
+	}
+	for i, bound := range buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// messageType names a message's type for the type label
+func messageType(broadcast *proto.Broadcast) string {
+	switch broadcast.BroadcastType.(type) {
+	case *proto.Broadcast_Image:
+		return "image"
+	case *proto.Broadcast_Audio:
+		return "audio"
+	case *proto.Broadcast_Status:
+		return "status"
+	case *proto.Broadcast_Roster:
+		return "roster"
+	case *proto.Broadcast_Bitrate:
+		return "bitrate"
+	case *proto.Broadcast_KeyframeRequest:
+		return "keyframe_request"
+	case *proto.Broadcast_Event:
+		return "event"
+	}
+	return "unknown"
+}
+
+func addTo(m *map[string]float64, key string, v float64) {
+	if *m == nil {
+		*m = map[string]float64{}
+	}
+	(*m)[key] += v
+}
+
+func (m *serverMetrics) streamOpened(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streams += delta
+}
+
+func (m *serverMetrics) receivedMessage(broadcast *proto.Broadcast, bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kind := messageType(broadcast)
+	addTo(&m.received, kind, 1)
+	addTo(&m.receivedBytes, kind, float64(bytes))
+}
+
+func (m *serverMetrics) sentMessage(broadcast *proto.Broadcast, bytes int, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kind := messageType(broadcast)
+	addTo(&m.sent, kind, 1)
+	addTo(&m.sentBytes, kind, float64(bytes))
+	m.sendDuration.observe(sendDurationBuckets, took.Seconds())
+}
+
+func (m *serverMetrics) sendError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendErrors++
+}
+
+func (m *serverMetrics) droppedFrame() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.droppedFrames++
+}
+
+func (m *serverMetrics) broadcastEnded(took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.broadcastDuration.observe(broadcastDurationBuckets, took.Seconds())
+}
+
+// broadcastTimer times a station's broadcasts, from its first audio or video
+// until it stops sending for broadcastGap
+type broadcastTimer struct {
+	started  time.Time
+	lastSeen time.Time
+}
+
+// media records audio or video arriving, ending the last broadcast if it had stopped
+func (t *broadcastTimer) media(m *serverMetrics) {
+	now := time.Now()
+	if !t.started.IsZero() && now.Sub(t.lastSeen) > broadcastGap {
+		t.end(m)
+	}
+	if t.started.IsZero() {
+		t.started = now
+	}
+	t.lastSeen = now
+}
+
+// end records the broadcast in progress, if there is one
+func (t *broadcastTimer) end(m *serverMetrics) {
+	if t.started.IsZero() {
+		return
+	}
+	m.broadcastEnded(t.lastSeen.Sub(t.started))
+	t.started = time.Time{}
+}
+
+// serveMetrics serves /metrics on addr for Prometheus to scrape, and the
+// dashboard if there is an admin token to protect it with
+func (s *intercomServer) serveMetrics(addr, adminToken string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	if adminToken != "" {
+		s.registerDashboard(mux, adminToken)
+		s.log.Infof("dashboard on http://%v/", addr)
+	}
+
+	s.log.Infof("metrics listening on http://%v/metrics", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		s.log.Errorf("metrics error: %v", err)
+	}
+}
+
+// stats returns the counters for the admin service
+func (m *serverMetrics) stats() *proto.Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := &proto.Stats{
+		Streams:       int32(m.streams),
+		DroppedFrames: int64(m.droppedFrames),
+		SendErrors:    int64(m.sendErrors),
+	}
+
+	kinds := map[string]bool{}
+	for _, counts := range []map[string]float64{m.received, m.sent} {
+		for kind := range counts {
+			kinds[kind] = true
+		}
+	}
+	for kind := range kinds {
+		stats.Messages = append(stats.Messages, &proto.MessageStats{
+			Type:          kind,
+			Received:      int64(m.received[kind]),
+			ReceivedBytes: int64(m.receivedBytes[kind]),
+			Sent:          int64(m.sent[kind]),
+			SentBytes:     int64(m.sentBytes[kind]),
+		})
+	}
+	sort.Slice(stats.Messages, func(i, j int) bool {
+		return stats.Messages[i].Type < stats.Messages[j].Type
+	})
+	return stats
+}
+
+func (s *intercomServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.rosterMutex.Lock()
+	participants := len(s.stations)
+	rooms := map[string]float64{}
+	for _, station := range s.stations {
+		if station.Room != "" {
+			rooms[station.Room]++
+		}
+	}
+	s.rosterMutex.Unlock()
+
+	s.subscribersMutex.Lock()
+	depths := map[string]float64{}
+	for _, r := range s.rooms {
+		for name, sub := range r.subscribers {
+			depths[name] = float64(sub.backlog())
+		}
+	}
+	s.subscribersMutex.Unlock()
+
+	m := &s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetric(w, "intercom_connected_streams", "gauge", "Streams currently connected.", float64(m.streams))
+	writeMetric(w, "intercom_participants", "gauge", "Stations that have named themselves on a connected stream.", float64(participants))
+	writeMetricVec(w, "intercom_room_participants", "gauge", "Stations in each room.", "room", rooms)
+	writeMetricVec(w, "intercom_messages_received_total", "counter", "Messages received from stations.", "type", m.received)
+	writeMetricVec(w, "intercom_bytes_received_total", "counter", "Bytes of messages received from stations.", "type", m.receivedBytes)
+	writeMetricVec(w, "intercom_messages_sent_total", "counter", "Messages sent to stations.", "type", m.sent)
+	writeMetricVec(w, "intercom_bytes_sent_total", "counter", "Bytes of messages sent to stations.", "type", m.sentBytes)
+	writeMetricVec(w, "intercom_queue_depth", "gauge", "Messages waiting to be sent to each station.", "station", depths)
+	writeMetric(w, "intercom_dropped_frames_total", "counter", "Video frames dropped for stations that could not keep up.", m.droppedFrames)
+	writeMetric(w, "intercom_send_errors_total", "counter", "Messages that failed to send.", m.sendErrors)
+	writeHistogram(w, "intercom_send_duration_seconds", "Time taken sending each message to a station.", sendDurationBuckets, m.sendDuration)
+	writeHistogram(w, "intercom_broadcast_duration_seconds", "How long stations broadcast audio or video for.", broadcastDurationBuckets, m.broadcastDuration)
+}
+
+func writeMetric(w io.Writer, name, kind, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, v)
+}
+
+func writeMetricVec(w io.Writer, name, kind, help, label string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %v\n", name, label, labelEscaper.Replace(key), values[key])
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, buckets []float64, h histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for i, bound := range buckets {
+		count := 0.0
+		if h.counts != nil {
+			count = h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"%v\"} %v\n", name, bound, count)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %v\n%s_sum %v\n%s_count %v\n", name, h.count, name, h.sum, name, h.count)
+}
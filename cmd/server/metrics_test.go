@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	log := logger.New(ioutil.Discard, logger.Error, logger.Text)
+	server := &intercomServer{log: log}
+
+	server.metrics.streamOpened(1)
+	server.metrics.streamOpened(1)
+	server.updateStation("kitchen", nil)
+	server.updateStation(`say "hi"`, nil)
+	for name, sub := range map[string]*subscriber{
+		"kitchen":  newSubscriber(&server.metrics, log),
+		`say "hi"`: newSubscriber(&server.metrics, log),
+	} {
+		server.addSubscriber(name, "upstairs", sub)
+	}
+
+	audio := &proto.Broadcast{BroadcastType: &proto.Broadcast_Audio{Audio: &proto.Audio{}}}
+	image := &proto.Broadcast{BroadcastType: &proto.Broadcast_Image{Image: &proto.Image{}}}
+	server.metrics.receivedMessage(audio, 100)
+	server.metrics.receivedMessage(audio, 50)
+	server.metrics.receivedMessage(image, 1000)
+	server.metrics.sentMessage(audio, 100, 2*time.Millisecond)
+	server.metrics.droppedFrame()
+	server.metrics.broadcastEnded(3 * time.Second)
+
+	w := httptest.NewRecorder()
+	server.handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Errorf("content type %q", got)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE intercom_connected_streams gauge\nintercom_connected_streams 2\n",
+		"intercom_participants 2\n",
+		`intercom_room_participants{room="upstairs"} 2` + "\n",
+		"# TYPE intercom_messages_received_total counter\n",
+		`intercom_messages_received_total{type="audio"} 2` + "\n",
+		`intercom_messages_received_total{type="image"} 1` + "\n",
+		`intercom_bytes_received_total{type="audio"} 150` + "\n",
+		`intercom_messages_sent_total{type="audio"} 1` + "\n",
+		`intercom_queue_depth{station="kitchen"} 0` + "\n",
+		`intercom_queue_depth{station="say \"hi\""} 0` + "\n",
+		"intercom_dropped_frames_total 1\n",
+		// buckets count every observation at or under their bound
+		`intercom_send_duration_seconds_bucket{le="0.001"} 0` + "\n",
+		`intercom_send_duration_seconds_bucket{le="0.005"} 1` + "\n",
+		`intercom_send_duration_seconds_bucket{le="1"} 1` + "\n",
+		`intercom_send_duration_seconds_bucket{le="+Inf"} 1` + "\n",
+		"intercom_send_duration_seconds_count 1\n",
+		`intercom_broadcast_duration_seconds_bucket{le="2"} 0` + "\n",
+		`intercom_broadcast_duration_seconds_bucket{le="5"} 1` + "\n",
+		"intercom_broadcast_duration_seconds_sum 3\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+)
+
+const (
+	// recordSampleRate and recordChannels are the format recordings are written
+	// in, every station's audio is resampled and mixed down to it
+	recordSampleRate = 44100
+	recordChannels   = 1
+)
+
+var (
+	errRecording    = errors.New("already recording")
+	errNotRecording = errors.New("not recording")
+)
+
+// recorder writes the audio relayed by the server to a WAV file, one station
+// after another as it arrives
+type recorder struct {
+	mu  sync.Mutex
+	dir string
+	log *logger.Logger
+
+	file    *os.File
+	wav     *audio.WAVWriter
+	path    string
+	started time.Time
+	// resamplers converts each station's audio to recordSampleRate
+	resamplers map[string]*audio.Resampler
+}
+
+// start creates name in the recording directory and records to it, an empty
+// name is made from the time
+func (r *recorder) start(name string) (*proto.Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wav != nil {
+		return nil, errRecording
+	}
+
+	now := time.Now()
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) {
+		name = now.Format("intercom-20060102-150405")
+	}
+	if !strings.HasSuffix(name, ".wav") {
+		name += ".wav"
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dir, name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	wav, err := audio.NewWAVWriter(file, recordSampleRate, recordChannels)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+
+	r.file, r.wav, r.path, r.started = file, wav, path, now
+	r.resamplers = map[string]*audio.Resampler{}
+	r.log.With("path", path).Infof("recording started")
+	return r.recording(), nil
+}
+
+// stop finishes the recording and returns it
+func (r *recorder) stop() (*proto.Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wav == nil {
+		return nil, errNotRecording
+	}
+	recording := r.recording()
+	err := r.close()
+	r.log.With("path", recording.Path).Infof("recording stopped after %.0fs", recording.Seconds)
+	return recording, err
+}
+
+// current returns the recording in progress, nil if there is none
+func (r *recorder) current() *proto.Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wav == nil {
+		return nil
+	}
+	return r.recording()
+}
+
+// record appends a station's audio if recording
+func (r *recorder) record(station string, a *proto.Audio) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wav == nil || !audio.ValidSampleRate(int(a.SampleRate)) {
+		return
+	}
+
+	resampler, ok := r.resamplers[station]
+	if !ok || resampler.InRate() != int(a.SampleRate) {
+		var err error
+		resampler, err = audio.NewResampler(int(a.SampleRate), recordSampleRate, recordChannels)
+		if err != nil {
+			return
+		}
+		r.resamplers[station] = resampler
+	}
+
+	samples := audio.Remix(a.Samples, int(a.Channels), recordChannels)
+
+	if err := r.wav.Write(resampler.Process(samples)); err != nil {
+		r.log.With("path", r.path).Errorf("recording stopped, write error: %v", err)
+		r.close()
+	}
+}
+
+// recording describes the recording in progress, the caller must hold mu
+func (r *recorder) recording() *proto.Recording {
+	return &proto.Recording{
+		Path:      r.path,
+		StartedAt: r.started.Unix(),
+		Seconds:   float32(r.wav.Frames()) / recordSampleRate,
+	}
+}
+
+// close finishes the file, the caller must hold mu
+func (r *recorder) close() error {
+	err := r.wav.Close()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file, r.wav, r.resamplers = nil, nil, nil
+	return err
+}
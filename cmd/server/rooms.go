@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/3xcellent/intercom/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// defaultRoom is the room stations join unless they name another
+	defaultRoom = "default"
+	// roomMetadata is the Connect metadata key a stream names the room its station joins with
+	roomMetadata = "room"
+	// videoExpiry is how long after its last frame a room's video stops being relayed
+	videoExpiry = 200 * time.Millisecond
+)
+
+// room is a group of stations that hear and see each other, and no one else
+type room struct {
+	name string
+	// subscribers holds the outgoing queues of the room's stations by name,
+	// guarded by the server's subscribersMutex
+	subscribers map[string]*subscriber
+	// hinter tells the station sending video the most the room's slowest stream can take
+	hinter bitrateHinter
+	// video is guarded by the server's imgMutex
+	video roomVideo
+}
+
+// roomVideo is the video being relayed in a room
+type roomVideo struct {
+	lastReceived time.Time
+	image        proto.Image
+	// seq counts received images so each stream sends every frame at most once
+	seq int
+	// from is the station sending video, bitrate hints go to it
+	from string
+	// tiles rebuilds tiled video so each stream can be sent the tiles it is missing
+	tiles tileCache
+	// lastKeyframeRequest is when the station sending video was last asked for a keyframe
+	lastKeyframeRequest time.Time
+}
+
+// live reports whether frames are still arriving
+func (v *roomVideo) live() bool {
+	return !v.lastReceived.IsZero() && time.Since(v.lastReceived) <= videoExpiry
+}
+
+// roomFromContext is the room a stream's metadata names, empty if none
+func roomFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(roomMetadata); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// addSubscriber adds sub as the station name in the room named roomName,
+// with a roster entry.  A station that connects again under a name in use
+// takes it over, the stream that had it is kicked, and true is returned.
+func (s *intercomServer) addSubscriber(name, roomName string, sub *subscriber) bool {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	old := s.subscriberNamed(name)
+	if old != nil && old != sub {
+		// the old stream no longer holds the name, so it leaves the roster
+		// entry to sub and relays nothing more once it ends
+		s.leaveRoom(name, old)
+		old.station = ""
+		old.room = nil
+		old.kick("another stream joined as " + name)
+	}
+	s.joinRoom(name, roomName, sub)
+	sub.station = name
+	// the roster entry is made with subscribersMutex held, so a stream
+	// leaving the name at the same time cannot remove it
+	s.updateStation(name, nil)
+	s.setStationRoom(name, roomName)
+	return old != nil && old != sub
+}
+
+// removeSubscriber takes the station name off the server as sub leaves it,
+// its queues, roster entry and thumbnail, unless another stream has taken
+// the name over since
+func (s *intercomServer) removeSubscriber(name string, sub *subscriber) {
+	s.subscribersMutex.Lock()
+	held := sub.station == name
+	s.leaveRoom(name, sub)
+	if held {
+		sub.station = ""
+		sub.room = nil
+		s.removeStation(name)
+	}
+	s.subscribersMutex.Unlock()
+
+	if held {
+		s.removeThumbnail(name)
+	}
+}
+
+// moveStation moves the station name to the room named roomName, reporting
+// false if no such station is connected
+func (s *intercomServer) moveStation(name, roomName string) bool {
+	s.subscribersMutex.Lock()
+	sub := s.subscriberNamed(name)
+	if sub == nil {
+		s.subscribersMutex.Unlock()
+		return false
+	}
+	if sub.room.name != roomName {
+		s.leaveRoom(name, sub)
+		s.joinRoom(name, roomName, sub)
+	}
+	s.subscribersMutex.Unlock()
+
+	s.setStationRoom(name, roomName)
+	return true
+}
+
+// joinRoom adds sub to a room, creating it if it is the first there.  The
+// caller must hold subscribersMutex.
+func (s *intercomServer) joinRoom(name, roomName string, sub *subscriber) {
+	if s.rooms == nil {
+		s.rooms = map[string]*room{}
+	}
+	r, ok := s.rooms[roomName]
+	if !ok {
+		r = &room{name: roomName, subscribers: map[string]*subscriber{}}
+		s.rooms[roomName] = r
+	}
+	r.subscribers[name] = sub
+	sub.room = r
+}
+
+// leaveRoom takes sub out of its room, dropping the room once it is empty.
+// The caller must hold subscribersMutex.
+func (s *intercomServer) leaveRoom(name string, sub *subscriber) {
+	r := sub.room
+	if r == nil || r.subscribers[name] != sub {
+		return
+	}
+	delete(r.subscribers, name)
+	if len(r.subscribers) == 0 && s.rooms[r.name] == r {
+		delete(s.rooms, r.name)
+	}
+}
+
+// subscriberNamed finds the station name in any room, nil if it is not
+// connected.  The caller must hold subscribersMutex.
+func (s *intercomServer) subscriberNamed(name string) *subscriber {
+	for _, r := range s.rooms {
+		if sub, ok := r.subscribers[name]; ok {
+			return sub
+		}
+	}
+	return nil
+}
+
+// roomOf is the room sub's station is in, nil until it names itself
+func (s *intercomServer) roomOf(sub *subscriber) *room {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+	return sub.room
+}
+
+// setStationRoom records the room a station is in, for the rosters
+func (s *intercomServer) setStationRoom(name, roomName string) {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+
+	station, ok := s.stations[name]
+	if !ok || station.Room == roomName {
+		return
+	}
+	station.Room = roomName
+	s.rosterVersion++
+}
+
+// broadcasting returns the station whose video is being relayed in each room that has any
+func (s *intercomServer) broadcasting() map[string]string {
+	s.subscribersMutex.Lock()
+	rooms := make([]*room, 0, len(s.rooms))
+	for _, r := range s.rooms {
+		rooms = append(rooms, r)
+	}
+	s.subscribersMutex.Unlock()
+
+	s.imgMutex.Lock()
+	defer s.imgMutex.Unlock()
+	from := map[string]string{}
+	for _, r := range rooms {
+		if r.video.live() {
+			from[r.name] = r.video.from
+		}
+	}
+	return from
+}
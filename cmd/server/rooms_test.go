@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/3xcellent/intercom/proto"
+)
+
+// roomsOf lists the stations in each room as ListRooms has them
+func roomsOf(ctx context.Context, t *testing.T, admin proto.IntercomAdminClient) map[string][]string {
+	t.Helper()
+	list, err := admin.ListRooms(ctx, &proto.ListRoomsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rooms := map[string][]string{}
+	for _, room := range list.Rooms {
+		rooms[room.Name] = room.Stations
+	}
+	return rooms
+}
+
+// waitForRooms fails unless ListRooms comes to have want
+func waitForRooms(ctx context.Context, t *testing.T, admin proto.IntercomAdminClient, want map[string][]string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := roomsOf(ctx, t, admin)
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rooms %v, want %v", got, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// talk sends a sample of audio from station
+func talk(t *testing.T, station proto.Intercom_ConnectClient, name string, sample int32) {
+	t.Helper()
+	err := station.Send(&proto.Broadcast{Name: name, BroadcastType: &proto.Broadcast_Audio{Audio: &proto.Audio{
+		SampleRate: 8000,
+		Length:     1,
+		Samples:    []int32{sample},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRooms(t *testing.T) {
+	server, grpcServer := newTestServer(10)
+	lis := bufconn.Listen(32 << 10)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "bufconn", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	admin := proto.NewIntercomAdminClient(conn)
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+testAdminToken)
+
+	// kitchen and bedroom name their room in the stream's metadata, garage
+	// joins the default room and porch moves itself once joined
+	upstairs := metadata.AppendToOutgoingContext(ctx, roomMetadata, "upstairs")
+	kitchen, _ := connectStation(upstairs, t, lis, "kitchen", 0)
+	_, bedroomGot := connectStation(upstairs, t, lis, "bedroom", 0)
+	_, garageGot := connectStation(ctx, t, lis, "garage", 0)
+	porch, porchGot := connectStation(ctx, t, lis, "porch", 0)
+	err = porch.Send(&proto.Broadcast{Name: "porch", Room: "outside", BroadcastType: &proto.Broadcast_Status{Status: &proto.Status{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForRooms(adminCtx, t, admin, map[string][]string{
+		defaultRoom: {"garage"},
+		"outside":   {"porch"},
+		"upstairs":  {"bedroom", "kitchen"},
+	})
+
+	t.Run("audio stays in its room", func(t *testing.T) {
+		talk(t, kitchen, "kitchen", 1)
+		time.Sleep(200 * time.Millisecond)
+		if audio, _, _ := bedroomGot.counts(); audio != 1 {
+			t.Errorf("bedroom got %v audio messages, want the kitchen's", audio)
+		}
+		for name, got := range map[string]*received{"garage": garageGot, "porch": porchGot} {
+			if audio, _, _ := got.counts(); audio != 0 {
+				t.Errorf("%v got %v audio messages from another room", name, audio)
+			}
+		}
+	})
+
+	t.Run("admin moves a station", func(t *testing.T) {
+		if _, err := admin.MoveStation(adminCtx, &proto.MoveRequest{Station: "garage", Room: "upstairs"}); err != nil {
+			t.Fatal(err)
+		}
+		waitForRooms(adminCtx, t, admin, map[string][]string{
+			"outside":  {"porch"},
+			"upstairs": {"bedroom", "garage", "kitchen"},
+		})
+
+		talk(t, kitchen, "kitchen", 2)
+		time.Sleep(200 * time.Millisecond)
+		garageGot.mu.Lock()
+		defer garageGot.mu.Unlock()
+		if !reflect.DeepEqual(garageGot.audio, []int32{2}) {
+			t.Errorf("garage got audio %v once moved, want the kitchen's latest", garageGot.audio)
+		}
+		roster := garageGot.rosters[len(garageGot.rosters)-1]
+		var names []string
+		for _, station := range roster.Stations {
+			names = append(names, station.Name)
+		}
+		if roster.Room != "upstairs" || !reflect.DeepEqual(names, []string{"bedroom", "garage", "kitchen"}) {
+			t.Errorf("garage's roster is of room %q with %v", roster.Room, names)
+		}
+	})
+
+	t.Run("metrics count each room's stations", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+		for _, want := range []string{
+			`intercom_room_participants{room="outside"} 1`,
+			`intercom_room_participants{room="upstairs"} 3`,
+		} {
+			if !strings.Contains(w.Body.String(), want+"\n") {
+				t.Errorf("metrics missing %v", want)
+			}
+		}
+		if strings.Contains(w.Body.String(), `room="`+defaultRoom+`"`) {
+			t.Error("metrics count the emptied default room")
+		}
+	})
+
+	t.Run("moving needs a connected station and a room", func(t *testing.T) {
+		tests := []struct {
+			req  *proto.MoveRequest
+			code codes.Code
+		}{
+			{&proto.MoveRequest{Station: "attic", Room: "upstairs"}, codes.NotFound},
+			{&proto.MoveRequest{Station: "garage"}, codes.InvalidArgument},
+			{&proto.MoveRequest{Room: "upstairs"}, codes.InvalidArgument},
+		}
+		for _, test := range tests {
+			if _, err := admin.MoveStation(adminCtx, test.req); status.Code(err) != test.code {
+				t.Errorf("moving %q to %q: %v, want %v", test.req.Station, test.req.Room, err, test.code)
+			}
+		}
+	})
+}
+
+func TestNameTakeover(t *testing.T) {
+	server, grpcServer := newTestServer(10)
+	lis := bufconn.Listen(32 << 10)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upstairs := metadata.AppendToOutgoingContext(ctx, roomMetadata, "upstairs")
+	_, oldGot := connectStation(ctx, t, lis, "kitchen", 0)
+	waitForRoster(t, server, map[string]string{"kitchen": defaultRoom})
+
+	// the station reconnects to another room before its old stream is gone
+	connectStation(upstairs, t, lis, "kitchen", 0)
+	connectStation(upstairs, t, lis, "bedroom", 0)
+	waitForRoster(t, server, map[string]string{"kitchen": "upstairs", "bedroom": "upstairs"})
+
+	// the old stream is kicked, and its ending leaves the new one's station be
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		oldGot.mu.Lock()
+		err := oldGot.err
+		oldGot.mu.Unlock()
+		if err != nil {
+			if status.Code(err) != codes.Aborted {
+				t.Errorf("old stream ended with %v, want Aborted", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("old stream not kicked")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	want := map[string]string{"kitchen": "upstairs", "bedroom": "upstairs"}
+	if got := stationRooms(server); !reflect.DeepEqual(got, want) {
+		t.Errorf("stations %v once the old stream ended, want %v", got, want)
+	}
+}
+
+// stationRooms is the room of each station in the roster
+func stationRooms(s *intercomServer) map[string]string {
+	s.rosterMutex.Lock()
+	defer s.rosterMutex.Unlock()
+	rooms := map[string]string{}
+	for name, station := range s.stations {
+		rooms[name] = station.Room
+	}
+	return rooms
+}
+
+// waitForRoster fails unless the roster comes to have the stations and rooms of want
+func waitForRoster(t *testing.T, s *intercomServer, want map[string]string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := stationRooms(s)
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stations %v, want %v", got, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
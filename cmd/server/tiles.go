@@ -0,0 +1,55 @@
+package main
+
+import "github.com/3xcellent/intercom/proto"
+
+// tileCache holds the latest JPEG of every tile of the tiled video being
+// relayed, and the image sequence each tile last changed at.  Streams that
+// skip frames, or join part way through, are sent every tile that changed
+// since their last frame, so frames can be dropped without breaking the deltas.
+type tileCache struct {
+	from     string
+	header   proto.Image
+	tiles    map[[2]int32]*cachedTile
+	keyframe int
+}
+
+type cachedTile struct {
+	tile *proto.Tile
+	seq  int
+}
+
+// apply adds image, received from station at seq, to the cache.  It returns
+// false for a delta the cache has no keyframe for, so one has to be asked for.
+func (c *tileCache) apply(from string, image *proto.Image, seq int) bool {
+	if image.Keyframe {
+		c.from = from
+		c.header = proto.Image{Width: image.Width, Height: image.Height, Type: image.Type, TileSize: image.TileSize}
+		c.tiles = map[[2]int32]*cachedTile{}
+		c.keyframe = seq
+	} else if c.tiles == nil || from != c.from ||
+		image.Width != c.header.Width || image.Height != c.header.Height || image.TileSize != c.header.TileSize {
+		return false
+	}
+
+	for _, tile := range image.Tiles {
+		c.tiles[[2]int32{tile.X, tile.Y}] = &cachedTile{tile: tile, seq: seq}
+	}
+	return true
+}
+
+func (c *tileCache) reset() {
+	c.tiles = nil
+}
+
+// since builds the frame to send a stream that was last sent seq, a keyframe
+// if it has not had the latest one, otherwise only the tiles changed since
+func (c *tileCache) since(seq int) *proto.Image {
+	image := c.header
+	image.Keyframe = seq < c.keyframe
+	for _, cached := range c.tiles {
+		if image.Keyframe || cached.seq > seq {
+			image.Tiles = append(image.Tiles, cached.tile)
+		}
+	}
+	return &image
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+)
+
+const (
+	// defaultSampleRate is assumed for audio from senders that do not fill in proto.Audio.sampleRate
+	defaultSampleRate = 44100
+	// maxMixerBacklog bounds the audio waiting to be sent to the phone per
+	// station, 200 ms, older audio is dropped so the call doesn't lag
+	maxMixerBacklog = audio.G711SampleRate / 5
+)
+
+// bridge is a station on the intercom that talks through phone calls: what
+// the stations say is sent to the phone and what the phone says to every
+// station.  It has one call at a time.
+type bridge struct {
+	log  *logger.Logger
+	name string
+	ua   *userAgent
+	// ringURI is called when a doorbell station rings, nobody is if empty
+	ringURI string
+	// payload is the G.711 law offered first and chosen if offered
+	payload int
+	// mediaHost is the address audio is received on, given in SDP
+	mediaHost string
+
+	stream    proto.Intercom_ConnectClient
+	sendMutex sync.Mutex
+
+	mu   sync.Mutex
+	call *call
+}
+
+// send serializes writes to the server stream, which is not safe for concurrent Send calls,
+// and tags them with the bridge's name
+func (b *bridge) send(req *proto.Broadcast) {
+	req.Name = b.name
+
+	b.sendMutex.Lock()
+	defer b.sendMutex.Unlock()
+	if err := b.stream.Send(req); err != nil {
+		b.log.Errorf("send error: %v", err)
+	}
+}
+
+// sendStatus tells the stations the bridge is audio only, and muted unless
+// it is in a call
+func (b *bridge) sendStatus() {
+	b.mu.Lock()
+	inCall := b.call != nil && b.call.established
+	b.mu.Unlock()
+
+	b.send(&proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Status{Status: &proto.Status{MicMuted: !inCall, CameraOff: true}},
+	})
+}
+
+func (b *bridge) sendEvent(event *proto.Event) {
+	b.send(&proto.Broadcast{BroadcastType: &proto.Broadcast_Event{Event: event}})
+}
+
+// sendAudio sends what the phone said to the stations
+func (b *bridge) sendAudio(samples []int32) {
+	level, peak := audio.Levels(samples)
+	b.send(&proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Audio{
+			Audio: &proto.Audio{
+				SampleRate: audio.G711SampleRate,
+				Channels:   1,
+				Length:     int32(len(samples)),
+				Samples:    samples,
+				Level:      float32(level),
+				Peak:       float32(peak),
+			},
+		},
+	})
+}
+
+// receive handles what the server sends until the stream ends
+func (b *bridge) receive() error {
+	for {
+		resp, err := b.stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.GetAudio() != nil:
+			b.mu.Lock()
+			if c := b.call; c != nil && c.established {
+				c.mixer.add(resp.Name, resp.GetAudio())
+			}
+			b.mu.Unlock()
+
+		case resp.GetEvent() != nil:
+			b.handleEvent(resp.Name, resp.GetEvent())
+
+		case resp.GetStatus() != nil:
+			// the server only sends a status to mute this station from its admin service
+			if resp.GetStatus().MicMuted {
+				b.log.Warnf("muted by the server, the phone isn't heard")
+			}
+		}
+	}
+}
+
+// handleEvent calls the phone when a doorbell rings, and follows the call
+// from the doorbell's end
+func (b *bridge) handleEvent(from string, event *proto.Event) {
+	b.mu.Lock()
+	c := b.call
+	b.mu.Unlock()
+
+	switch event.Type {
+	case proto.EventType_RING:
+		if b.ringURI != "" && c == nil {
+			go b.dial(from)
+		}
+
+	case proto.EventType_ANSWER:
+		// another station answered the doorbell before the phone did
+		if c != nil && c.doorbell == event.Station && from != b.name && !c.established {
+			b.log.Infof("%v answered %v, stopping the call", from, event.Station)
+			b.hangUp(c, false)
+		}
+
+	case proto.EventType_HANGUP:
+		if c != nil && c.doorbell == event.Station {
+			b.log.Infof("%v hung up", from)
+			b.hangUp(c, false)
+		}
+
+	case proto.EventType_ANNOUNCEMENT:
+		b.log.Infof("announcement: %v", event.Text)
+	}
+}
+
+// mixer sums the audio of the stations talking into the packets sent to the phone
+type mixer struct {
+	mu       sync.Mutex
+	stations map[string]*mixerInput
+}
+
+type mixerInput struct {
+	resampler *audio.Resampler
+	samples   []int32
+}
+
+func newMixer() *mixer {
+	return &mixer{stations: map[string]*mixerInput{}}
+}
+
+// add queues a station's audio, converted to 8000 Hz mono
+func (m *mixer) add(station string, a *proto.Audio) {
+	rate := int(a.SampleRate)
+	if rate == 0 {
+		rate = defaultSampleRate
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in, ok := m.stations[station]
+	if !ok || in.resampler.InRate() != rate {
+		resampler, err := audio.NewResampler(rate, audio.G711SampleRate, 1)
+		if err != nil {
+			return
+		}
+		in = &mixerInput{resampler: resampler}
+		m.stations[station] = in
+	}
+	samples := audio.Remix(a.Samples, int(a.Channels), 1)
+	in.samples = append(in.samples, in.resampler.Process(samples)...)
+	if extra := len(in.samples) - maxMixerBacklog; extra > 0 {
+		in.samples = in.samples[extra:]
+	}
+}
+
+// next returns a packet of the stations' audio mixed, silence if nobody is talking
+func (m *mixer) next() []int32 {
+	var sum [packetSamples]int64
+
+	m.mu.Lock()
+	for _, in := range m.stations {
+		n := len(in.samples)
+		if n > packetSamples {
+			n = packetSamples
+		}
+		for i, s := range in.samples[:n] {
+			sum[i] += int64(s)
+		}
+		in.samples = in.samples[n:]
+	}
+	m.mu.Unlock()
+
+	out := make([]int32, packetSamples)
+	for i, s := range sum {
+		switch {
+		case s > math.MaxInt32:
+			out[i] = math.MaxInt32
+		case s < math.MinInt32:
+			out[i] = math.MinInt32
+		default:
+			out[i] = int32(s)
+		}
+	}
+	return out
+}
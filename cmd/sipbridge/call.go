@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/media"
+	"github.com/3xcellent/intercom/proto"
+)
+
+const (
+	// ringTimeout is how long the phone rings for a doorbell, as long as the doorbell rings
+	ringTimeout = 30 * time.Second
+	// byeTimeout bounds hanging up, the call is over either way
+	byeTimeout = 5 * time.Second
+	allowed    = "INVITE, ACK, BYE, CANCEL, OPTIONS"
+)
+
+// call is a phone call the bridge is in, or ringing for
+type call struct {
+	dialog
+	log *logger.Logger
+	// doorbell is the doorbell station the phone was called for, empty if the
+	// phone called the bridge
+	doorbell string
+	rtp      *rtpSession
+	mixer    *mixer
+	// established is set once both ends have answered, under the bridge's mu
+	established bool
+	// cancel stops ringing the phone, for calls the bridge makes
+	cancel context.CancelFunc
+	// acked is closed when the phone acknowledges the bridge answering
+	acked   chan struct{}
+	ackOnce sync.Once
+	// done is closed when the call ends
+	done    chan struct{}
+	endOnce sync.Once
+}
+
+func (b *bridge) newCall(d dialog, doorbell string) (*call, error) {
+	rtp, err := newRTPSession(b.mediaHost, b.log)
+	if err != nil {
+		return nil, err
+	}
+	return &call{
+		dialog:   d,
+		log:      b.log.With("call", d.callID),
+		doorbell: doorbell,
+		rtp:      rtp,
+		mixer:    newMixer(),
+		cancel:   func() {},
+		acked:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// handleRequest answers a request from the phone or the SIP server
+func (b *bridge) handleRequest(req *message, from *net.UDPAddr) {
+	b.mu.Lock()
+	c := b.call
+	b.mu.Unlock()
+	inCall := c != nil && c.callID == req.get("Call-ID")
+
+	switch req.method {
+	case "INVITE":
+		if inCall {
+			// a re-INVITE, the audio stays as it is
+			b.answer(c, req, from)
+			return
+		}
+		b.takeCall(req, from)
+
+	case "ACK":
+		if inCall {
+			c.ackOnce.Do(func() { close(c.acked) })
+		}
+
+	case "BYE":
+		if !inCall {
+			b.ua.respond(req, req.response(481, "Call/Transaction Does Not Exist"), from)
+			return
+		}
+		b.ua.respond(req, req.response(200, "OK"), from)
+		c.log.Infof("phone hung up")
+		b.endCall(c, true)
+
+	case "CANCEL":
+		// calls are answered straight away, so there's never one left to cancel
+		b.ua.respond(req, req.response(200, "OK"), from)
+
+	case "OPTIONS":
+		resp := req.response(200, "OK")
+		resp.add("Allow", allowed)
+		b.ua.respond(req, resp, from)
+
+	default:
+		resp := req.response(501, "Not Implemented")
+		resp.add("Allow", allowed)
+		b.ua.respond(req, resp, from)
+	}
+}
+
+// takeCall answers a call from the phone, which then talks to every station
+func (b *bridge) takeCall(req *message, from *net.UDPAddr) {
+	offer, err := parseSDP(req.body)
+	if err != nil {
+		b.ua.respond(req, req.response(400, "Bad Request"), from)
+		return
+	}
+	payload, err := offer.choosePayload(b.payload)
+	if err != nil {
+		b.ua.respond(req, req.response(488, "Not Acceptable Here"), from)
+		return
+	}
+
+	d := dialog{
+		callID: req.get("Call-ID"),
+		local:  req.get("To") + ";tag=" + media.RandomToken(4),
+		remote: req.get("From"),
+		target: headerURI(req.get("Contact")),
+		routes: req.all("Record-Route"),
+	}
+	c, err := b.newCall(d, "")
+	if err != nil {
+		b.log.Errorf("cannot take call: %v", err)
+		b.ua.respond(req, req.response(500, "Server Internal Error"), from)
+		return
+	}
+	if err := c.rtp.setRemote(offer.addr, payload); err != nil {
+		c.rtp.close()
+		b.ua.respond(req, req.response(488, "Not Acceptable Here"), from)
+		return
+	}
+
+	b.mu.Lock()
+	if b.call != nil {
+		b.mu.Unlock()
+		c.rtp.close()
+		b.ua.respond(req, req.response(486, "Busy Here"), from)
+		return
+	}
+	b.call = c
+	c.established = true
+	b.mu.Unlock()
+
+	c.log.Infof("call from %v, %v", headerURI(d.remote), payloadNames[payload])
+	b.answer(c, req, from)
+	b.startAudio(c)
+	b.sendStatus()
+
+	// the answer is resent until the phone acknowledges it
+	go func() {
+		interval := t1
+		timeout := time.After(transactionTimeout)
+		for {
+			select {
+			case <-c.acked:
+				return
+			case <-c.done:
+				return
+			case <-timeout:
+				c.log.Warnf("phone never acknowledged the answer, hanging up")
+				b.hangUp(c, false)
+				return
+			case <-time.After(interval):
+				b.answer(c, req, from)
+				if interval *= 2; interval > t2 {
+					interval = t2
+				}
+			}
+		}
+	}()
+}
+
+// answer sends the 200 OK to an INVITE in call c
+func (b *bridge) answer(c *call, req *message, from *net.UDPAddr) {
+	resp := req.response(200, "OK")
+	resp.set("To", c.local)
+	resp.add("Contact", b.ua.contact())
+	resp.add("Allow", allowed)
+	resp.add("Content-Type", "application/sdp")
+	c.rtp.mu.Lock()
+	payload := c.rtp.payload
+	c.rtp.mu.Unlock()
+	resp.body = buildSDP(b.mediaHost, c.rtp.port(), []int{payload})
+	b.ua.respond(req, resp, from)
+}
+
+// dial calls the phone for a doorbell that rang, and answers the doorbell
+// for the phone once it picks up
+func (b *bridge) dial(doorbell string) {
+	d := dialog{
+		callID: media.RandomToken(16),
+		local:  "<" + b.ua.aor() + ">;tag=" + media.RandomToken(4),
+		remote: "<" + b.ringURI + ">",
+	}
+	c, err := b.newCall(d, doorbell)
+	if err != nil {
+		b.log.Errorf("cannot call %v: %v", b.ringURI, err)
+		return
+	}
+	to := b.ua.proxy
+	if to == nil {
+		if to, err = net.ResolveUDPAddr("udp", uriHost(b.ringURI)); err != nil {
+			b.log.Errorf("cannot call %v: %v", b.ringURI, err)
+			c.rtp.close()
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ringTimeout)
+	defer cancel()
+	c.cancel = cancel
+
+	b.mu.Lock()
+	if b.call != nil {
+		b.mu.Unlock()
+		c.rtp.close()
+		return
+	}
+	b.call = c
+	b.mu.Unlock()
+
+	offer := []int{b.payload, payloadPCMU}
+	if b.payload == payloadPCMU {
+		offer[1] = payloadPCMA
+	}
+	invite := b.ua.request(&c.dialog, "INVITE", b.ringURI, 0)
+	invite.add("Allow", allowed)
+	invite.add("Content-Type", "application/sdp")
+	invite.body = buildSDP(b.mediaHost, c.rtp.port(), offer)
+
+	c.log.Infof("%v rang, calling %v", doorbell, b.ringURI)
+	resp, err := b.ua.sendAuthorized(ctx, &c.dialog, invite, to, func(resp *message) {
+		if resp.status == 180 || resp.status == 183 {
+			c.log.Debugf("phone ringing")
+		}
+	})
+	switch {
+	case err != nil:
+		c.log.Warnf("call to %v failed: %v", b.ringURI, err)
+		b.endCall(c, false)
+		return
+	case resp.status >= 300:
+		if resp.status != 487 {
+			c.log.Warnf("call to %v failed: %v %v", b.ringURI, resp.status, resp.reason)
+		} else {
+			c.log.Infof("stopped calling %v", b.ringURI)
+		}
+		b.endCall(c, false)
+		return
+	}
+
+	// the phone picked up
+	c.remote = resp.get("To")
+	c.target = headerURI(resp.get("Contact"))
+	routes := resp.all("Record-Route")
+	for i, j := 0, len(routes)-1; i < j; i, j = i+1, j-1 {
+		routes[i], routes[j] = routes[j], routes[i]
+	}
+	c.routes = routes
+
+	ackTo, err := b.ua.destination(&c.dialog)
+	if err != nil {
+		ackTo = to
+	}
+	b.ua.sendACK(b.ua.request(&c.dialog, "ACK", c.target, c.cseq), ackTo)
+
+	answer, err := parseSDP(resp.body)
+	var payload int
+	if err == nil {
+		payload, err = answer.choosePayload(b.payload)
+	}
+	if err == nil {
+		err = c.rtp.setRemote(answer.addr, payload)
+	}
+	if err != nil || ctx.Err() != nil {
+		// answered as the doorbell stopped ringing, or without audio the bridge can use
+		if err != nil {
+			c.log.Warnf("phone answered with unusable audio: %v", err)
+		}
+		b.hangUp(c, false)
+		return
+	}
+
+	b.mu.Lock()
+	c.established = true
+	b.mu.Unlock()
+
+	c.log.Infof("phone answered %v, %v", doorbell, payloadNames[payload])
+	b.startAudio(c)
+	b.sendStatus()
+	b.sendEvent(&proto.Event{Type: proto.EventType_ANSWER, Station: doorbell})
+}
+
+// startAudio relays the call's audio both ways until it ends
+func (b *bridge) startAudio(c *call) {
+	go c.rtp.receive(b.sendAudio)
+	go func() {
+		ticker := time.NewTicker(packetInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if err := c.rtp.send(c.mixer.next()); err != nil {
+					c.log.Debugf("audio send error: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// hangUp ends call c from the bridge's end: a call still ringing is
+// cancelled and an established one is ended with BYE.  tellDoorbell hangs
+// up the doorbell the call was for too.
+func (b *bridge) hangUp(c *call, tellDoorbell bool) {
+	b.mu.Lock()
+	established := c.established
+	b.mu.Unlock()
+
+	if !established {
+		// dial ends the call once the cancelled INVITE fails
+		c.cancel()
+		return
+	}
+
+	to, err := b.ua.destination(&c.dialog)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), byeTimeout)
+		defer cancel()
+		_, err = b.ua.sendAuthorized(ctx, &c.dialog, b.ua.request(&c.dialog, "BYE", c.target, 0), to, nil)
+	}
+	if err != nil {
+		c.log.Warnf("hang up error: %v", err)
+	}
+	c.log.Infof("hung up")
+	b.endCall(c, tellDoorbell)
+}
+
+// endCall clears up after call c, telling the doorbell it was for that it's over if tellDoorbell
+func (b *bridge) endCall(c *call, tellDoorbell bool) {
+	b.mu.Lock()
+	established := c.established
+	if b.call == c {
+		b.call = nil
+	}
+	b.mu.Unlock()
+
+	c.endOnce.Do(func() {
+		close(c.done)
+		c.rtp.close()
+		b.ua.forget(c.callID)
+		if established {
+			b.sendStatus()
+			if tellDoorbell && c.doorbell != "" {
+				b.sendEvent(&proto.Event{Type: proto.EventType_HANGUP, Station: c.doorbell})
+			}
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/3xcellent/intercom/dial"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+func main() {
+	var server dial.Config
+	server.RegisterFlags(flag.CommandLine)
+	name := flag.String("name", "phone", "station name the bridge joins the intercom as")
+	room := flag.String("room", "", "room the bridge joins, empty for the server's default")
+	sipAddr := flag.String("sip-addr", ":5062", "local UDP address SIP is sent and received on")
+	registrar := flag.String("registrar", "", "SIP server to register with and place calls through as host:port, empty to only take calls made straight to -sip-addr")
+	user := flag.String("user", "intercom", "SIP user the bridge registers as")
+	password := flag.String("password", os.Getenv("INTERCOM_SIP_PASSWORD"), "SIP password (default $INTERCOM_SIP_PASSWORD)")
+	domain := flag.String("domain", "", "SIP domain, the registrar's host by default")
+	expiry := flag.Duration("register-expiry", 5*time.Minute, "how long each registration asks to last")
+	ringURI := flag.String("ring", "", "SIP URI called when a doorbell station rings, e.g. sip:desk@pbx.local, empty to not call")
+	codec := flag.String("codec", "pcmu", "G.711 law preferred for calls: pcmu or pcma")
+	logLevel := flag.String("log-level", "info", "least important messages logged: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	flag.Parse()
+
+	log, err := logger.Parse(*logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	payload, ok := payloadByName[strings.ToLower(*codec)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "-codec must be pcmu or pcma, not %q\n", *codec)
+		os.Exit(2)
+	}
+
+	listenAddr, err := net.ResolveUDPAddr("udp", *sipAddr)
+	if err != nil {
+		log.Errorf("-sip-addr: %v", err)
+		os.Exit(2)
+	}
+	var proxy *net.UDPAddr
+	if *registrar != "" {
+		if proxy, err = net.ResolveUDPAddr("udp", *registrar); err != nil {
+			log.Errorf("-registrar: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	conn, err := net.ListenUDP("udp", listenAddr)
+	if err != nil {
+		log.Errorf("cannot listen for SIP: %v", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	host := localHost(listenAddr, proxy)
+	if *domain == "" {
+		*domain = host
+		if proxy != nil {
+			*domain, _, _ = net.SplitHostPort(*registrar)
+		}
+	}
+	ua := newUserAgent(conn, net.JoinHostPort(host, fmt.Sprint(conn.LocalAddr().(*net.UDPAddr).Port)), proxy, log)
+	ua.user, ua.password, ua.domain = *user, *password, *domain
+
+	intercomConn, err := server.Dial()
+	if err != nil {
+		log.Errorf("cannot connect to %v: %v", server.Addr, err)
+		os.Exit(1)
+	}
+	defer intercomConn.Close()
+	ctx := context.Background()
+	if *room != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "room", *room)
+	}
+	stream, err := proto.NewIntercomClient(intercomConn).Connect(ctx)
+	if err != nil {
+		log.Errorf("cannot connect to %v: %v", server.Addr, err)
+		os.Exit(1)
+	}
+
+	b := &bridge{
+		log:       log,
+		name:      *name,
+		ua:        ua,
+		ringURI:   *ringURI,
+		payload:   payload,
+		mediaHost: host,
+		stream:    stream,
+	}
+	ua.handler = b.handleRequest
+	b.sendStatus()
+	log.Infof("joined %v as %v, taking calls at sip:%v@%v", server.Addr, *name, *user, ua.host)
+
+	go ua.serve()
+	ctx, cancel := context.WithCancel(context.Background())
+	registered := make(chan struct{})
+	if proxy != nil {
+		go func() {
+			ua.register(ctx, *expiry)
+			close(registered)
+		}()
+	} else {
+		close(registered)
+	}
+
+	received := make(chan error, 1)
+	go func() { received <- b.receive() }()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case sig := <-signals:
+		log.Infof("%v, stopping", sig)
+	case err := <-received:
+		log.Errorf("intercom stream ended: %v", err)
+		exitCode = 1
+	}
+
+	b.mu.Lock()
+	c := b.call
+	b.mu.Unlock()
+	if c != nil {
+		b.hangUp(c, true)
+	}
+	cancel()
+	<-registered
+	os.Exit(exitCode)
+}
+
+// localHost is the address phones reach this machine at: the one -sip-addr
+// names, or the one packets to the SIP server leave from
+func localHost(listen, proxy *net.UDPAddr) string {
+	if listen.IP != nil && !listen.IP.IsUnspecified() {
+		return listen.IP.String()
+	}
+	if proxy != nil {
+		if conn, err := net.DialUDP("udp", nil, proxy); err == nil {
+			defer conn.Close()
+			return conn.LocalAddr().(*net.UDPAddr).IP.String()
+		}
+	}
+	addrs, _ := net.InterfaceAddrs()
+	for _, addr := range addrs {
+		if ip, ok := addr.(*net.IPNet); ok && !ip.IP.IsLoopback() && ip.IP.To4() != nil {
+			return ip.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/audio"
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/media"
+)
+
+const (
+	// payloadPCMU and payloadPCMA are G.711's static RTP payload types, RFC 3551
+	payloadPCMU = 0
+	payloadPCMA = 8
+
+	// packetSamples is 20 ms at 8000 Hz, the packet size phones use by default
+	packetSamples  = 160
+	packetInterval = 20 * time.Millisecond
+	maxPacketSize  = 1500
+)
+
+var (
+	errBadSDP     = errors.New("malformed SDP")
+	errNoG711     = errors.New("no G.711 audio offered")
+	payloadByName = map[string]int{"pcmu": payloadPCMU, "pcma": payloadPCMA}
+	payloadNames  = map[int]string{payloadPCMU: "PCMU", payloadPCMA: "PCMA"}
+)
+
+// sessionDescription is what the bridge uses of SDP: where to send audio and
+// the payload types the other end takes, in its order of preference
+type sessionDescription struct {
+	addr     string
+	payloads []int
+}
+
+// parseSDP reads the first audio stream of an SDP body
+func parseSDP(body []byte) (*sessionDescription, error) {
+	var sessionHost, mediaHost, port string
+	var payloads []int
+	// section is where the line is: the session, the audio stream or other media
+	const (
+		inSession = iota
+		inAudio
+		inOther
+	)
+	section := inSession
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "m="):
+			fields := strings.Fields(line[2:])
+			if port != "" || len(fields) < 4 || fields[0] != "audio" {
+				section = inOther
+				continue
+			}
+			section = inAudio
+			port = fields[1]
+			for _, f := range fields[3:] {
+				if pt, err := strconv.Atoi(f); err == nil {
+					payloads = append(payloads, pt)
+				}
+			}
+		case strings.HasPrefix(line, "c="):
+			fields := strings.Fields(line[2:])
+			if len(fields) != 3 {
+				continue
+			}
+			switch section {
+			case inSession:
+				sessionHost = fields[2]
+			case inAudio:
+				mediaHost = fields[2]
+			}
+		}
+	}
+
+	// a connection line in the media section overrides the session's
+	host := mediaHost
+	if host == "" {
+		host = sessionHost
+	}
+	if host == "" || port == "" {
+		return nil, errBadSDP
+	}
+	return &sessionDescription{addr: net.JoinHostPort(host, port), payloads: payloads}, nil
+}
+
+// choosePayload picks the G.711 law to use from those offered, preferred if it can
+func (sd *sessionDescription) choosePayload(preferred int) (int, error) {
+	chosen := -1
+	for _, pt := range sd.payloads {
+		if pt == preferred {
+			return pt, nil
+		}
+		if _, ok := payloadNames[pt]; ok && chosen < 0 {
+			chosen = pt
+		}
+	}
+	if chosen < 0 {
+		return 0, errNoG711
+	}
+	return chosen, nil
+}
+
+// buildSDP describes the bridge's audio stream at host:port taking payloads
+func buildSDP(host string, port int, payloads []int) []byte {
+	id := time.Now().Unix()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "v=0\r\n")
+	fmt.Fprintf(&b, "o=- %v %v IN IP4 %v\r\n", id, id, host)
+	fmt.Fprintf(&b, "s=intercom\r\n")
+	fmt.Fprintf(&b, "c=IN IP4 %v\r\n", host)
+	fmt.Fprintf(&b, "t=0 0\r\n")
+	fmt.Fprintf(&b, "m=audio %v RTP/AVP", port)
+	for _, pt := range payloads {
+		fmt.Fprintf(&b, " %v", pt)
+	}
+	fmt.Fprintf(&b, "\r\n")
+	for _, pt := range payloads {
+		fmt.Fprintf(&b, "a=rtpmap:%v %v/%v\r\n", pt, payloadNames[pt], audio.G711SampleRate)
+	}
+	fmt.Fprintf(&b, "a=ptime:%v\r\n", int(packetInterval/time.Millisecond))
+	fmt.Fprintf(&b, "a=sendrecv\r\n")
+	return b.Bytes()
+}
+
+// rtpSession sends and receives a call's G.711 audio
+type rtpSession struct {
+	log  *logger.Logger
+	conn *net.UDPConn
+
+	mu sync.Mutex
+	// remote is where audio is sent, updated to where it comes from once it
+	// arrives, which gets it through NAT
+	remote    *net.UDPAddr
+	learned   bool
+	payload   int
+	seq       uint16
+	timestamp uint32
+	ssrc      uint32
+}
+
+// newRTPSession listens for audio on a free port of host
+func newRTPSession(host string, log *logger.Logger) (*rtpSession, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(host)})
+	if err != nil {
+		return nil, err
+	}
+	return &rtpSession{
+		log:       log,
+		conn:      conn,
+		payload:   payloadPCMU,
+		seq:       uint16(rand.Uint32()),
+		timestamp: rand.Uint32(),
+		ssrc:      rand.Uint32(),
+	}, nil
+}
+
+func (r *rtpSession) port() int {
+	return r.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// setRemote sets where audio is sent and the payload type it is sent as
+func (r *rtpSession) setRemote(addr string, payload int) error {
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remote, r.payload = remote, payload
+	return nil
+}
+
+// send sends one packet of 8000 Hz mono samples
+func (r *rtpSession) send(samples []int32) error {
+	r.mu.Lock()
+	remote := r.remote
+	packet := media.Packet{PayloadType: r.payload, Sequence: r.seq, Timestamp: r.timestamp, SSRC: r.ssrc}
+	r.seq++
+	r.timestamp += uint32(len(samples))
+	r.mu.Unlock()
+
+	if remote == nil {
+		return nil
+	}
+	if packet.PayloadType == payloadPCMA {
+		packet.Payload = audio.EncodeALaw(samples)
+	} else {
+		packet.Payload = audio.EncodeULaw(samples)
+	}
+	_, err := r.conn.WriteToUDP(packet.Marshal(), remote)
+	return err
+}
+
+// receive calls handle with the 8000 Hz mono samples of each G.711 packet
+// received, until the session is closed.  Other payloads, like DTMF, are skipped.
+func (r *rtpSession) receive(handle func(samples []int32)) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		packet, ok := media.ParseRTP(buf[:n])
+		if !ok {
+			continue
+		}
+
+		r.mu.Lock()
+		if !r.learned {
+			r.learned = true
+			r.remote = from
+			r.log.Debugf("audio arriving from %v", from)
+		}
+		r.mu.Unlock()
+
+		switch packet.PayloadType {
+		case payloadPCMU:
+			handle(audio.DecodeULaw(packet.Payload))
+		case payloadPCMA:
+			handle(audio.DecodeALaw(packet.Payload))
+		}
+	}
+}
+
+func (r *rtpSession) close() {
+	r.conn.Close()
+}
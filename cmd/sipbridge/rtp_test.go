@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sdp(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func TestParseSDP(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		addr     string
+		payloads []int
+	}{
+		{
+			name: "session connection",
+			body: sdp("v=0", "o=- 1 1 IN IP4 10.0.0.5", "s=-", "c=IN IP4 10.0.0.5", "t=0 0",
+				"m=audio 4000 RTP/AVP 0 8 101", "a=rtpmap:101 telephone-event/8000"),
+			addr:     "10.0.0.5:4000",
+			payloads: []int{0, 8, 101},
+		},
+		{
+			name:     "media connection overrides the session's",
+			body:     sdp("v=0", "c=IN IP4 10.0.0.5", "m=audio 4000 RTP/AVP 8", "c=IN IP4 192.168.1.9"),
+			addr:     "192.168.1.9:4000",
+			payloads: []int{8},
+		},
+		{
+			name: "video before the audio",
+			body: sdp("v=0", "c=IN IP4 10.0.0.5", "m=video 5000 RTP/AVP 96", "c=IN IP4 10.0.0.6",
+				"m=audio 4000 RTP/AVP 0"),
+			addr:     "10.0.0.5:4000",
+			payloads: []int{0},
+		},
+		{
+			name:     "second audio stream ignored",
+			body:     sdp("c=IN IP4 10.0.0.5", "m=audio 4000 RTP/AVP 0", "m=audio 4002 RTP/AVP 8"),
+			addr:     "10.0.0.5:4000",
+			payloads: []int{0},
+		},
+		{
+			name:     "bare newlines and an IPv6 address",
+			body:     []byte("v=0\nc=IN IP6 fe80::1\nm=audio 4000 RTP/AVP 8 x 0\n"),
+			addr:     "[fe80::1]:4000",
+			payloads: []int{8, 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sd, err := parseSDP(test.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sd.addr != test.addr || !reflect.DeepEqual(sd.payloads, test.payloads) {
+				t.Errorf("got %v %v, want %v %v", sd.addr, sd.payloads, test.addr, test.payloads)
+			}
+		})
+	}
+}
+
+func TestParseSDPMalformed(t *testing.T) {
+	for name, body := range map[string][]byte{
+		"empty":            nil,
+		"no audio":         sdp("c=IN IP4 10.0.0.5", "m=video 5000 RTP/AVP 96"),
+		"no connection":    sdp("m=audio 4000 RTP/AVP 0"),
+		"short media line": sdp("c=IN IP4 10.0.0.5", "m=audio 4000"),
+		"only video's connection": sdp("m=video 5000 RTP/AVP 96", "c=IN IP4 10.0.0.6",
+			"m=audio 4000 RTP/AVP 0"),
+	} {
+		if sd, err := parseSDP(body); err == nil {
+			t.Errorf("%v: parsed %+v, want an error", name, sd)
+		}
+	}
+}
+
+func TestBuildSDP(t *testing.T) {
+	sd, err := parseSDP(buildSDP("10.0.0.2", 4000, []int{payloadPCMA, payloadPCMU}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.addr != "10.0.0.2:4000" || !reflect.DeepEqual(sd.payloads, []int{payloadPCMA, payloadPCMU}) {
+		t.Errorf("read back %v %v", sd.addr, sd.payloads)
+	}
+}
+
+func TestChoosePayload(t *testing.T) {
+	tests := []struct {
+		offered   []int
+		preferred int
+		want      int
+		ok        bool
+	}{
+		{[]int{0, 8}, payloadPCMU, payloadPCMU, true},
+		{[]int{0, 8}, payloadPCMA, payloadPCMA, true},
+		{[]int{8}, payloadPCMU, payloadPCMA, true},
+		{[]int{18, 0, 8}, payloadPCMA, payloadPCMA, true},
+		{[]int{18, 101, 0}, payloadPCMA, payloadPCMU, true},
+		{[]int{18, 101}, payloadPCMU, 0, false},
+		{nil, payloadPCMU, 0, false},
+	}
+	for _, test := range tests {
+		sd := &sessionDescription{payloads: test.offered}
+		got, err := sd.choosePayload(test.preferred)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("offered %v preferring %v: chose %v, %v", test.offered, test.preferred, got, err)
+		}
+	}
+}
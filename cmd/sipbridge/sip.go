@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/3xcellent/intercom/media"
+)
+
+const (
+	sipVersion = "SIP/2.0"
+	// branchMagic starts every Via branch of RFC 3261 transactions
+	branchMagic = "z9hG4bK"
+	agentName   = "intercom-sipbridge"
+)
+
+var errBadMessage = errors.New("malformed SIP message")
+
+// compactHeaders are the single letter forms of headers, expanded on parsing
+var compactHeaders = map[string]string{
+	"v": "Via",
+	"f": "From",
+	"t": "To",
+	"i": "Call-ID",
+	"m": "Contact",
+	"l": "Content-Length",
+	"c": "Content-Type",
+	"k": "Supported",
+}
+
+type header struct {
+	name, value string
+}
+
+// message is a SIP request or response, headers keep their order as Via has to
+type message struct {
+	// method and uri are set for requests, status and reason for responses
+	method string
+	uri    string
+	status int
+	reason string
+
+	headers []header
+	body    []byte
+}
+
+func (m *message) isRequest() bool {
+	return m.method != ""
+}
+
+// parseMessage parses a SIP message from a UDP datagram
+func parseMessage(data []byte) (*message, error) {
+	head, body := data, []byte(nil)
+	if i := bytes.Index(data, []byte("\r\n\r\n")); i >= 0 {
+		head, body = data[:i], data[i+4:]
+	}
+
+	lines := strings.Split(string(head), "\r\n")
+	start := strings.SplitN(lines[0], " ", 3)
+	if len(start) < 3 {
+		return nil, errBadMessage
+	}
+
+	m := &message{}
+	if start[0] == sipVersion {
+		status, err := strconv.Atoi(start[1])
+		if err != nil {
+			return nil, errBadMessage
+		}
+		m.status, m.reason = status, start[2]
+	} else {
+		if start[2] != sipVersion {
+			return nil, errBadMessage
+		}
+		m.method, m.uri = start[0], start[1]
+	}
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		// folded lines continue the header before them
+		if (line[0] == ' ' || line[0] == '\t') && len(m.headers) > 0 {
+			m.headers[len(m.headers)-1].value += " " + strings.TrimSpace(line)
+			continue
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			return nil, errBadMessage
+		}
+		name := strings.TrimSpace(line[:colon])
+		if long, ok := compactHeaders[strings.ToLower(name)]; ok {
+			name = long
+		}
+		m.headers = append(m.headers, header{name: name, value: strings.TrimSpace(line[colon+1:])})
+	}
+
+	if length := m.get("Content-Length"); length != "" {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 || n > len(body) {
+			return nil, errBadMessage
+		}
+		body = body[:n]
+	}
+	m.body = body
+	return m, nil
+}
+
+func (m *message) bytes() []byte {
+	var b bytes.Buffer
+	if m.isRequest() {
+		fmt.Fprintf(&b, "%v %v %v\r\n", m.method, m.uri, sipVersion)
+	} else {
+		fmt.Fprintf(&b, "%v %v %v\r\n", sipVersion, m.status, m.reason)
+	}
+	for _, h := range m.headers {
+		if strings.EqualFold(h.name, "Content-Length") {
+			continue
+		}
+		fmt.Fprintf(&b, "%v: %v\r\n", h.name, h.value)
+	}
+	fmt.Fprintf(&b, "Content-Length: %v\r\n\r\n", len(m.body))
+	b.Write(m.body)
+	return b.Bytes()
+}
+
+// get returns the first value of the header name
+func (m *message) get(name string) string {
+	for _, h := range m.headers {
+		if strings.EqualFold(h.name, name) {
+			return h.value
+		}
+	}
+	return ""
+}
+
+// all returns every value of the header name, comma separated values split apart
+func (m *message) all(name string) []string {
+	var values []string
+	for _, h := range m.headers {
+		if strings.EqualFold(h.name, name) {
+			values = append(values, splitHeader(h.value)...)
+		}
+	}
+	return values
+}
+
+func (m *message) add(name, value string) {
+	m.headers = append(m.headers, header{name: name, value: value})
+}
+
+// set replaces the header name with value, in place if it is there
+func (m *message) set(name, value string) {
+	for i, h := range m.headers {
+		if strings.EqualFold(h.name, name) {
+			m.headers[i].value = value
+			rest := &message{headers: m.headers[i+1:]}
+			rest.del(name)
+			m.headers = append(m.headers[:i+1], rest.headers...)
+			return
+		}
+	}
+	m.add(name, value)
+}
+
+func (m *message) del(name string) {
+	headers := m.headers[:0]
+	for _, h := range m.headers {
+		if !strings.EqualFold(h.name, name) {
+			headers = append(headers, h)
+		}
+	}
+	m.headers = headers
+}
+
+// cseq returns the sequence number and method of the CSeq header
+func (m *message) cseq() (int, string) {
+	fields := strings.Fields(m.get("CSeq"))
+	if len(fields) != 2 {
+		return 0, ""
+	}
+	n, _ := strconv.Atoi(fields[0])
+	return n, fields[1]
+}
+
+// branch is the branch of the top Via, which names the message's transaction
+func (m *message) branch() string {
+	vias := m.all("Via")
+	if len(vias) == 0 {
+		return ""
+	}
+	return headerParam(vias[0], "branch")
+}
+
+// response builds a response to the request m, copying the headers that
+// tie it to the request
+func (m *message) response(status int, reason string) *message {
+	resp := &message{status: status, reason: reason}
+	for _, h := range m.headers {
+		switch strings.ToLower(h.name) {
+		case "via", "from", "to", "call-id", "cseq", "record-route":
+			resp.add(h.name, h.value)
+		}
+	}
+	resp.add("User-Agent", agentName)
+	return resp
+}
+
+// splitHeader splits a header's comma separated values, leaving commas in
+// quotes and angle brackets alone
+func splitHeader(value string) []string {
+	var values []string
+	quoted, bracketed, start := false, false, 0
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '<' && !quoted:
+			bracketed = true
+		case r == '>' && !quoted:
+			bracketed = false
+		case r == ',' && !quoted && !bracketed:
+			values = append(values, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, strings.TrimSpace(value[start:]))
+}
+
+// headerParam returns the ;name=value parameter of a header value, after its
+// address if it has one
+func headerParam(value, name string) string {
+	if i := strings.LastIndexByte(value, '>'); i >= 0 {
+		value = value[i+1:]
+	}
+	for _, param := range strings.Split(value, ";")[1:] {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if strings.EqualFold(kv[0], name) {
+			if len(kv) == 2 {
+				return strings.Trim(kv[1], `"`)
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// headerURI returns the URI of a From, To or Contact header value
+func headerURI(value string) string {
+	if i := strings.IndexByte(value, '<'); i >= 0 {
+		if j := strings.IndexByte(value[i:], '>'); j >= 0 {
+			return value[i+1 : i+j]
+		}
+	}
+	if i := strings.IndexByte(value, ';'); i >= 0 {
+		return strings.TrimSpace(value[:i])
+	}
+	return strings.TrimSpace(value)
+}
+
+// uriHost returns the host and port of a sip: URI, with the default port if it has none
+func uriHost(uri string) string {
+	uri = strings.TrimPrefix(strings.TrimPrefix(uri, "sips:"), "sip:")
+	if i := strings.IndexByte(uri, '@'); i >= 0 {
+		uri = uri[i+1:]
+	}
+	if i := strings.IndexAny(uri, ";?"); i >= 0 {
+		uri = uri[:i]
+	}
+	if !strings.Contains(uri, ":") {
+		uri += ":5060"
+	}
+	return uri
+}
+
+// uriUser returns the user part of a sip: URI
+func uriUser(uri string) string {
+	uri = strings.TrimPrefix(strings.TrimPrefix(uri, "sips:"), "sip:")
+	if i := strings.IndexByte(uri, '@'); i >= 0 {
+		return uri[:i]
+	}
+	return ""
+}
+
+func newBranch() string {
+	return branchMagic + media.RandomToken(8)
+}
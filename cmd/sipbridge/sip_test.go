@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+const testInvite = "INVITE sip:intercom@10.0.0.2 SIP/2.0\r\n" +
+	"Via: SIP/2.0/UDP 10.0.0.5:5060;branch=z9hG4bK776asdhds\r\n" +
+	"v: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bKproxy\r\n" +
+	"From: \"Desk\" <sip:desk@pbx.local>;tag=1928301774\r\n" +
+	"To: <sip:intercom@pbx.local>\r\n" +
+	"Call-ID: a84b4c76e66710\r\n" +
+	"CSeq: 314159 INVITE\r\n" +
+	"Contact: <sip:desk@10.0.0.5>\r\n" +
+	"Subject: front\r\n" +
+	" door\r\n" +
+	"Content-Type: application/sdp\r\n" +
+	"Content-Length: 4\r\n" +
+	"\r\n" +
+	"v=0\n" +
+	"trailing junk"
+
+func TestParseMessage(t *testing.T) {
+	m, err := parseMessage([]byte(testInvite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.isRequest() || m.method != "INVITE" || m.uri != "sip:intercom@10.0.0.2" {
+		t.Errorf("request line %q %q", m.method, m.uri)
+	}
+	if vias := m.all("Via"); len(vias) != 2 {
+		t.Errorf("vias %q, want both, the compact one expanded", vias)
+	}
+	if branch := m.branch(); branch != "z9hG4bK776asdhds" {
+		t.Errorf("branch %q", branch)
+	}
+	if n, method := m.cseq(); n != 314159 || method != "INVITE" {
+		t.Errorf("cseq %v %v", n, method)
+	}
+	if subject := m.get("subject"); subject != "front door" {
+		t.Errorf("folded subject %q", subject)
+	}
+	if tag := headerParam(m.get("From"), "tag"); tag != "1928301774" {
+		t.Errorf("from tag %q", tag)
+	}
+	if string(m.body) != "v=0\n" {
+		t.Errorf("body %q, want Content-Length of it", m.body)
+	}
+
+	again, err := parseMessage(m.bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.bytes(), m.bytes()) {
+		t.Errorf("reparsed\n%s\nwant\n%s", again.bytes(), m.bytes())
+	}
+
+	resp, err := parseMessage([]byte("SIP/2.0 401 Unauthorized\r\nCSeq: 1 REGISTER\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.isRequest() || resp.status != 401 || resp.reason != "Unauthorized" {
+		t.Errorf("status line %v %q", resp.status, resp.reason)
+	}
+}
+
+func TestParseMessageMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"blank line", "\r\n\r\n"},
+		{"short start line", "INVITE sip:a\r\n\r\n"},
+		{"wrong version", "INVITE sip:a SIP/3.0\r\n\r\n"},
+		{"bad status", "SIP/2.0 abc OK\r\n\r\n"},
+		{"header without colon", "OPTIONS sip:a SIP/2.0\r\nVia\r\n\r\n"},
+		{"negative length", "OPTIONS sip:a SIP/2.0\r\nContent-Length: -1\r\n\r\nbody"},
+		{"long length", "OPTIONS sip:a SIP/2.0\r\nContent-Length: 10\r\n\r\nbody"},
+		{"bad length", "OPTIONS sip:a SIP/2.0\r\nl: four\r\n\r\nbody"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if m, err := parseMessage([]byte(test.data)); err == nil {
+				t.Errorf("parsed %+v, want an error", m)
+			}
+		})
+	}
+}
+
+// TestParseMessageNoPanic parses every truncation of a message and random
+// corruptions of it, then reads it as the user agent would
+func TestParseMessageNoPanic(t *testing.T) {
+	inputs := [][]byte{}
+	for i := 0; i <= len(testInvite); i++ {
+		inputs = append(inputs, []byte(testInvite[:i]))
+	}
+	random := rand.New(rand.NewSource(1))
+	alphabet := []byte(" \t\r\n:;,<>\"=-0123456789@vlSIP/2.")
+	for i := 0; i < 20000; i++ {
+		data := []byte(testInvite)
+		for n := random.Intn(8) + 1; n > 0; n-- {
+			data[random.Intn(len(data))] = alphabet[random.Intn(len(alphabet))]
+		}
+		inputs = append(inputs, data)
+	}
+
+	for _, data := range inputs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("panic parsing %q: %v", data, r)
+				}
+			}()
+			m, err := parseMessage(data)
+			if err != nil {
+				return
+			}
+			m.branch()
+			m.cseq()
+			for _, name := range []string{"From", "To", "Contact"} {
+				uri := headerURI(m.get(name))
+				uriHost(uri)
+				uriUser(uri)
+				headerParam(m.get(name), "tag")
+			}
+			m.response(200, "OK").bytes()
+		}()
+	}
+}
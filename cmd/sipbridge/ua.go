@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/media"
+)
+
+const (
+	// t1 and t2 are RFC 3261's retransmission intervals over UDP
+	t1 = 500 * time.Millisecond
+	t2 = 4 * time.Second
+	// transactionTimeout is how long a request waits for a response, 64*T1
+	transactionTimeout = 64 * t1
+	// registerRetry is how long to wait before registering again after failing
+	registerRetry  = 30 * time.Second
+	maxMessageSize = 65535
+)
+
+var errTimeout = errors.New("no response")
+
+// dialog is what the requests of one conversation share, a call or a
+// registration.  local and remote are the From and To headers of the
+// requests sent, tags included.
+type dialog struct {
+	callID string
+	local  string
+	remote string
+	// target is where requests in the dialog go, the other end's Contact
+	target string
+	routes []string
+	cseq   int
+}
+
+// userAgent sends and receives SIP over UDP
+type userAgent struct {
+	log  *logger.Logger
+	conn *net.UDPConn
+	// host is the address the agent is reached at, in Via and Contact
+	host string
+	// proxy is where requests outside a dialog go, the SIP server
+	proxy *net.UDPAddr
+
+	user     string
+	password string
+	domain   string
+
+	// handler is called for each new request received, in its own goroutine
+	handler func(req *message, from *net.UDPAddr)
+
+	mu sync.Mutex
+	// transactions are the requests waiting for responses, by branch and method
+	transactions map[string]chan *message
+	// answered are the responses sent to requests, by branch and method, to
+	// resend when a request is retransmitted
+	answered map[string][]byte
+	// acks are the ACKs sent for calls, by Call-ID, to resend when the other
+	// end retransmits its 200 OK
+	acks map[string][]byte
+}
+
+func newUserAgent(conn *net.UDPConn, host string, proxy *net.UDPAddr, log *logger.Logger) *userAgent {
+	return &userAgent{
+		log:          log,
+		conn:         conn,
+		host:         host,
+		proxy:        proxy,
+		transactions: map[string]chan *message{},
+		answered:     map[string][]byte{},
+		acks:         map[string][]byte{},
+	}
+}
+
+// aor is the agent's address of record, what phones call it at
+func (ua *userAgent) aor() string {
+	return fmt.Sprintf("sip:%v@%v", ua.user, ua.domain)
+}
+
+func (ua *userAgent) contact() string {
+	return fmt.Sprintf("<sip:%v@%v>", ua.user, ua.host)
+}
+
+// serve reads messages until the connection is closed, handing responses to
+// the requests waiting for them and requests to the handler
+func (ua *userAgent) serve() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := ua.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		m, err := parseMessage(data)
+		if err != nil {
+			// keep-alives and other noise
+			continue
+		}
+
+		_, method := m.cseq()
+		key := m.branch() + " " + method
+		if !m.isRequest() {
+			ua.mu.Lock()
+			responses, ok := ua.transactions[key]
+			ack := ua.acks[m.get("Call-ID")]
+			ua.mu.Unlock()
+			switch {
+			case ok:
+				select {
+				case responses <- m:
+				default:
+				}
+			case method == "INVITE" && m.status >= 200 && m.status < 300 && ack != nil:
+				// the 200 OK came again, the ACK for it was lost
+				ua.write(ack, from)
+			}
+			continue
+		}
+
+		ua.mu.Lock()
+		response, retransmitted := ua.answered[key]
+		if !retransmitted {
+			ua.answered[key] = nil
+			time.AfterFunc(transactionTimeout, func() {
+				ua.mu.Lock()
+				delete(ua.answered, key)
+				ua.mu.Unlock()
+			})
+		}
+		ua.mu.Unlock()
+		if retransmitted {
+			if response != nil {
+				ua.write(response, from)
+			}
+			continue
+		}
+
+		if ua.log.Enabled(logger.Debug) {
+			ua.log.With("call", m.get("Call-ID")).Debugf("received %v from %v", m.method, from)
+		}
+		go ua.handler(m, from)
+	}
+}
+
+func (ua *userAgent) write(data []byte, to *net.UDPAddr) {
+	if _, err := ua.conn.WriteToUDP(data, to); err != nil {
+		ua.log.Warnf("send error: %v", err)
+	}
+}
+
+// respond sends a response to req, and again whenever req is retransmitted
+func (ua *userAgent) respond(req, resp *message, to *net.UDPAddr) {
+	data := resp.bytes()
+	_, method := req.cseq()
+	ua.mu.Lock()
+	if method != "ACK" {
+		ua.answered[req.branch()+" "+method] = data
+	}
+	ua.mu.Unlock()
+	ua.write(data, to)
+}
+
+// request builds a request in the dialog d, a new transaction with the next
+// CSeq unless cseq is given
+func (ua *userAgent) request(d *dialog, method, uri string, cseq int) *message {
+	if cseq == 0 {
+		d.cseq++
+		cseq = d.cseq
+	}
+
+	m := &message{method: method, uri: uri}
+	m.add("Via", fmt.Sprintf("SIP/2.0/UDP %v;branch=%v;rport", ua.host, newBranch()))
+	for _, route := range d.routes {
+		m.add("Route", route)
+	}
+	m.add("Max-Forwards", "70")
+	m.add("From", d.local)
+	m.add("To", d.remote)
+	m.add("Call-ID", d.callID)
+	m.add("CSeq", fmt.Sprintf("%v %v", cseq, method))
+	m.add("Contact", ua.contact())
+	m.add("User-Agent", agentName)
+	return m
+}
+
+// destination is where requests in d are sent: the first route, or the
+// target if it has none
+func (ua *userAgent) destination(d *dialog) (*net.UDPAddr, error) {
+	uri := d.target
+	if len(d.routes) > 0 {
+		uri = headerURI(d.routes[0])
+	}
+	if uri == "" {
+		return ua.proxy, nil
+	}
+	return net.ResolveUDPAddr("udp", uriHost(uri))
+}
+
+// send sends req to to and waits for its final response, retransmitting it
+// until a response arrives.  Provisional responses are passed to progress, if
+// set.  Cancelling ctx cancels an INVITE with no final response yet, send
+// then returns its 487 Request Terminated.
+func (ua *userAgent) send(ctx context.Context, req *message, to *net.UDPAddr, progress func(*message)) (*message, error) {
+	key := req.branch() + " " + req.method
+	responses := make(chan *message, 8)
+	ua.mu.Lock()
+	ua.transactions[key] = responses
+	ua.mu.Unlock()
+	defer func() {
+		ua.mu.Lock()
+		delete(ua.transactions, key)
+		ua.mu.Unlock()
+	}()
+
+	data := req.bytes()
+	ua.write(data, to)
+
+	interval := t1
+	retransmit := time.NewTimer(interval)
+	defer retransmit.Stop()
+	timeout := time.NewTimer(transactionTimeout)
+	defer timeout.Stop()
+	done := ctx.Done()
+
+	for {
+		select {
+		case resp := <-responses:
+			if resp.status < 200 {
+				// the request arrived, an INVITE now rings for as long as ctx allows
+				retransmit.Stop()
+				if req.method == "INVITE" {
+					timeout.Stop()
+				}
+				if progress != nil {
+					progress(resp)
+				}
+				continue
+			}
+			if req.method == "INVITE" && resp.status >= 300 {
+				ua.write(ua.ackFailure(req, resp).bytes(), to)
+			}
+			return resp, nil
+
+		case <-retransmit.C:
+			ua.write(data, to)
+			if interval *= 2; interval > t2 {
+				interval = t2
+			}
+			retransmit.Reset(interval)
+
+		case <-timeout.C:
+			return nil, errTimeout
+
+		case <-done:
+			done = nil
+			if req.method != "INVITE" {
+				return nil, ctx.Err()
+			}
+			go ua.send(context.Background(), ua.cancelRequest(req), to, nil)
+			timeout.Reset(transactionTimeout)
+		}
+	}
+}
+
+// cancelRequest builds the CANCEL for an INVITE, in the INVITE's transaction
+func (ua *userAgent) cancelRequest(invite *message) *message {
+	cancel := &message{method: "CANCEL", uri: invite.uri}
+	for _, h := range invite.headers {
+		switch h.name {
+		case "Via", "Route", "From", "To", "Call-ID", "Max-Forwards":
+			cancel.add(h.name, h.value)
+		}
+	}
+	n, _ := invite.cseq()
+	cancel.add("CSeq", fmt.Sprintf("%v CANCEL", n))
+	cancel.add("User-Agent", agentName)
+	return cancel
+}
+
+// ackFailure builds the ACK for a failed INVITE, also in the INVITE's transaction
+func (ua *userAgent) ackFailure(invite, resp *message) *message {
+	ack := ua.cancelRequest(invite)
+	ack.method = "ACK"
+	ack.set("To", resp.get("To"))
+	n, _ := invite.cseq()
+	ack.set("CSeq", fmt.Sprintf("%v ACK", n))
+	return ack
+}
+
+// sendACK acknowledges the 200 OK to an INVITE, and again if the 200 OK is retransmitted
+func (ua *userAgent) sendACK(ack *message, to *net.UDPAddr) {
+	data := ack.bytes()
+	ua.mu.Lock()
+	ua.acks[ack.get("Call-ID")] = data
+	ua.mu.Unlock()
+	ua.write(data, to)
+}
+
+// forget drops what is kept for a call once it is over
+func (ua *userAgent) forget(callID string) {
+	ua.mu.Lock()
+	delete(ua.acks, callID)
+	ua.mu.Unlock()
+}
+
+// sendAuthorized sends a request in d like send, and sends it again with
+// credentials if the server challenges it
+func (ua *userAgent) sendAuthorized(ctx context.Context, d *dialog, req *message, to *net.UDPAddr, progress func(*message)) (*message, error) {
+	resp, err := ua.send(ctx, req, to, progress)
+	if err != nil || (resp.status != 401 && resp.status != 407) || ua.password == "" {
+		return resp, err
+	}
+
+	challenge, credentials := "WWW-Authenticate", "Authorization"
+	if resp.status == 407 {
+		challenge, credentials = "Proxy-Authenticate", "Proxy-Authorization"
+	}
+	auth, err := media.DigestAuthorization(resp.get(challenge), req.method, req.uri, ua.user, ua.password, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := &message{method: req.method, uri: req.uri, headers: append([]header(nil), req.headers...), body: req.body}
+	d.cseq++
+	retry.set("Via", fmt.Sprintf("SIP/2.0/UDP %v;branch=%v;rport", ua.host, newBranch()))
+	retry.set("CSeq", fmt.Sprintf("%v %v", d.cseq, req.method))
+	retry.set(credentials, auth)
+	return ua.send(ctx, retry, to, progress)
+}
+
+// register keeps the agent registered with the SIP server until ctx is
+// done, then unregisters it
+func (ua *userAgent) register(ctx context.Context, expiry time.Duration) {
+	aor := ua.aor()
+	d := &dialog{
+		callID: media.RandomToken(16),
+		local:  fmt.Sprintf("<%v>;tag=%v", aor, media.RandomToken(4)),
+		remote: "<" + aor + ">",
+	}
+	log := ua.log.With("registrar", ua.proxy)
+
+	registered := false
+	for {
+		wait := registerRetry
+		req := ua.request(d, "REGISTER", "sip:"+ua.domain, 0)
+		req.add("Expires", strconv.Itoa(int(expiry.Seconds())))
+		resp, err := ua.sendAuthorized(ctx, d, req, ua.proxy, nil)
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			log.Warnf("cannot register: %v", err)
+			registered = false
+		case resp.status != 200:
+			log.Warnf("cannot register: %v %v", resp.status, resp.reason)
+			registered = false
+		default:
+			granted := grantedExpiry(resp, expiry)
+			if !registered {
+				log.Infof("registered as %v for %v", aor, granted)
+			}
+			registered = true
+			wait = granted / 2
+		}
+
+		select {
+		case <-ctx.Done():
+			if registered {
+				ua.unregister(d)
+			}
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (ua *userAgent) unregister(d *dialog) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req := ua.request(d, "REGISTER", "sip:"+ua.domain, 0)
+	req.add("Expires", "0")
+	if _, err := ua.sendAuthorized(ctx, d, req, ua.proxy, nil); err != nil {
+		ua.log.Warnf("cannot unregister: %v", err)
+		return
+	}
+	ua.log.Infof("unregistered")
+}
+
+// grantedExpiry is how long the server keeps a registration, which it may
+// shorten from what was asked for
+func grantedExpiry(resp *message, asked time.Duration) time.Duration {
+	seconds := headerParam(resp.get("Contact"), "expires")
+	if seconds == "" {
+		seconds = resp.get("Expires")
+	}
+	if n, err := strconv.Atoi(seconds); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return asked
+}
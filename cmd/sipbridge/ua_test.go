@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/3xcellent/intercom/logger"
+	"github.com/3xcellent/intercom/media"
+	"github.com/3xcellent/intercom/proto"
+	"google.golang.org/grpc"
+)
+
+// testStream stands in for the bridge's stream to the intercom server,
+// keeping what the bridge sends
+type testStream struct {
+	grpc.ClientStream
+	sent chan *proto.Broadcast
+}
+
+func (s *testStream) Send(b *proto.Broadcast) error {
+	select {
+	case s.sent <- b:
+	default:
+	}
+	return nil
+}
+
+func (s *testStream) Recv() (*proto.Broadcast, error) {
+	select {}
+}
+
+// waitFor returns the first broadcast the bridge sends that match accepts
+func (s *testStream) waitFor(t *testing.T, what string, match func(*proto.Broadcast) bool) *proto.Broadcast {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case b := <-s.sent:
+			if match(b) {
+				return b
+			}
+		case <-timeout:
+			t.Fatalf("bridge never sent %v", what)
+		}
+	}
+}
+
+func micMuted(muted bool) func(*proto.Broadcast) bool {
+	return func(b *proto.Broadcast) bool {
+		return b.GetStatus() != nil && b.GetStatus().MicMuted == muted
+	}
+}
+
+// sipPeer is the other end of the bridge's SIP, the SIP server or a phone
+type sipPeer struct {
+	t    *testing.T
+	conn *net.UDPConn
+}
+
+func newSIPPeer(t *testing.T) *sipPeer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &sipPeer{t: t, conn: conn}
+}
+
+func (p *sipPeer) addr() *net.UDPAddr {
+	return p.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// expect reads messages until one starting with first, a method or a
+// status code, skipping the retransmissions and provisional responses before it
+func (p *sipPeer) expect(first string) (*message, *net.UDPAddr) {
+	p.t.Helper()
+	buf := make([]byte, maxMessageSize)
+	deadline := time.Now().Add(5 * time.Second)
+	p.conn.SetReadDeadline(deadline)
+	for {
+		n, from, err := p.conn.ReadFromUDP(buf)
+		if err != nil {
+			p.t.Fatalf("waiting for %v: %v", first, err)
+		}
+		m, err := parseMessage(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			p.t.Fatalf("bridge sent %q: %v", buf[:n], err)
+		}
+		if m.method == first || fmt.Sprint(m.status) == first {
+			return m, from
+		}
+	}
+}
+
+func (p *sipPeer) send(m *message, to *net.UDPAddr) {
+	if _, err := p.conn.WriteToUDP(m.bytes(), to); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// request builds a request from the peer to the bridge
+func (p *sipPeer) request(method, uri, callID string, cseq int, from, to string) *message {
+	m := &message{method: method, uri: uri}
+	m.add("Via", fmt.Sprintf("SIP/2.0/UDP %v;branch=%v", p.addr(), newBranch()))
+	m.add("From", from)
+	m.add("To", to)
+	m.add("Call-ID", callID)
+	m.add("CSeq", fmt.Sprintf("%v %v", cseq, method))
+	m.add("Contact", fmt.Sprintf("<sip:desk@%v>", p.addr()))
+	return m
+}
+
+// newTestBridge starts a bridge on a free local port, with proxy as its SIP
+// server if not nil
+func newTestBridge(t *testing.T, proxy *net.UDPAddr, ringURI string) (*bridge, *testStream) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	log := logger.New(ioutil.Discard, logger.Error, logger.Text)
+	ua := newUserAgent(conn, conn.LocalAddr().String(), proxy, log)
+	ua.user, ua.password, ua.domain = "intercom", "secret", "pbx.local"
+	stream := &testStream{sent: make(chan *proto.Broadcast, 1000)}
+	b := &bridge{
+		log:       log,
+		name:      "phone",
+		ua:        ua,
+		ringURI:   ringURI,
+		payload:   payloadPCMU,
+		mediaHost: "127.0.0.1",
+		stream:    stream,
+	}
+	ua.handler = b.handleRequest
+	go ua.serve()
+	return b, stream
+}
+
+func (b *bridge) currentCall() *call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.call
+}
+
+func md5Hex(s string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+}
+
+// digestParams reads the parameters of an Authorization header
+func digestParams(t *testing.T, auth string) map[string]string {
+	if !strings.HasPrefix(auth, "Digest ") {
+		t.Fatalf("authorization %q, want digest", auth)
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(auth[len("Digest "):], ", ") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			t.Fatalf("authorization %q", auth)
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return params
+}
+
+func TestRegister(t *testing.T) {
+	registrar := newSIPPeer(t)
+	b, _ := newTestBridge(t, registrar.addr(), "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.ua.register(ctx, time.Minute)
+		close(done)
+	}()
+
+	// the first REGISTER is challenged
+	req, from := registrar.expect("REGISTER")
+	if req.uri != "sip:pbx.local" || req.get("Expires") != "60" || req.get("Authorization") != "" {
+		t.Errorf("first REGISTER to %v for %vs with %q", req.uri, req.get("Expires"), req.get("Authorization"))
+	}
+	if to := headerURI(req.get("To")); to != "sip:intercom@pbx.local" {
+		t.Errorf("registering %v", to)
+	}
+	challenge := req.response(401, "Unauthorized")
+	challenge.add("WWW-Authenticate", `Digest realm="pbx", nonce="3bada1a0", qop="auth"`)
+	registrar.send(challenge, from)
+
+	// and sent again with credentials, in a new transaction of the same registration
+	retry, from := registrar.expect("REGISTER")
+	if retry.branch() == req.branch() || retry.get("Call-ID") != req.get("Call-ID") {
+		t.Errorf("retry in branch %v of call %v, want a new branch of %v", retry.branch(), retry.get("Call-ID"), req.get("Call-ID"))
+	}
+	first, _ := req.cseq()
+	if n, _ := retry.cseq(); n != first+1 {
+		t.Errorf("retry CSeq %v after %v", n, first)
+	}
+	auth := digestParams(t, retry.get("Authorization"))
+	ha1 := md5Hex("intercom:pbx:secret")
+	ha2 := md5Hex("REGISTER:sip:pbx.local")
+	want := md5Hex(strings.Join([]string{ha1, "3bada1a0", auth["nc"], auth["cnonce"], "auth", ha2}, ":"))
+	if auth["username"] != "intercom" || auth["uri"] != "sip:pbx.local" || auth["response"] != want {
+		t.Errorf("credentials %v, want response %v", auth, want)
+	}
+	ok := retry.response(200, "OK")
+	ok.add("Contact", retry.get("Contact")+";expires=60")
+	registrar.send(ok, from)
+
+	// stopping unregisters
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	unregister, from := registrar.expect("REGISTER")
+	if unregister.get("Expires") != "0" || unregister.get("Call-ID") != req.get("Call-ID") {
+		t.Errorf("unregistered with Expires %q in call %v", unregister.get("Expires"), unregister.get("Call-ID"))
+	}
+	registrar.send(unregister.response(200, "OK"), from)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("register never returned")
+	}
+}
+
+func TestIncomingCall(t *testing.T) {
+	phone := newSIPPeer(t)
+	b, stream := newTestBridge(t, nil, "")
+	bridgeAddr := b.ua.conn.LocalAddr().(*net.UDPAddr)
+	phoneAudio, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer phoneAudio.Close()
+
+	from := "<sip:desk@pbx.local>;tag=1928301774"
+	invite := phone.request("INVITE", "sip:intercom@"+bridgeAddr.String(), "call-1", 1, from, "<sip:intercom@pbx.local>")
+	invite.add("Content-Type", "application/sdp")
+	invite.body = buildSDP("127.0.0.1", phoneAudio.LocalAddr().(*net.UDPAddr).Port, []int{payloadPCMA, payloadPCMU})
+	phone.send(invite, bridgeAddr)
+
+	// answered straight away with the bridge's law, offered second
+	ok, _ := phone.expect("200")
+	if ok.branch() != invite.branch() || headerParam(ok.get("To"), "tag") == "" {
+		t.Errorf("answer in branch %v with To %q", ok.branch(), ok.get("To"))
+	}
+	answer, err := parseSDP(ok.body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answer.payloads) != 1 || answer.payloads[0] != payloadPCMU {
+		t.Errorf("answered with payloads %v, want PCMU", answer.payloads)
+	}
+	stream.waitFor(t, "an unmuted status", micMuted(false))
+
+	ack := phone.request("ACK", invite.uri, "call-1", 1, from, ok.get("To"))
+	phone.send(ack, bridgeAddr)
+
+	// audio flows both ways
+	buf := make([]byte, maxPacketSize)
+	phoneAudio.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := phoneAudio.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("no audio from the bridge: %v", err)
+	}
+	if packet, ok := media.ParseRTP(buf[:n]); !ok || packet.PayloadType != payloadPCMU || len(packet.Payload) != packetSamples {
+		t.Errorf("bridge sent %x", buf[:n])
+	}
+	bridgeAudio, err := net.ResolveUDPAddr("udp", answer.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet := media.Packet{PayloadType: payloadPCMA, SSRC: 1, Payload: make([]byte, packetSamples)}
+	if _, err := phoneAudio.WriteToUDP(packet.Marshal(), bridgeAudio); err != nil {
+		t.Fatal(err)
+	}
+	got := stream.waitFor(t, "the phone's audio", func(b *proto.Broadcast) bool { return b.GetAudio() != nil })
+	if a := got.GetAudio(); a.SampleRate != 8000 || len(a.Samples) != packetSamples {
+		t.Errorf("phone's audio sent as %v samples at %v Hz", len(a.Samples), a.SampleRate)
+	}
+
+	// a second call is turned away while in this one
+	other := phone.request("INVITE", invite.uri, "call-2", 1, "<sip:lobby@pbx.local>;tag=2", "<sip:intercom@pbx.local>")
+	other.body = invite.body
+	phone.send(other, bridgeAddr)
+	phone.expect("486")
+
+	// the phone hangs up
+	bye := phone.request("BYE", invite.uri, "call-1", 2, from, ok.get("To"))
+	phone.send(bye, bridgeAddr)
+	if resp, _ := phone.expect("200"); resp.branch() != bye.branch() {
+		t.Errorf("BYE answered in branch %v", resp.branch())
+	}
+	stream.waitFor(t, "a muted status", micMuted(true))
+	if c := b.currentCall(); c != nil {
+		t.Errorf("call %v still going", c.callID)
+	}
+
+	// a BYE for the call again finds no call
+	phone.send(phone.request("BYE", invite.uri, "call-1", 3, from, ok.get("To")), bridgeAddr)
+	phone.expect("481")
+}
+
+func TestRingCancelled(t *testing.T) {
+	phone := newSIPPeer(t)
+	b, _ := newTestBridge(t, nil, "sip:desk@"+phone.addr().String())
+
+	// a doorbell rings, the bridge calls the phone
+	b.handleEvent("door", &proto.Event{Type: proto.EventType_RING})
+	invite, from := phone.expect("INVITE")
+	if invite.uri != b.ringURI || headerURI(invite.get("To")) != b.ringURI {
+		t.Errorf("calling %v, To %v", invite.uri, invite.get("To"))
+	}
+	if _, err := parseSDP(invite.body); err != nil {
+		t.Errorf("offer %q: %v", invite.body, err)
+	}
+	phone.send(invite.response(100, "Trying"), from)
+	ringing := invite.response(180, "Ringing")
+	ringing.set("To", invite.get("To")+";tag=phone")
+	phone.send(ringing, from)
+
+	// and stops once the doorbell hangs up
+	b.handleEvent("door", &proto.Event{Type: proto.EventType_HANGUP, Station: "door"})
+	cancel, from := phone.expect("CANCEL")
+	n, _ := invite.cseq()
+	if m, method := cancel.cseq(); cancel.branch() != invite.branch() || m != n || method != "CANCEL" || cancel.uri != invite.uri {
+		t.Errorf("CANCEL %v in branch %v CSeq %v %v, want the INVITE's", cancel.uri, cancel.branch(), m, method)
+	}
+	phone.send(cancel.response(200, "OK"), from)
+	terminated := invite.response(487, "Request Terminated")
+	terminated.set("To", ringing.get("To"))
+	phone.send(terminated, from)
+
+	ack, _ := phone.expect("ACK")
+	if ack.branch() != invite.branch() || ack.get("To") != ringing.get("To") {
+		t.Errorf("ACK in branch %v to %v, want the INVITE's to %v", ack.branch(), ack.get("To"), ringing.get("To"))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for b.currentCall() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("call never ended")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
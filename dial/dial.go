@@ -0,0 +1,68 @@
+// Package dial holds the server address and TLS settings shared by the intercom clients.
+package dial
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"io/ioutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Config is where the server is and how to connect to it.
+type Config struct {
+	// Addr is the server's host and port
+	Addr string
+	// TLS connects over TLS, verifying the server against the system roots or CAFile
+	TLS bool
+	// CAFile is a PEM file of the certificates the server's is checked against, instead of the system roots
+	CAFile string
+	// ServerName overrides the name checked in the server's certificate, the host in Addr by default
+	ServerName string
+	// InsecureSkipVerify connects over TLS without checking the server's certificate
+	InsecureSkipVerify bool
+}
+
+// RegisterFlags adds -server and the -tls flags to fs.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "server", "localhost:6000", "intercom server address")
+	fs.BoolVar(&c.TLS, "tls", false, "connect to the server over TLS")
+	fs.StringVar(&c.CAFile, "tls-ca", "", "PEM file of CA certificates to check the server's against, instead of the system's")
+	fs.StringVar(&c.ServerName, "tls-server-name", "", "name expected in the server's certificate, the -server host by default")
+	fs.BoolVar(&c.InsecureSkipVerify, "tls-skip-verify", false, "don't check the server's certificate, for testing only")
+}
+
+// Options returns the dial options for the transport, TLS or plaintext.
+func (c Config) Options() ([]grpc.DialOption, error) {
+	if !c.TLS {
+		return []grpc.DialOption{grpc.WithInsecure()}, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("dial: no certificates in " + c.CAFile)
+		}
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(config))}, nil
+}
+
+// Dial connects to the server, opts are added to the transport's.
+func (c Config) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	transport, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(c.Addr, append(transport, opts...)...)
+}
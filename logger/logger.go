@@ -0,0 +1,198 @@
+// Package logger is the leveled, structured logger used by the intercom binaries.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is how important a message is, messages below a logger's level are dropped.
+type Level int
+
+// The levels from least to most important.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+}
+
+// Format is how each message is written.
+type Format int
+
+const (
+	// Text writes a line of the time, level, message and key=value fields.
+	Text Format = iota
+	// JSON writes a JSON object per line.
+	JSON
+)
+
+// ParseFormat parses text or json.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return Text, nil
+	case "json":
+		return JSON, nil
+	}
+	return Text, fmt.Errorf("unknown log format %q, want text or json", s)
+}
+
+// Fields are the key value pairs attached to messages.
+type Fields map[string]interface{}
+
+// Logger writes leveled messages with fields.  Loggers made by With share
+// their parent's output and settings.
+type Logger struct {
+	out    *output
+	fields Fields
+}
+
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  int32
+	format Format
+}
+
+// New creates a logger writing messages at level and above to w.
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{out: &output{w: w, level: int32(level), format: format}}
+}
+
+// Default logs info and above as text to stderr.
+func Default() *Logger {
+	return New(os.Stderr, Info, Text)
+}
+
+// With returns a logger adding key and value to every message, on top of l's fields.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	fields := make(Fields, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	return &Logger{out: l.out, fields: fields}
+}
+
+// Enabled reports whether messages at level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// Level returns the least important level written.
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(&l.out.level))
+}
+
+// SetLevel changes the least important level written, for l and every logger
+// sharing its output.
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(&l.out.level, int32(level))
+}
+
+// Debugf logs a debug message.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.log(Debug, format, args) }
+
+// Infof logs an info message.
+func (l *Logger) Infof(format string, args ...interface{}) { l.log(Info, format, args) }
+
+// Warnf logs a warning.
+func (l *Logger) Warnf(format string, args ...interface{}) { l.log(Warn, format, args) }
+
+// Errorf logs an error.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.log(Error, format, args) }
+
+func (l *Logger) log(level Level, format string, args []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+
+	var line []byte
+	if l.out.format == JSON {
+		entry := make(map[string]interface{}, len(l.fields)+3)
+		for key, value := range l.fields {
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			entry[key] = value
+		}
+		entry["time"] = now
+		entry["level"] = level.String()
+		entry["msg"] = msg
+
+		var err error
+		if line, err = json.Marshal(entry); err != nil {
+			line = []byte(fmt.Sprintf(`{"time":%q,"level":"error","msg":"cannot encode log entry: %v"}`, now, err))
+		}
+	} else {
+		keys := make([]string, 0, len(l.fields))
+		for key := range l.fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %-5s %s", now, strings.ToUpper(level.String()), msg)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=%s", key, textValue(l.fields[key]))
+		}
+		line = []byte(b.String())
+	}
+	line = append(line, '\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(line)
+}
+
+// textValue quotes values that would otherwise run into the next field
+func textValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// Parse creates a logger writing to stderr from level and format names, as given in flags.
+func Parse(level, format string) (*Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return New(os.Stderr, l, f), nil
+}
@@ -0,0 +1,95 @@
+package media
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errNoNonce = errors.New("digest challenge without a nonce")
+
+// DigestAuthorization answers a WWW-Authenticate or Proxy-Authenticate
+// challenge with RFC 2617 digest authentication, MD5 only, which is what SIP
+// servers and cameras ask for.  nc counts the requests answered with the
+// challenge's nonce, from 1.
+func DigestAuthorization(challenge, method, uri, user, password string, nc int) (string, error) {
+	return digestAuthorization(challenge, method, uri, user, password, nc, RandomToken(8))
+}
+
+// digestAuthorization is DigestAuthorization with the client nonce given
+func digestAuthorization(challenge, method, uri, user, password string, nc int, cnonce string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "digest ") {
+		return "", fmt.Errorf("unsupported authentication %q", challenge)
+	}
+	params := map[string]string{}
+	for _, param := range splitParams(challenge[len("digest "):]) {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+	}
+	algorithm := params["algorithm"]
+	if algorithm != "" && !strings.EqualFold(algorithm, "MD5") {
+		return "", fmt.Errorf("unsupported digest algorithm %v", algorithm)
+	}
+
+	realm, nonce := params["realm"], params["nonce"]
+	if nonce == "" {
+		return "", errNoNonce
+	}
+	ha1 := md5Hex(user + ":" + realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	auth := fmt.Sprintf(`Digest username="%v", realm="%v", nonce="%v", uri="%v"`, user, realm, nonce, uri)
+	if algorithm != "" {
+		auth += ", algorithm=" + algorithm
+	}
+
+	qop := ""
+	for _, q := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	if qop == "" {
+		auth += fmt.Sprintf(`, response="%v"`, md5Hex(ha1+":"+nonce+":"+ha2))
+	} else {
+		count := fmt.Sprintf("%08x", nc)
+		response := md5Hex(ha1 + ":" + nonce + ":" + count + ":" + cnonce + ":" + qop + ":" + ha2)
+		auth += fmt.Sprintf(`, response="%v", qop=%v, nc=%v, cnonce="%v"`, response, qop, count, cnonce)
+	}
+	if opaque, ok := params["opaque"]; ok {
+		auth += fmt.Sprintf(`, opaque="%v"`, opaque)
+	}
+	return auth, nil
+}
+
+// splitParams splits comma separated parameters, leaving commas in quotes alone
+func splitParams(s string) []string {
+	var params []string
+	quoted, start := false, 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(params, strings.TrimSpace(s[start:]))
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomToken returns n random bytes in hex, for nonces, tags and IDs.
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
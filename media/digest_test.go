@@ -0,0 +1,86 @@
+package media
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDigestAuthorization(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		nc        int
+		want      string
+	}{
+		{
+			// RFC 2617 section 3.5
+			name: "qop auth",
+			challenge: `Digest realm="testrealm@host.com", qop="auth,auth-int", ` +
+				`nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+			nc: 1,
+			want: `Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", ` +
+				`uri="/dir/index.html", response="6629fae49393a05397450978507c4ef1", qop=auth, nc=00000001, ` +
+				`cnonce="0a4f113b", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+		},
+		{
+			name:      "no qop",
+			challenge: `Digest realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093"`,
+			want: `Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", ` +
+				`uri="/dir/index.html", response="670fd8c2df070c60b045671b8b24ff02"`,
+		},
+		{
+			name:      "algorithm named, unquoted and any case",
+			challenge: `DIGEST nonce=abc,realm="a, b",algorithm=md5,qop=auth`,
+			nc:        0x1f,
+			want: `Digest username="Mufasa", realm="a, b", nonce="abc", uri="/dir/index.html", algorithm=md5, ` +
+				`response="40765f32ee57a0397f79c0f2bbe16f62", qop=auth, nc=0000001f, cnonce="0a4f113b"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := digestAuthorization(test.challenge, "GET", "/dir/index.html", "Mufasa", "Circle Of Life", test.nc, "0a4f113b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got\n%v\nwant\n%v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("random client nonce", func(t *testing.T) {
+		challenge := `Digest realm="r", nonce="n", qop="auth"`
+		a, _ := DigestAuthorization(challenge, "REGISTER", "sip:pbx", "u", "p", 1)
+		b, _ := DigestAuthorization(challenge, "REGISTER", "sip:pbx", "u", "p", 1)
+		if a == b || !strings.Contains(a, "cnonce=") {
+			t.Errorf("answers %q and %q, want each with its own cnonce", a, b)
+		}
+	})
+}
+
+func TestDigestAuthorizationRefused(t *testing.T) {
+	for _, challenge := range []string{
+		"",
+		"Digest",
+		"digest",
+		"Digest ",
+		`Digest realm="r"`,
+		`Basic realm="camera"`,
+		`Digestrealm="r", nonce="n"`,
+		`Digest realm="r", nonce="n", algorithm=SHA-256`,
+		`Digest nonce`,
+		`Digest "`,
+	} {
+		got, err := DigestAuthorization(challenge, "DESCRIBE", "rtsp://camera/", "admin", "secret", 1)
+		if err == nil {
+			t.Errorf("%q answered with %q, want an error", challenge, got)
+		}
+	}
+}
+
+func TestRandomToken(t *testing.T) {
+	a, b := RandomToken(8), RandomToken(8)
+	if len(a) != 16 || a == b {
+		t.Errorf("tokens %q and %q, want 16 different hex digits", a, b)
+	}
+}
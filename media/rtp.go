@@ -0,0 +1,70 @@
+// Package media holds the protocol pieces shared by the binaries that bring
+// other devices onto the intercom, SIP phones and IP cameras: RTP packets and
+// the digest authentication both SIP and RTSP servers ask for.
+package media
+
+import "encoding/binary"
+
+const (
+	// RTPVersion is the version every RTP packet carries, RFC 3550
+	RTPVersion = 2
+	// RTPHeaderSize is the fixed part of an RTP header, before any CSRCs or extension
+	RTPHeaderSize = 12
+)
+
+// Packet is an RTP packet, without the CSRCs or header extension it may carry.
+type Packet struct {
+	PayloadType int
+	Marker      bool
+	Sequence    uint16
+	Timestamp   uint32
+	SSRC        uint32
+	Payload     []byte
+}
+
+// ParseRTP reads an RTP packet, skipping its CSRCs, header extension and
+// padding.  The payload shares packet's memory.  It reports false for a packet
+// that is not RTP or is cut short.
+func ParseRTP(packet []byte) (Packet, bool) {
+	if len(packet) < RTPHeaderSize || packet[0]>>6 != RTPVersion {
+		return Packet{}, false
+	}
+	offset := RTPHeaderSize + int(packet[0]&0x0f)*4
+	if packet[0]&0x10 != 0 {
+		// header extension, its length is in 32 bit words after a 16 bit profile
+		if len(packet) < offset+4 {
+			return Packet{}, false
+		}
+		offset += 4 + int(binary.BigEndian.Uint16(packet[offset+2:]))*4
+	}
+	end := len(packet)
+	if packet[0]&0x20 != 0 {
+		// padding, the last byte counts it
+		end -= int(packet[end-1])
+	}
+	if offset > end {
+		return Packet{}, false
+	}
+	return Packet{
+		PayloadType: int(packet[1] & 0x7f),
+		Marker:      packet[1]&0x80 != 0,
+		Sequence:    binary.BigEndian.Uint16(packet[2:]),
+		Timestamp:   binary.BigEndian.Uint32(packet[4:]),
+		SSRC:        binary.BigEndian.Uint32(packet[8:]),
+		Payload:     packet[offset:end],
+	}, true
+}
+
+// Marshal returns the packet as sent, with a plain header.
+func (p Packet) Marshal() []byte {
+	packet := make([]byte, RTPHeaderSize, RTPHeaderSize+len(p.Payload))
+	packet[0] = RTPVersion << 6
+	packet[1] = byte(p.PayloadType & 0x7f)
+	if p.Marker {
+		packet[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(packet[2:], p.Sequence)
+	binary.BigEndian.PutUint32(packet[4:], p.Timestamp)
+	binary.BigEndian.PutUint32(packet[8:], p.SSRC)
+	return append(packet, p.Payload...)
+}
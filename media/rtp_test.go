@@ -0,0 +1,83 @@
+package media
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRTPRoundTrip(t *testing.T) {
+	want := Packet{PayloadType: 96, Marker: true, Sequence: 65535, Timestamp: 0xdeadbeef, SSRC: 42, Payload: []byte("jpeg")}
+	got, ok := ParseRTP(want.Marshal())
+	if !ok {
+		t.Fatal("marshalled packet did not parse")
+	}
+	if got.PayloadType != want.PayloadType || got.Marker != want.Marker || got.Sequence != want.Sequence ||
+		got.Timestamp != want.Timestamp || got.SSRC != want.SSRC || !bytes.Equal(got.Payload, want.Payload) {
+		t.Errorf("parsed %+v, want %+v", got, want)
+	}
+}
+
+func TestParseRTP(t *testing.T) {
+	header := func(first byte) []byte {
+		return []byte{first, 0x80 | 26, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3}
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := []struct {
+		name    string
+		packet  []byte
+		ok      bool
+		payload string
+	}{
+		{"plain", join(header(0x80), []byte("data")), true, "data"},
+		{"no payload", header(0x80), true, ""},
+		{"two CSRCs", join(header(0x82), make([]byte, 8), []byte("data")), true, "data"},
+		{"header extension", join(header(0x90), []byte{0xbe, 0xde, 0, 1}, make([]byte, 4), []byte("data")), true, "data"},
+		{"padding", join(header(0xa0), []byte("data"), []byte{0, 0, 3}), true, "data"},
+		{"CSRC and extension and padding", join(header(0xb1), make([]byte, 4), []byte{0, 0, 0, 0}, []byte("data"), []byte{2}), true, "dat"},
+		{"too short", header(0x80)[:11], false, ""},
+		{"empty", nil, false, ""},
+		{"version 1", join(header(0x40), []byte("data")), false, ""},
+		{"CSRCs cut short", join(header(0x83), make([]byte, 8)), false, ""},
+		{"extension header cut short", join(header(0x90), []byte{0xbe, 0xde}), false, ""},
+		{"extension cut short", join(header(0x90), []byte{0xbe, 0xde, 0, 2}, make([]byte, 4)), false, ""},
+		{"padding longer than the packet", join(header(0xa0), []byte{200}), false, ""},
+		{"padding into the header", join(header(0xa0), []byte("data"), []byte{6}), false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packet, ok := ParseRTP(test.packet)
+			if ok != test.ok {
+				t.Fatalf("parsed %v, want %v", ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if string(packet.Payload) != test.payload {
+				t.Errorf("payload %q, want %q", packet.Payload, test.payload)
+			}
+			if packet.PayloadType != 26 || !packet.Marker || packet.Sequence != 1 || packet.Timestamp != 2 || packet.SSRC != 3 {
+				t.Errorf("header read as %+v", packet)
+			}
+		})
+	}
+}
+
+func TestParseRTPNoPanic(t *testing.T) {
+	// every header flag combination over every length up to a full packet
+	for first := 0; first < 256; first++ {
+		full := append([]byte{byte(first), 0}, bytes.Repeat([]byte{0xff}, 40)...)
+		for n := 0; n <= len(full); n++ {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("panic parsing %x: %v", full[:n], r)
+					}
+				}()
+				ParseRTP(full[:n])
+			}()
+		}
+	}
+}
@@ -24,11 +24,63 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type EventType int32
+
+const (
+	EventType_UNKNOWN EventType = 0
+	// the station's camera saw motion in its watched region
+	EventType_MOTION EventType = 1
+	// the doorbell station was rung
+	EventType_RING EventType = 2
+	// a station answered the doorbell named in station, opening two-way audio with it
+	EventType_ANSWER EventType = 3
+	// a station ended its call with the doorbell named in station
+	EventType_HANGUP EventType = 4
+	// a message from the server's operator, in text
+	EventType_ANNOUNCEMENT EventType = 5
+)
+
+var EventType_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "MOTION",
+	2: "RING",
+	3: "ANSWER",
+	4: "HANGUP",
+	5: "ANNOUNCEMENT",
+}
+
+var EventType_value = map[string]int32{
+	"UNKNOWN":      0,
+	"MOTION":       1,
+	"RING":         2,
+	"ANSWER":       3,
+	"HANGUP":       4,
+	"ANNOUNCEMENT": 5,
+}
+
+func (x EventType) String() string {
+	return proto.EnumName(EventType_name, int32(x))
+}
+
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{0}
+}
+
 type Broadcast struct {
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// room is the room a station joins, or moves to once joined.  Stations
+	// only hear and see the others in their room, empty keeps the station
+	// where it is, or joins the room named in the stream's "room" metadata
+	// or the server's default room
+	Room string `protobuf:"bytes,9,opt,name=room,proto3" json:"room,omitempty"`
 	// Types that are valid to be assigned to BroadcastType:
 	//	*Broadcast_Image
 	//	*Broadcast_Audio
+	//	*Broadcast_Status
+	//	*Broadcast_Roster
+	//	*Broadcast_Bitrate
+	//	*Broadcast_KeyframeRequest
+	//	*Broadcast_Event
 	BroadcastType        isBroadcast_BroadcastType `protobuf_oneof:"broadcast_type"`
 	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
 	XXX_unrecognized     []byte                    `json:"-"`
@@ -67,6 +119,13 @@ func (m *Broadcast) GetName() string {
 	return ""
 }
 
+func (m *Broadcast) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
 type isBroadcast_BroadcastType interface {
 	isBroadcast_BroadcastType()
 }
@@ -79,10 +138,40 @@ type Broadcast_Audio struct {
 	Audio *Audio `protobuf:"bytes,3,opt,name=audio,proto3,oneof"`
 }
 
+type Broadcast_Status struct {
+	Status *Status `protobuf:"bytes,4,opt,name=status,proto3,oneof"`
+}
+
+type Broadcast_Roster struct {
+	Roster *Roster `protobuf:"bytes,5,opt,name=roster,proto3,oneof"`
+}
+
+type Broadcast_Bitrate struct {
+	Bitrate *BitrateHint `protobuf:"bytes,6,opt,name=bitrate,proto3,oneof"`
+}
+
+type Broadcast_KeyframeRequest struct {
+	KeyframeRequest *KeyframeRequest `protobuf:"bytes,7,opt,name=keyframeRequest,proto3,oneof"`
+}
+
+type Broadcast_Event struct {
+	Event *Event `protobuf:"bytes,8,opt,name=event,proto3,oneof"`
+}
+
 func (*Broadcast_Image) isBroadcast_BroadcastType() {}
 
 func (*Broadcast_Audio) isBroadcast_BroadcastType() {}
 
+func (*Broadcast_Status) isBroadcast_BroadcastType() {}
+
+func (*Broadcast_Roster) isBroadcast_BroadcastType() {}
+
+func (*Broadcast_Bitrate) isBroadcast_BroadcastType() {}
+
+func (*Broadcast_KeyframeRequest) isBroadcast_BroadcastType() {}
+
+func (*Broadcast_Event) isBroadcast_BroadcastType() {}
+
 func (m *Broadcast) GetBroadcastType() isBroadcast_BroadcastType {
 	if m != nil {
 		return m.BroadcastType
@@ -104,19 +193,65 @@ func (m *Broadcast) GetAudio() *Audio {
 	return nil
 }
 
+func (m *Broadcast) GetStatus() *Status {
+	if x, ok := m.GetBroadcastType().(*Broadcast_Status); ok {
+		return x.Status
+	}
+	return nil
+}
+
+func (m *Broadcast) GetRoster() *Roster {
+	if x, ok := m.GetBroadcastType().(*Broadcast_Roster); ok {
+		return x.Roster
+	}
+	return nil
+}
+
+func (m *Broadcast) GetBitrate() *BitrateHint {
+	if x, ok := m.GetBroadcastType().(*Broadcast_Bitrate); ok {
+		return x.Bitrate
+	}
+	return nil
+}
+
+func (m *Broadcast) GetKeyframeRequest() *KeyframeRequest {
+	if x, ok := m.GetBroadcastType().(*Broadcast_KeyframeRequest); ok {
+		return x.KeyframeRequest
+	}
+	return nil
+}
+
+func (m *Broadcast) GetEvent() *Event {
+	if x, ok := m.GetBroadcastType().(*Broadcast_Event); ok {
+		return x.Event
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*Broadcast) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*Broadcast_Image)(nil),
 		(*Broadcast_Audio)(nil),
+		(*Broadcast_Status)(nil),
+		(*Broadcast_Roster)(nil),
+		(*Broadcast_Bitrate)(nil),
+		(*Broadcast_KeyframeRequest)(nil),
+		(*Broadcast_Event)(nil),
 	}
 }
 
 type Image struct {
-	Height               int32    `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
-	Width                int32    `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
-	Type                 int32    `protobuf:"varint,3,opt,name=type,proto3" json:"type,omitempty"`
-	Bytes                []byte   `protobuf:"bytes,4,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Height int32 `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	Width  int32 `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Type   int32 `protobuf:"varint,3,opt,name=type,proto3" json:"type,omitempty"`
+	// raw pixels of the whole frame, empty when the frame is sent as tiles
+	Bytes []byte `protobuf:"bytes,4,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// width and height of the JPEG tiles, 0 when the frame is sent raw
+	TileSize int32 `protobuf:"varint,5,opt,name=tileSize,proto3" json:"tileSize,omitempty"`
+	// set when tiles covers the whole frame, otherwise they are only the tiles that changed
+	Keyframe             bool     `protobuf:"varint,6,opt,name=keyframe,proto3" json:"keyframe,omitempty"`
+	Tiles                []*Tile  `protobuf:"bytes,7,rep,name=tiles,proto3" json:"tiles,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -175,10 +310,92 @@ func (m *Image) GetBytes() []byte {
 	return nil
 }
 
+func (m *Image) GetTileSize() int32 {
+	if m != nil {
+		return m.TileSize
+	}
+	return 0
+}
+
+func (m *Image) GetKeyframe() bool {
+	if m != nil {
+		return m.Keyframe
+	}
+	return false
+}
+
+func (m *Image) GetTiles() []*Tile {
+	if m != nil {
+		return m.Tiles
+	}
+	return nil
+}
+
+type Tile struct {
+	// column and row of the tile in the frame
+	X                    int32    `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y                    int32    `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Jpeg                 []byte   `protobuf:"bytes,3,opt,name=jpeg,proto3" json:"jpeg,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Tile) Reset()         { *m = Tile{} }
+func (m *Tile) String() string { return proto.CompactTextString(m) }
+func (*Tile) ProtoMessage()    {}
+func (*Tile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{2}
+}
+
+func (m *Tile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Tile.Unmarshal(m, b)
+}
+func (m *Tile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Tile.Marshal(b, m, deterministic)
+}
+func (m *Tile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Tile.Merge(m, src)
+}
+func (m *Tile) XXX_Size() int {
+	return xxx_messageInfo_Tile.Size(m)
+}
+func (m *Tile) XXX_DiscardUnknown() {
+	xxx_messageInfo_Tile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Tile proto.InternalMessageInfo
+
+func (m *Tile) GetX() int32 {
+	if m != nil {
+		return m.X
+	}
+	return 0
+}
+
+func (m *Tile) GetY() int32 {
+	if m != nil {
+		return m.Y
+	}
+	return 0
+}
+
+func (m *Tile) GetJpeg() []byte {
+	if m != nil {
+		return m.Jpeg
+	}
+	return nil
+}
+
 type Audio struct {
-	SampleRate           int32    `protobuf:"varint,1,opt,name=sampleRate,proto3" json:"sampleRate,omitempty"`
-	Length               int32    `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
-	Samples              []int32  `protobuf:"varint,3,rep,packed,name=samples,proto3" json:"samples,omitempty"`
+	SampleRate int32   `protobuf:"varint,1,opt,name=sampleRate,proto3" json:"sampleRate,omitempty"`
+	Length     int32   `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	Samples    []int32 `protobuf:"varint,3,rep,packed,name=samples,proto3" json:"samples,omitempty"`
+	// channels in samples, interleaved. 0 is treated as mono
+	Channels int32 `protobuf:"varint,4,opt,name=channels,proto3" json:"channels,omitempty"`
+	// RMS and peak of samples relative to full scale, 0 to 1
+	Level                float32  `protobuf:"fixed32,5,opt,name=level,proto3" json:"level,omitempty"`
+	Peak                 float32  `protobuf:"fixed32,6,opt,name=peak,proto3" json:"peak,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -188,7 +405,7 @@ func (m *Audio) Reset()         { *m = Audio{} }
 func (m *Audio) String() string { return proto.CompactTextString(m) }
 func (*Audio) ProtoMessage()    {}
 func (*Audio) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4b7dc4dbe05ff714, []int{2}
+	return fileDescriptor_4b7dc4dbe05ff714, []int{3}
 }
 
 func (m *Audio) XXX_Unmarshal(b []byte) error {
@@ -230,145 +447,1932 @@ func (m *Audio) GetSamples() []int32 {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*Broadcast)(nil), "Broadcast")
-	proto.RegisterType((*Image)(nil), "Image")
-	proto.RegisterType((*Audio)(nil), "Audio")
+func (m *Audio) GetChannels() int32 {
+	if m != nil {
+		return m.Channels
+	}
+	return 0
 }
 
-func init() { proto.RegisterFile("intercom.proto", fileDescriptor_4b7dc4dbe05ff714) }
+func (m *Audio) GetLevel() float32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
 
-var fileDescriptor_4b7dc4dbe05ff714 = []byte{
-	// 265 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0xc1, 0x6a, 0x83, 0x40,
-	0x10, 0x86, 0xb3, 0x35, 0xab, 0xcd, 0xb4, 0x84, 0xb2, 0x94, 0xb2, 0xf4, 0x10, 0xc4, 0x4b, 0x3d,
-	0x49, 0x49, 0x9e, 0xa0, 0xf6, 0xd2, 0x5c, 0xf7, 0xd6, 0x5e, 0xc2, 0xaa, 0x83, 0x0a, 0xd1, 0xb5,
-	0xba, 0xa5, 0xe4, 0xed, 0xc3, 0x8e, 0x9b, 0x90, 0x93, 0xf3, 0xcd, 0x8c, 0xfb, 0x0d, 0x3f, 0xac,
-	0xdb, 0xde, 0xe2, 0x58, 0x9a, 0x2e, 0x1b, 0x46, 0x63, 0x4d, 0xf2, 0x0b, 0xab, 0x7c, 0x34, 0xba,
-	0x2a, 0xf5, 0x64, 0x85, 0x80, 0x65, 0xaf, 0x3b, 0x94, 0x2c, 0x66, 0xe9, 0x4a, 0x51, 0x2d, 0x36,
-	0xc0, 0xdb, 0x4e, 0xd7, 0x28, 0xef, 0x62, 0x96, 0x3e, 0x6c, 0xc3, 0x6c, 0xef, 0xe8, 0x6b, 0xa1,
-	0xe6, 0xb6, 0x9b, 0xeb, 0xbf, 0xaa, 0x35, 0x32, 0xf0, 0xf3, 0x0f, 0x47, 0x6e, 0x4e, 0xed, 0xfc,
-	0x09, 0xd6, 0xc5, 0x45, 0x70, 0xb0, 0xa7, 0x01, 0x93, 0x03, 0x70, 0x7a, 0x43, 0xbc, 0x40, 0xd8,
-	0x60, 0x5b, 0x37, 0x96, 0x84, 0x5c, 0x79, 0x12, 0xcf, 0xc0, 0xff, 0xdb, 0xca, 0x36, 0xa4, 0xe4,
-	0x6a, 0x06, 0x77, 0x9c, 0xfb, 0x9d, 0x3c, 0x5c, 0x51, 0xed, 0x36, 0x8b, 0x93, 0xc5, 0x49, 0x2e,
-	0x63, 0x96, 0x3e, 0xaa, 0x19, 0x92, 0x6f, 0xe0, 0x74, 0x84, 0xd8, 0x00, 0x4c, 0xba, 0x1b, 0x8e,
-	0xa8, 0xb4, 0x45, 0x2f, 0xb9, 0xe9, 0xb8, 0x03, 0x8e, 0xd8, 0xd7, 0x57, 0x93, 0x27, 0x21, 0x21,
-	0x9a, 0xb7, 0x26, 0x19, 0xc4, 0x41, 0xca, 0xd5, 0x05, 0xb7, 0x3b, 0xb8, 0xdf, 0xfb, 0x00, 0xc5,
-	0x1b, 0x44, 0x9f, 0xa6, 0xef, 0xb1, 0xb4, 0x02, 0xb2, 0x6b, 0x88, 0xaf, 0x37, 0x75, 0xb2, 0x48,
-	0xd9, 0x3b, 0xcb, 0xa3, 0x1f, 0x4e, 0x61, 0x17, 0x21, 0x7d, 0x76, 0xe7, 0x00, 0x00, 0x00, 0xff,
-	0xff, 0x2e, 0xd7, 0xc7, 0xdb, 0x85, 0x01, 0x00, 0x00,
+func (m *Audio) GetPeak() float32 {
+	if m != nil {
+		return m.Peak
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+type Status struct {
+	MicMuted  bool `protobuf:"varint,1,opt,name=micMuted,proto3" json:"micMuted,omitempty"`
+	CameraOff bool `protobuf:"varint,2,opt,name=cameraOff,proto3" json:"cameraOff,omitempty"`
+	// privacy mode has the mic and camera closed
+	Privacy              bool     `protobuf:"varint,3,opt,name=privacy,proto3" json:"privacy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *Status) Reset()         { *m = Status{} }
+func (m *Status) String() string { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()    {}
+func (*Status) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{4}
+}
 
-// IntercomClient is the client API for Intercom service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type IntercomClient interface {
-	// Connect will hold the connection to the server
-	Connect(ctx context.Context, opts ...grpc.CallOption) (Intercom_ConnectClient, error)
+func (m *Status) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Status.Unmarshal(m, b)
+}
+func (m *Status) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Status.Marshal(b, m, deterministic)
+}
+func (m *Status) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Status.Merge(m, src)
+}
+func (m *Status) XXX_Size() int {
+	return xxx_messageInfo_Status.Size(m)
+}
+func (m *Status) XXX_DiscardUnknown() {
+	xxx_messageInfo_Status.DiscardUnknown(m)
 }
 
-type intercomClient struct {
-	cc *grpc.ClientConn
+var xxx_messageInfo_Status proto.InternalMessageInfo
+
+func (m *Status) GetMicMuted() bool {
+	if m != nil {
+		return m.MicMuted
+	}
+	return false
 }
 
-func NewIntercomClient(cc *grpc.ClientConn) IntercomClient {
-	return &intercomClient{cc}
+func (m *Status) GetCameraOff() bool {
+	if m != nil {
+		return m.CameraOff
+	}
+	return false
 }
 
-func (c *intercomClient) Connect(ctx context.Context, opts ...grpc.CallOption) (Intercom_ConnectClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Intercom_serviceDesc.Streams[0], "/Intercom/Connect", opts...)
-	if err != nil {
-		return nil, err
+func (m *Status) GetPrivacy() bool {
+	if m != nil {
+		return m.Privacy
 	}
-	x := &intercomConnectClient{stream}
-	return x, nil
+	return false
 }
 
-type Intercom_ConnectClient interface {
-	Send(*Broadcast) error
-	Recv() (*Broadcast, error)
-	grpc.ClientStream
+type Station struct {
+	Name   string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Status *Status `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// RMS and peak of the station's latest audio relative to full scale, 0 when not talking
+	Level                float32  `protobuf:"fixed32,3,opt,name=level,proto3" json:"level,omitempty"`
+	Peak                 float32  `protobuf:"fixed32,4,opt,name=peak,proto3" json:"peak,omitempty"`
+	Room                 string   `protobuf:"bytes,5,opt,name=room,proto3" json:"room,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type intercomConnectClient struct {
-	grpc.ClientStream
+func (m *Station) Reset()         { *m = Station{} }
+func (m *Station) String() string { return proto.CompactTextString(m) }
+func (*Station) ProtoMessage()    {}
+func (*Station) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{5}
 }
 
-func (x *intercomConnectClient) Send(m *Broadcast) error {
-	return x.ClientStream.SendMsg(m)
+func (m *Station) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Station.Unmarshal(m, b)
+}
+func (m *Station) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Station.Marshal(b, m, deterministic)
+}
+func (m *Station) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Station.Merge(m, src)
+}
+func (m *Station) XXX_Size() int {
+	return xxx_messageInfo_Station.Size(m)
+}
+func (m *Station) XXX_DiscardUnknown() {
+	xxx_messageInfo_Station.DiscardUnknown(m)
 }
 
-func (x *intercomConnectClient) Recv() (*Broadcast, error) {
-	m := new(Broadcast)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+var xxx_messageInfo_Station proto.InternalMessageInfo
+
+func (m *Station) GetName() string {
+	if m != nil {
+		return m.Name
 	}
-	return m, nil
+	return ""
 }
 
-// IntercomServer is the server API for Intercom service.
-type IntercomServer interface {
-	// Connect will hold the connection to the server
-	Connect(Intercom_ConnectServer) error
+func (m *Station) GetStatus() *Status {
+	if m != nil {
+		return m.Status
+	}
+	return nil
 }
 
-// UnimplementedIntercomServer can be embedded to have forward compatible implementations.
-type UnimplementedIntercomServer struct {
+func (m *Station) GetLevel() float32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
 }
 
-func (*UnimplementedIntercomServer) Connect(srv Intercom_ConnectServer) error {
-	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+func (m *Station) GetPeak() float32 {
+	if m != nil {
+		return m.Peak
+	}
+	return 0
 }
 
-func RegisterIntercomServer(s *grpc.Server, srv IntercomServer) {
-	s.RegisterService(&_Intercom_serviceDesc, srv)
+func (m *Station) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
 }
 
-func _Intercom_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(IntercomServer).Connect(&intercomConnectServer{stream})
+type Roster struct {
+	// stations are those in room, the room of the station the roster is sent to
+	Stations             []*Station `protobuf:"bytes,1,rep,name=stations,proto3" json:"stations,omitempty"`
+	Room                 string     `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
-type Intercom_ConnectServer interface {
-	Send(*Broadcast) error
-	Recv() (*Broadcast, error)
-	grpc.ServerStream
+func (m *Roster) Reset()         { *m = Roster{} }
+func (m *Roster) String() string { return proto.CompactTextString(m) }
+func (*Roster) ProtoMessage()    {}
+func (*Roster) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{6}
 }
 
-type intercomConnectServer struct {
-	grpc.ServerStream
+func (m *Roster) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Roster.Unmarshal(m, b)
+}
+func (m *Roster) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Roster.Marshal(b, m, deterministic)
+}
+func (m *Roster) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Roster.Merge(m, src)
+}
+func (m *Roster) XXX_Size() int {
+	return xxx_messageInfo_Roster.Size(m)
+}
+func (m *Roster) XXX_DiscardUnknown() {
+	xxx_messageInfo_Roster.DiscardUnknown(m)
 }
 
-func (x *intercomConnectServer) Send(m *Broadcast) error {
-	return x.ServerStream.SendMsg(m)
+var xxx_messageInfo_Roster proto.InternalMessageInfo
+
+func (m *Roster) GetStations() []*Station {
+	if m != nil {
+		return m.Stations
+	}
+	return nil
 }
 
-func (x *intercomConnectServer) Recv() (*Broadcast, error) {
-	m := new(Broadcast)
-	if err := x.ServerStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *Roster) GetRoom() string {
+	if m != nil {
+		return m.Room
 	}
-	return m, nil
+	return ""
 }
 
-var _Intercom_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "Intercom",
-	HandlerType: (*IntercomServer)(nil),
-	Methods:     []grpc.MethodDesc{},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "Connect",
-			Handler:       _Intercom_Connect_Handler,
-			ServerStreams: true,
-			ClientStreams: true,
+type BitrateHint struct {
+	// maxVideoBitrate is the most bits per second of video to send, 0 lifts the limit
+	MaxVideoBitrate      int32    `protobuf:"varint,1,opt,name=maxVideoBitrate,proto3" json:"maxVideoBitrate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BitrateHint) Reset()         { *m = BitrateHint{} }
+func (m *BitrateHint) String() string { return proto.CompactTextString(m) }
+func (*BitrateHint) ProtoMessage()    {}
+func (*BitrateHint) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{7}
+}
+
+func (m *BitrateHint) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BitrateHint.Unmarshal(m, b)
+}
+func (m *BitrateHint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BitrateHint.Marshal(b, m, deterministic)
+}
+func (m *BitrateHint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BitrateHint.Merge(m, src)
+}
+func (m *BitrateHint) XXX_Size() int {
+	return xxx_messageInfo_BitrateHint.Size(m)
+}
+func (m *BitrateHint) XXX_DiscardUnknown() {
+	xxx_messageInfo_BitrateHint.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BitrateHint proto.InternalMessageInfo
+
+func (m *BitrateHint) GetMaxVideoBitrate() int32 {
+	if m != nil {
+		return m.MaxVideoBitrate
+	}
+	return 0
+}
+
+type KeyframeRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyframeRequest) Reset()         { *m = KeyframeRequest{} }
+func (m *KeyframeRequest) String() string { return proto.CompactTextString(m) }
+func (*KeyframeRequest) ProtoMessage()    {}
+func (*KeyframeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{8}
+}
+
+func (m *KeyframeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyframeRequest.Unmarshal(m, b)
+}
+func (m *KeyframeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyframeRequest.Marshal(b, m, deterministic)
+}
+func (m *KeyframeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyframeRequest.Merge(m, src)
+}
+func (m *KeyframeRequest) XXX_Size() int {
+	return xxx_messageInfo_KeyframeRequest.Size(m)
+}
+func (m *KeyframeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyframeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyframeRequest proto.InternalMessageInfo
+
+type Event struct {
+	Type EventType `protobuf:"varint,1,opt,name=type,proto3,enum=EventType" json:"type,omitempty"`
+	// how much of the watched region moved for MOTION, 0 to 1
+	Level float32 `protobuf:"fixed32,2,opt,name=level,proto3" json:"level,omitempty"`
+	// the doorbell station an ANSWER or HANGUP is for
+	Station string `protobuf:"bytes,3,opt,name=station,proto3" json:"station,omitempty"`
+	// the message of an ANNOUNCEMENT
+	Text                 string   `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{9}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetType() EventType {
+	if m != nil {
+		return m.Type
+	}
+	return EventType_UNKNOWN
+}
+
+func (m *Event) GetLevel() float32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *Event) GetStation() string {
+	if m != nil {
+		return m.Station
+	}
+	return ""
+}
+
+func (m *Event) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+type ListStreamsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListStreamsRequest) Reset()         { *m = ListStreamsRequest{} }
+func (m *ListStreamsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListStreamsRequest) ProtoMessage()    {}
+func (*ListStreamsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{10}
+}
+
+func (m *ListStreamsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStreamsRequest.Unmarshal(m, b)
+}
+func (m *ListStreamsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStreamsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListStreamsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStreamsRequest.Merge(m, src)
+}
+func (m *ListStreamsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListStreamsRequest.Size(m)
+}
+func (m *ListStreamsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStreamsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStreamsRequest proto.InternalMessageInfo
+
+type StreamList struct {
+	Streams              []*StreamInfo `protobuf:"bytes,1,rep,name=streams,proto3" json:"streams,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *StreamList) Reset()         { *m = StreamList{} }
+func (m *StreamList) String() string { return proto.CompactTextString(m) }
+func (*StreamList) ProtoMessage()    {}
+func (*StreamList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{11}
+}
+
+func (m *StreamList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamList.Unmarshal(m, b)
+}
+func (m *StreamList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamList.Marshal(b, m, deterministic)
+}
+func (m *StreamList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamList.Merge(m, src)
+}
+func (m *StreamList) XXX_Size() int {
+	return xxx_messageInfo_StreamList.Size(m)
+}
+func (m *StreamList) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamList proto.InternalMessageInfo
+
+func (m *StreamList) GetStreams() []*StreamInfo {
+	if m != nil {
+		return m.Streams
+	}
+	return nil
+}
+
+type StreamInfo struct {
+	// id numbers streams in the order they connected, as in the server's logs
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// station is empty until the stream's first message names it
+	Station string `protobuf:"bytes,2,opt,name=station,proto3" json:"station,omitempty"`
+	Peer    string `protobuf:"bytes,3,opt,name=peer,proto3" json:"peer,omitempty"`
+	// connectedAt is when the stream connected, in Unix seconds
+	ConnectedAt int64   `protobuf:"varint,4,opt,name=connectedAt,proto3" json:"connectedAt,omitempty"`
+	Status      *Status `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	// forceMuted is set when the station's audio is dropped by Mute
+	ForceMuted bool `protobuf:"varint,6,opt,name=forceMuted,proto3" json:"forceMuted,omitempty"`
+	// queued is how many messages are waiting to be sent to the stream
+	Queued int32 `protobuf:"varint,7,opt,name=queued,proto3" json:"queued,omitempty"`
+	// room is empty until the stream's station joins one
+	Room                 string   `protobuf:"bytes,8,opt,name=room,proto3" json:"room,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamInfo) Reset()         { *m = StreamInfo{} }
+func (m *StreamInfo) String() string { return proto.CompactTextString(m) }
+func (*StreamInfo) ProtoMessage()    {}
+func (*StreamInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{12}
+}
+
+func (m *StreamInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamInfo.Unmarshal(m, b)
+}
+func (m *StreamInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamInfo.Marshal(b, m, deterministic)
+}
+func (m *StreamInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamInfo.Merge(m, src)
+}
+func (m *StreamInfo) XXX_Size() int {
+	return xxx_messageInfo_StreamInfo.Size(m)
+}
+func (m *StreamInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamInfo proto.InternalMessageInfo
+
+func (m *StreamInfo) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *StreamInfo) GetStation() string {
+	if m != nil {
+		return m.Station
+	}
+	return ""
+}
+
+func (m *StreamInfo) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *StreamInfo) GetConnectedAt() int64 {
+	if m != nil {
+		return m.ConnectedAt
+	}
+	return 0
+}
+
+func (m *StreamInfo) GetStatus() *Status {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+func (m *StreamInfo) GetForceMuted() bool {
+	if m != nil {
+		return m.ForceMuted
+	}
+	return false
+}
+
+func (m *StreamInfo) GetQueued() int32 {
+	if m != nil {
+		return m.Queued
+	}
+	return 0
+}
+
+func (m *StreamInfo) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+type StationRequest struct {
+	Station              string   `protobuf:"bytes,1,opt,name=station,proto3" json:"station,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StationRequest) Reset()         { *m = StationRequest{} }
+func (m *StationRequest) String() string { return proto.CompactTextString(m) }
+func (*StationRequest) ProtoMessage()    {}
+func (*StationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{13}
+}
+
+func (m *StationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StationRequest.Unmarshal(m, b)
+}
+func (m *StationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StationRequest.Marshal(b, m, deterministic)
+}
+func (m *StationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StationRequest.Merge(m, src)
+}
+func (m *StationRequest) XXX_Size() int {
+	return xxx_messageInfo_StationRequest.Size(m)
+}
+func (m *StationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StationRequest proto.InternalMessageInfo
+
+func (m *StationRequest) GetStation() string {
+	if m != nil {
+		return m.Station
+	}
+	return ""
+}
+
+type MuteRequest struct {
+	Station string `protobuf:"bytes,1,opt,name=station,proto3" json:"station,omitempty"`
+	// muted false lifts a previous Mute
+	Muted                bool     `protobuf:"varint,2,opt,name=muted,proto3" json:"muted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MuteRequest) Reset()         { *m = MuteRequest{} }
+func (m *MuteRequest) String() string { return proto.CompactTextString(m) }
+func (*MuteRequest) ProtoMessage()    {}
+func (*MuteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{14}
+}
+
+func (m *MuteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MuteRequest.Unmarshal(m, b)
+}
+func (m *MuteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MuteRequest.Marshal(b, m, deterministic)
+}
+func (m *MuteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MuteRequest.Merge(m, src)
+}
+func (m *MuteRequest) XXX_Size() int {
+	return xxx_messageInfo_MuteRequest.Size(m)
+}
+func (m *MuteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MuteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MuteRequest proto.InternalMessageInfo
+
+func (m *MuteRequest) GetStation() string {
+	if m != nil {
+		return m.Station
+	}
+	return ""
+}
+
+func (m *MuteRequest) GetMuted() bool {
+	if m != nil {
+		return m.Muted
+	}
+	return false
+}
+
+type Announcement struct {
+	Text                 string   `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Announcement) Reset()         { *m = Announcement{} }
+func (m *Announcement) String() string { return proto.CompactTextString(m) }
+func (*Announcement) ProtoMessage()    {}
+func (*Announcement) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{15}
+}
+
+func (m *Announcement) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Announcement.Unmarshal(m, b)
+}
+func (m *Announcement) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Announcement.Marshal(b, m, deterministic)
+}
+func (m *Announcement) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Announcement.Merge(m, src)
+}
+func (m *Announcement) XXX_Size() int {
+	return xxx_messageInfo_Announcement.Size(m)
+}
+func (m *Announcement) XXX_DiscardUnknown() {
+	xxx_messageInfo_Announcement.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Announcement proto.InternalMessageInfo
+
+func (m *Announcement) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+type AdminReply struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AdminReply) Reset()         { *m = AdminReply{} }
+func (m *AdminReply) String() string { return proto.CompactTextString(m) }
+func (*AdminReply) ProtoMessage()    {}
+func (*AdminReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{16}
+}
+
+func (m *AdminReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AdminReply.Unmarshal(m, b)
+}
+func (m *AdminReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AdminReply.Marshal(b, m, deterministic)
+}
+func (m *AdminReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AdminReply.Merge(m, src)
+}
+func (m *AdminReply) XXX_Size() int {
+	return xxx_messageInfo_AdminReply.Size(m)
+}
+func (m *AdminReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_AdminReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AdminReply proto.InternalMessageInfo
+
+type GetSettingsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSettingsRequest) Reset()         { *m = GetSettingsRequest{} }
+func (m *GetSettingsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSettingsRequest) ProtoMessage()    {}
+func (*GetSettingsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{17}
+}
+
+func (m *GetSettingsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSettingsRequest.Unmarshal(m, b)
+}
+func (m *GetSettingsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSettingsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetSettingsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSettingsRequest.Merge(m, src)
+}
+func (m *GetSettingsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSettingsRequest.Size(m)
+}
+func (m *GetSettingsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSettingsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSettingsRequest proto.InternalMessageInfo
+
+type Settings struct {
+	// frameRate is the most video frames per second sent to each station, 0 leaves it unchanged
+	FrameRate int32 `protobuf:"varint,1,opt,name=frameRate,proto3" json:"frameRate,omitempty"`
+	// logLevel is debug, info, warn or error, empty leaves it unchanged
+	LogLevel             string   `protobuf:"bytes,2,opt,name=logLevel,proto3" json:"logLevel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Settings) Reset()         { *m = Settings{} }
+func (m *Settings) String() string { return proto.CompactTextString(m) }
+func (*Settings) ProtoMessage()    {}
+func (*Settings) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{18}
+}
+
+func (m *Settings) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Settings.Unmarshal(m, b)
+}
+func (m *Settings) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Settings.Marshal(b, m, deterministic)
+}
+func (m *Settings) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Settings.Merge(m, src)
+}
+func (m *Settings) XXX_Size() int {
+	return xxx_messageInfo_Settings.Size(m)
+}
+func (m *Settings) XXX_DiscardUnknown() {
+	xxx_messageInfo_Settings.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Settings proto.InternalMessageInfo
+
+func (m *Settings) GetFrameRate() int32 {
+	if m != nil {
+		return m.FrameRate
+	}
+	return 0
+}
+
+func (m *Settings) GetLogLevel() string {
+	if m != nil {
+		return m.LogLevel
+	}
+	return ""
+}
+
+type RecordRequest struct {
+	// name is the file name in the server's recording directory, empty names it by the time
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecordRequest) Reset()         { *m = RecordRequest{} }
+func (m *RecordRequest) String() string { return proto.CompactTextString(m) }
+func (*RecordRequest) ProtoMessage()    {}
+func (*RecordRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{19}
+}
+
+func (m *RecordRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecordRequest.Unmarshal(m, b)
+}
+func (m *RecordRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecordRequest.Marshal(b, m, deterministic)
+}
+func (m *RecordRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecordRequest.Merge(m, src)
+}
+func (m *RecordRequest) XXX_Size() int {
+	return xxx_messageInfo_RecordRequest.Size(m)
+}
+func (m *RecordRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecordRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecordRequest proto.InternalMessageInfo
+
+func (m *RecordRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type StopRecordingRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StopRecordingRequest) Reset()         { *m = StopRecordingRequest{} }
+func (m *StopRecordingRequest) String() string { return proto.CompactTextString(m) }
+func (*StopRecordingRequest) ProtoMessage()    {}
+func (*StopRecordingRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{20}
+}
+
+func (m *StopRecordingRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StopRecordingRequest.Unmarshal(m, b)
+}
+func (m *StopRecordingRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StopRecordingRequest.Marshal(b, m, deterministic)
+}
+func (m *StopRecordingRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StopRecordingRequest.Merge(m, src)
+}
+func (m *StopRecordingRequest) XXX_Size() int {
+	return xxx_messageInfo_StopRecordingRequest.Size(m)
+}
+func (m *StopRecordingRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StopRecordingRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StopRecordingRequest proto.InternalMessageInfo
+
+type Recording struct {
+	// path is the file on the server
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// startedAt is when recording started, in Unix seconds
+	StartedAt int64 `protobuf:"varint,2,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
+	// seconds is the length of the audio recorded so far
+	Seconds              float32  `protobuf:"fixed32,3,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Recording) Reset()         { *m = Recording{} }
+func (m *Recording) String() string { return proto.CompactTextString(m) }
+func (*Recording) ProtoMessage()    {}
+func (*Recording) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{21}
+}
+
+func (m *Recording) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Recording.Unmarshal(m, b)
+}
+func (m *Recording) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Recording.Marshal(b, m, deterministic)
+}
+func (m *Recording) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Recording.Merge(m, src)
+}
+func (m *Recording) XXX_Size() int {
+	return xxx_messageInfo_Recording.Size(m)
+}
+func (m *Recording) XXX_DiscardUnknown() {
+	xxx_messageInfo_Recording.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Recording proto.InternalMessageInfo
+
+func (m *Recording) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Recording) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *Recording) GetSeconds() float32 {
+	if m != nil {
+		return m.Seconds
+	}
+	return 0
+}
+
+type GetStatsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStatsRequest) Reset()         { *m = GetStatsRequest{} }
+func (m *GetStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStatsRequest) ProtoMessage()    {}
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{22}
+}
+
+func (m *GetStatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStatsRequest.Unmarshal(m, b)
+}
+func (m *GetStatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStatsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetStatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStatsRequest.Merge(m, src)
+}
+func (m *GetStatsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStatsRequest.Size(m)
+}
+func (m *GetStatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStatsRequest proto.InternalMessageInfo
+
+type Stats struct {
+	Streams  int32 `protobuf:"varint,1,opt,name=streams,proto3" json:"streams,omitempty"`
+	Stations int32 `protobuf:"varint,2,opt,name=stations,proto3" json:"stations,omitempty"`
+	// uptimeSeconds is how long the server has been running
+	UptimeSeconds int64           `protobuf:"varint,3,opt,name=uptimeSeconds,proto3" json:"uptimeSeconds,omitempty"`
+	Messages      []*MessageStats `protobuf:"bytes,4,rep,name=messages,proto3" json:"messages,omitempty"`
+	DroppedFrames int64           `protobuf:"varint,5,opt,name=droppedFrames,proto3" json:"droppedFrames,omitempty"`
+	SendErrors    int64           `protobuf:"varint,6,opt,name=sendErrors,proto3" json:"sendErrors,omitempty"`
+	// recording is the recording in progress, if there is one
+	Recording            *Recording `protobuf:"bytes,7,opt,name=recording,proto3" json:"recording,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Stats) Reset()         { *m = Stats{} }
+func (m *Stats) String() string { return proto.CompactTextString(m) }
+func (*Stats) ProtoMessage()    {}
+func (*Stats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{23}
+}
+
+func (m *Stats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Stats.Unmarshal(m, b)
+}
+func (m *Stats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Stats.Marshal(b, m, deterministic)
+}
+func (m *Stats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Stats.Merge(m, src)
+}
+func (m *Stats) XXX_Size() int {
+	return xxx_messageInfo_Stats.Size(m)
+}
+func (m *Stats) XXX_DiscardUnknown() {
+	xxx_messageInfo_Stats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Stats proto.InternalMessageInfo
+
+func (m *Stats) GetStreams() int32 {
+	if m != nil {
+		return m.Streams
+	}
+	return 0
+}
+
+func (m *Stats) GetStations() int32 {
+	if m != nil {
+		return m.Stations
+	}
+	return 0
+}
+
+func (m *Stats) GetUptimeSeconds() int64 {
+	if m != nil {
+		return m.UptimeSeconds
+	}
+	return 0
+}
+
+func (m *Stats) GetMessages() []*MessageStats {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func (m *Stats) GetDroppedFrames() int64 {
+	if m != nil {
+		return m.DroppedFrames
+	}
+	return 0
+}
+
+func (m *Stats) GetSendErrors() int64 {
+	if m != nil {
+		return m.SendErrors
+	}
+	return 0
+}
+
+func (m *Stats) GetRecording() *Recording {
+	if m != nil {
+		return m.Recording
+	}
+	return nil
+}
+
+type ListRoomsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRoomsRequest) Reset()         { *m = ListRoomsRequest{} }
+func (m *ListRoomsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRoomsRequest) ProtoMessage()    {}
+func (*ListRoomsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{24}
+}
+
+func (m *ListRoomsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRoomsRequest.Unmarshal(m, b)
+}
+func (m *ListRoomsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRoomsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRoomsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRoomsRequest.Merge(m, src)
+}
+func (m *ListRoomsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRoomsRequest.Size(m)
+}
+func (m *ListRoomsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRoomsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRoomsRequest proto.InternalMessageInfo
+
+type RoomList struct {
+	Rooms                []*Room  `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RoomList) Reset()         { *m = RoomList{} }
+func (m *RoomList) String() string { return proto.CompactTextString(m) }
+func (*RoomList) ProtoMessage()    {}
+func (*RoomList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{25}
+}
+
+func (m *RoomList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoomList.Unmarshal(m, b)
+}
+func (m *RoomList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoomList.Marshal(b, m, deterministic)
+}
+func (m *RoomList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoomList.Merge(m, src)
+}
+func (m *RoomList) XXX_Size() int {
+	return xxx_messageInfo_RoomList.Size(m)
+}
+func (m *RoomList) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoomList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoomList proto.InternalMessageInfo
+
+func (m *RoomList) GetRooms() []*Room {
+	if m != nil {
+		return m.Rooms
+	}
+	return nil
+}
+
+type Room struct {
+	Name     string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Stations []string `protobuf:"bytes,2,rep,name=stations,proto3" json:"stations,omitempty"`
+	// broadcasting is the station whose video is being relayed in the room, if any
+	Broadcasting         string   `protobuf:"bytes,3,opt,name=broadcasting,proto3" json:"broadcasting,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Room) Reset()         { *m = Room{} }
+func (m *Room) String() string { return proto.CompactTextString(m) }
+func (*Room) ProtoMessage()    {}
+func (*Room) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{26}
+}
+
+func (m *Room) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Room.Unmarshal(m, b)
+}
+func (m *Room) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Room.Marshal(b, m, deterministic)
+}
+func (m *Room) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Room.Merge(m, src)
+}
+func (m *Room) XXX_Size() int {
+	return xxx_messageInfo_Room.Size(m)
+}
+func (m *Room) XXX_DiscardUnknown() {
+	xxx_messageInfo_Room.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Room proto.InternalMessageInfo
+
+func (m *Room) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Room) GetStations() []string {
+	if m != nil {
+		return m.Stations
+	}
+	return nil
+}
+
+func (m *Room) GetBroadcasting() string {
+	if m != nil {
+		return m.Broadcasting
+	}
+	return ""
+}
+
+type MoveRequest struct {
+	Station              string   `protobuf:"bytes,1,opt,name=station,proto3" json:"station,omitempty"`
+	Room                 string   `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MoveRequest) Reset()         { *m = MoveRequest{} }
+func (m *MoveRequest) String() string { return proto.CompactTextString(m) }
+func (*MoveRequest) ProtoMessage()    {}
+func (*MoveRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{27}
+}
+
+func (m *MoveRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MoveRequest.Unmarshal(m, b)
+}
+func (m *MoveRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MoveRequest.Marshal(b, m, deterministic)
+}
+func (m *MoveRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MoveRequest.Merge(m, src)
+}
+func (m *MoveRequest) XXX_Size() int {
+	return xxx_messageInfo_MoveRequest.Size(m)
+}
+func (m *MoveRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MoveRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MoveRequest proto.InternalMessageInfo
+
+func (m *MoveRequest) GetStation() string {
+	if m != nil {
+		return m.Station
+	}
+	return ""
+}
+
+func (m *MoveRequest) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+type MessageStats struct {
+	// type is image, audio, status, roster, bitrate, keyframe_request or event
+	Type                 string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Received             int64    `protobuf:"varint,2,opt,name=received,proto3" json:"received,omitempty"`
+	ReceivedBytes        int64    `protobuf:"varint,3,opt,name=receivedBytes,proto3" json:"receivedBytes,omitempty"`
+	Sent                 int64    `protobuf:"varint,4,opt,name=sent,proto3" json:"sent,omitempty"`
+	SentBytes            int64    `protobuf:"varint,5,opt,name=sentBytes,proto3" json:"sentBytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MessageStats) Reset()         { *m = MessageStats{} }
+func (m *MessageStats) String() string { return proto.CompactTextString(m) }
+func (*MessageStats) ProtoMessage()    {}
+func (*MessageStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4b7dc4dbe05ff714, []int{28}
+}
+
+func (m *MessageStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MessageStats.Unmarshal(m, b)
+}
+func (m *MessageStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MessageStats.Marshal(b, m, deterministic)
+}
+func (m *MessageStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MessageStats.Merge(m, src)
+}
+func (m *MessageStats) XXX_Size() int {
+	return xxx_messageInfo_MessageStats.Size(m)
+}
+func (m *MessageStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_MessageStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MessageStats proto.InternalMessageInfo
+
+func (m *MessageStats) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *MessageStats) GetReceived() int64 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *MessageStats) GetReceivedBytes() int64 {
+	if m != nil {
+		return m.ReceivedBytes
+	}
+	return 0
+}
+
+func (m *MessageStats) GetSent() int64 {
+	if m != nil {
+		return m.Sent
+	}
+	return 0
+}
+
+func (m *MessageStats) GetSentBytes() int64 {
+	if m != nil {
+		return m.SentBytes
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("EventType", EventType_name, EventType_value)
+	proto.RegisterType((*Broadcast)(nil), "Broadcast")
+	proto.RegisterType((*Image)(nil), "Image")
+	proto.RegisterType((*Tile)(nil), "Tile")
+	proto.RegisterType((*Audio)(nil), "Audio")
+	proto.RegisterType((*Status)(nil), "Status")
+	proto.RegisterType((*Station)(nil), "Station")
+	proto.RegisterType((*Roster)(nil), "Roster")
+	proto.RegisterType((*BitrateHint)(nil), "BitrateHint")
+	proto.RegisterType((*KeyframeRequest)(nil), "KeyframeRequest")
+	proto.RegisterType((*Event)(nil), "Event")
+	proto.RegisterType((*ListStreamsRequest)(nil), "ListStreamsRequest")
+	proto.RegisterType((*StreamList)(nil), "StreamList")
+	proto.RegisterType((*StreamInfo)(nil), "StreamInfo")
+	proto.RegisterType((*StationRequest)(nil), "StationRequest")
+	proto.RegisterType((*MuteRequest)(nil), "MuteRequest")
+	proto.RegisterType((*Announcement)(nil), "Announcement")
+	proto.RegisterType((*AdminReply)(nil), "AdminReply")
+	proto.RegisterType((*GetSettingsRequest)(nil), "GetSettingsRequest")
+	proto.RegisterType((*Settings)(nil), "Settings")
+	proto.RegisterType((*RecordRequest)(nil), "RecordRequest")
+	proto.RegisterType((*StopRecordingRequest)(nil), "StopRecordingRequest")
+	proto.RegisterType((*Recording)(nil), "Recording")
+	proto.RegisterType((*GetStatsRequest)(nil), "GetStatsRequest")
+	proto.RegisterType((*Stats)(nil), "Stats")
+	proto.RegisterType((*ListRoomsRequest)(nil), "ListRoomsRequest")
+	proto.RegisterType((*RoomList)(nil), "RoomList")
+	proto.RegisterType((*Room)(nil), "Room")
+	proto.RegisterType((*MoveRequest)(nil), "MoveRequest")
+	proto.RegisterType((*MessageStats)(nil), "MessageStats")
+}
+
+func init() { proto.RegisterFile("intercom.proto", fileDescriptor_4b7dc4dbe05ff714) }
+
+var fileDescriptor_4b7dc4dbe05ff714 = []byte{
+	// 1390 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x57, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x8e, 0xe3, 0x38, 0x71, 0x4e, 0xd2, 0x34, 0x3b, 0xbb, 0xac, 0xa2, 0x80, 0x4a, 0xf1, 0x2e,
+	0xbb, 0x61, 0x91, 0x0c, 0x74, 0x11, 0x5c, 0x00, 0x17, 0xe9, 0x52, 0xb6, 0xd5, 0x6e, 0x53, 0x34,
+	0x69, 0x59, 0xb4, 0x42, 0x42, 0xae, 0x3d, 0x4d, 0x4d, 0xe3, 0x9f, 0xb5, 0x27, 0xa5, 0x41, 0x3c,
+	0x07, 0xb7, 0xbc, 0x04, 0x6f, 0xc2, 0x15, 0xcf, 0x82, 0x84, 0xd0, 0x99, 0xf1, 0x8c, 0x9d, 0x34,
+	0x12, 0x5c, 0x75, 0xce, 0x77, 0x8e, 0x27, 0xe7, 0xef, 0xfb, 0xec, 0x42, 0x2f, 0x8c, 0x39, 0xcb,
+	0xfc, 0x24, 0x72, 0xd3, 0x2c, 0xe1, 0x89, 0xf3, 0x67, 0x1d, 0xda, 0xfb, 0x59, 0xe2, 0x05, 0xbe,
+	0x97, 0x73, 0x42, 0xa0, 0x11, 0x7b, 0x11, 0x1b, 0x18, 0xbb, 0xc6, 0xa8, 0x4d, 0xc5, 0x19, 0xb1,
+	0x2c, 0x49, 0xa2, 0x41, 0x5b, 0x62, 0x78, 0x26, 0x3b, 0x60, 0x85, 0x91, 0x37, 0x63, 0x83, 0xfa,
+	0xae, 0x31, 0xea, 0xec, 0x35, 0xdd, 0x23, 0xb4, 0x0e, 0x6b, 0x54, 0xc2, 0xe8, 0xf7, 0x16, 0x41,
+	0x98, 0x0c, 0xcc, 0xc2, 0x3f, 0x46, 0x0b, 0xfd, 0x02, 0x26, 0xef, 0x41, 0x33, 0xe7, 0x1e, 0x5f,
+	0xe4, 0x83, 0x86, 0x08, 0x68, 0xb9, 0x53, 0x61, 0x1e, 0xd6, 0x68, 0xe1, 0xc0, 0x90, 0x2c, 0xc9,
+	0x39, 0xcb, 0x06, 0x56, 0x11, 0x42, 0x85, 0x89, 0x21, 0xd2, 0x41, 0x46, 0xd0, 0x3a, 0x0f, 0x79,
+	0xe6, 0x71, 0x36, 0x68, 0x8a, 0x98, 0xae, 0xbb, 0x2f, 0xed, 0xc3, 0x30, 0xe6, 0x87, 0x35, 0xaa,
+	0xdc, 0xe4, 0x4b, 0xd8, 0xbe, 0x62, 0xcb, 0x8b, 0xcc, 0x8b, 0x18, 0x65, 0x6f, 0x16, 0x2c, 0xe7,
+	0x83, 0x96, 0x78, 0xa2, 0xef, 0xbe, 0x58, 0xc5, 0x0f, 0x6b, 0x74, 0x3d, 0x14, 0xab, 0x61, 0xd7,
+	0x2c, 0xe6, 0x03, 0xbb, 0xa8, 0xe6, 0x00, 0x2d, 0xac, 0x46, 0xc0, 0xfb, 0x7d, 0xe8, 0x9d, 0xab,
+	0x16, 0xfe, 0xc8, 0x97, 0x29, 0x73, 0xfe, 0x30, 0xc0, 0x12, 0x2d, 0x21, 0xf7, 0xa1, 0x79, 0xc9,
+	0xc2, 0xd9, 0x25, 0x17, 0x3d, 0xb5, 0x68, 0x61, 0x91, 0x7b, 0x60, 0xfd, 0x1c, 0x06, 0xfc, 0x52,
+	0x74, 0xd0, 0xa2, 0xd2, 0xc0, 0x5e, 0xe3, 0xf3, 0xa2, 0x6d, 0x16, 0x15, 0x67, 0x8c, 0x3c, 0x5f,
+	0x72, 0x26, 0x5b, 0xd5, 0xa5, 0xd2, 0x20, 0x43, 0xb0, 0x79, 0x38, 0x67, 0xd3, 0xf0, 0x17, 0x26,
+	0x1a, 0x64, 0x51, 0x6d, 0xa3, 0x4f, 0x95, 0x20, 0x1a, 0x63, 0x53, 0x6d, 0x93, 0xb7, 0xc1, 0xc2,
+	0xb8, 0x7c, 0xd0, 0xda, 0x35, 0x47, 0x9d, 0x3d, 0xcb, 0x3d, 0x0d, 0xe7, 0x8c, 0x4a, 0xcc, 0xf9,
+	0x0c, 0x1a, 0x68, 0x92, 0x2e, 0x18, 0x37, 0x45, 0xbe, 0xc6, 0x0d, 0x5a, 0xcb, 0x22, 0x4d, 0x63,
+	0x89, 0x29, 0xfe, 0x94, 0xb2, 0x99, 0x48, 0xb1, 0x4b, 0xc5, 0xd9, 0xf9, 0xdd, 0x00, 0x4b, 0x4c,
+	0x98, 0xec, 0x00, 0xe4, 0x5e, 0x94, 0xce, 0x19, 0xc5, 0xa9, 0xc8, 0x2b, 0x2a, 0x08, 0xb6, 0x63,
+	0xce, 0xe2, 0x99, 0xae, 0xbb, 0xb0, 0xc8, 0x00, 0x5a, 0x32, 0x2a, 0x1f, 0x98, 0xbb, 0xe6, 0xc8,
+	0xa2, 0xca, 0xc4, 0x62, 0xfc, 0x4b, 0x2f, 0x8e, 0xd9, 0x5c, 0x76, 0xc0, 0xa2, 0xda, 0xc6, 0xd6,
+	0xcc, 0xd9, 0x35, 0x9b, 0x8b, 0x0e, 0xd4, 0xa9, 0x34, 0x30, 0xc3, 0x94, 0x79, 0x57, 0xa2, 0xf4,
+	0x3a, 0x15, 0x67, 0xe7, 0x07, 0x68, 0xca, 0x0d, 0xc3, 0xfb, 0xa2, 0xd0, 0x3f, 0x5e, 0x70, 0x16,
+	0x88, 0xfc, 0x6c, 0xaa, 0x6d, 0xf2, 0x0e, 0xb4, 0x7d, 0x2f, 0x62, 0x99, 0x77, 0x72, 0x71, 0x21,
+	0x12, 0xb4, 0x69, 0x09, 0x60, 0x8e, 0x69, 0x16, 0x5e, 0x7b, 0xfe, 0x52, 0x14, 0x6f, 0x53, 0x65,
+	0x3a, 0xbf, 0x42, 0x0b, 0x6f, 0x0f, 0x93, 0x78, 0x23, 0x83, 0xde, 0xd5, 0xdb, 0x5e, 0x5f, 0xd9,
+	0x76, 0xbd, 0xeb, 0xba, 0x0e, 0x73, 0x53, 0x1d, 0x8d, 0xb2, 0x0e, 0x4d, 0x46, 0xab, 0x24, 0xa3,
+	0xb3, 0x0f, 0x4d, 0x49, 0x0d, 0xf2, 0x10, 0xec, 0x5c, 0xe6, 0x91, 0x0f, 0x0c, 0x31, 0x5f, 0xdb,
+	0x2d, 0x12, 0xa3, 0xda, 0xa3, 0xef, 0xa8, 0x57, 0xee, 0xf8, 0x1c, 0x3a, 0x15, 0xea, 0x90, 0x11,
+	0x6c, 0x47, 0xde, 0xcd, 0x77, 0x61, 0xc0, 0x92, 0x02, 0x2e, 0x66, 0xb9, 0x0e, 0x3b, 0x77, 0x60,
+	0x7b, 0x8d, 0x41, 0xce, 0x15, 0x58, 0x82, 0x20, 0x64, 0xa7, 0xd8, 0x66, 0x7c, 0xb4, 0xb7, 0x07,
+	0x92, 0x36, 0xa7, 0xcb, 0x94, 0x95, 0x9b, 0x2d, 0xcb, 0xae, 0x57, 0xcb, 0xc6, 0x55, 0x90, 0xa9,
+	0x8a, 0x76, 0xb4, 0xa9, 0x32, 0x05, 0x3b, 0xd8, 0x0d, 0x17, 0x0d, 0x69, 0x53, 0x71, 0x76, 0xee,
+	0x01, 0x79, 0x19, 0xe6, 0x7c, 0xca, 0x33, 0xe6, 0x45, 0xb9, 0x4a, 0xe1, 0x29, 0x80, 0x44, 0xd0,
+	0x47, 0xde, 0xc7, 0x1b, 0x85, 0xbf, 0xe8, 0x4a, 0xc7, 0x95, 0xde, 0xa3, 0xf8, 0x22, 0xa1, 0xca,
+	0xe7, 0xfc, 0x65, 0x00, 0x94, 0x38, 0xe9, 0x41, 0x3d, 0x94, 0x2b, 0x62, 0xd2, 0x7a, 0x18, 0x54,
+	0xf3, 0xaa, 0xdf, 0xca, 0x2b, 0x65, 0x2c, 0x2b, 0xd2, 0x15, 0x67, 0xb2, 0x0b, 0x1d, 0x3f, 0x89,
+	0x63, 0xe6, 0x73, 0x16, 0x8c, 0x65, 0xca, 0x26, 0xad, 0x42, 0x95, 0xad, 0xb0, 0x36, 0x6f, 0xc5,
+	0x0e, 0xc0, 0x45, 0x92, 0xf9, 0x4c, 0xee, 0xaa, 0x24, 0x72, 0x05, 0x41, 0x2e, 0xbd, 0x59, 0xb0,
+	0x05, 0x0b, 0x84, 0x96, 0x59, 0xb4, 0xb0, 0xf4, 0x7c, 0xed, 0xca, 0x7c, 0x9f, 0x40, 0x4f, 0x2d,
+	0x42, 0x21, 0x6a, 0x95, 0x72, 0x8c, 0x95, 0x72, 0x9c, 0xaf, 0xa0, 0x83, 0x3f, 0xf0, 0x9f, 0x81,
+	0x38, 0xbf, 0x48, 0xe4, 0x26, 0xa9, 0x22, 0x0d, 0xc7, 0x81, 0xee, 0x38, 0x8e, 0x93, 0x45, 0xec,
+	0xb3, 0x08, 0xb7, 0x40, 0x4d, 0xcd, 0xa8, 0x4c, 0xad, 0x0b, 0x30, 0x0e, 0xa2, 0x30, 0xa6, 0x2c,
+	0x9d, 0x2f, 0x71, 0x86, 0xcf, 0x19, 0x9f, 0x32, 0xce, 0xc3, 0x78, 0xa6, 0x67, 0xf8, 0x35, 0xd8,
+	0x0a, 0x42, 0x62, 0xca, 0x15, 0x2b, 0x37, 0xb1, 0x04, 0x90, 0xd2, 0xf3, 0x64, 0xf6, 0x52, 0xaf,
+	0x52, 0x9b, 0x6a, 0xdb, 0x79, 0x00, 0x5b, 0x94, 0xf9, 0x49, 0x16, 0xa8, 0x72, 0x36, 0x10, 0xd4,
+	0xb9, 0x0f, 0xf7, 0xa6, 0x3c, 0x49, 0x65, 0x60, 0x18, 0xcf, 0x54, 0x0a, 0xaf, 0xa0, 0xad, 0x31,
+	0x31, 0x65, 0x8f, 0x5f, 0xaa, 0x07, 0xf1, 0x8c, 0x79, 0xe5, 0xdc, 0xcb, 0xe4, 0x8c, 0xeb, 0x62,
+	0xc6, 0x25, 0x20, 0x3a, 0xc7, 0xfc, 0x24, 0x0e, 0xf2, 0x82, 0xd8, 0xca, 0x44, 0xd6, 0x60, 0xc5,
+	0xdc, 0xe3, 0xba, 0xdc, 0x7f, 0x0c, 0xb0, 0x04, 0x20, 0x1b, 0xae, 0xd6, 0xd5, 0x10, 0x5a, 0x28,
+	0x4d, 0x2c, 0x54, 0xf3, 0x5b, 0xea, 0xa7, 0xb6, 0xc9, 0x43, 0xd8, 0x5a, 0xa4, 0x3c, 0x8c, 0xd8,
+	0xb4, 0xf2, 0x93, 0x26, 0x5d, 0x05, 0xc9, 0x07, 0x60, 0x47, 0x2c, 0xcf, 0xbd, 0x99, 0x78, 0x9f,
+	0x20, 0x17, 0xb6, 0xdc, 0x63, 0x09, 0xc8, 0x6c, 0xb4, 0x1b, 0x2f, 0x0c, 0xb2, 0x24, 0x4d, 0x59,
+	0xf0, 0x0d, 0x76, 0x5a, 0xae, 0xa9, 0x49, 0x57, 0x41, 0x21, 0xf8, 0x2c, 0x0e, 0x0e, 0xb2, 0x2c,
+	0xc9, 0x72, 0xb1, 0xa4, 0x26, 0xad, 0x20, 0x64, 0x04, 0xed, 0x4c, 0xb5, 0xb0, 0x78, 0xe7, 0x82,
+	0x5b, 0x36, 0xba, 0x74, 0x3a, 0x04, 0xfa, 0xc8, 0x56, 0x9a, 0x24, 0x25, 0x8f, 0x1f, 0x83, 0x8d,
+	0x36, 0xe2, 0xf8, 0xe6, 0xc2, 0x55, 0x56, 0x1c, 0xb6, 0x5c, 0xf4, 0x50, 0x89, 0x39, 0xaf, 0xa1,
+	0x81, 0xe6, 0x46, 0xf9, 0x5d, 0xed, 0x9a, 0x89, 0xeb, 0xa1, 0xbb, 0xe6, 0x40, 0x57, 0xbf, 0xba,
+	0x31, 0x43, 0x49, 0xe1, 0x15, 0xcc, 0xf9, 0x02, 0x3a, 0xc7, 0xc9, 0xf5, 0xff, 0xe0, 0xc3, 0x26,
+	0x61, 0xfd, 0xcd, 0x80, 0x6e, 0xb5, 0xc1, 0xfa, 0x15, 0xaf, 0xe8, 0x80, 0x42, 0x38, 0x04, 0x3b,
+	0x63, 0x3e, 0x0b, 0xaf, 0x0b, 0x2e, 0x99, 0x54, 0xdb, 0x38, 0x06, 0x75, 0xde, 0x17, 0x9f, 0x01,
+	0xc5, 0x5c, 0x57, 0x40, 0xbc, 0x35, 0x67, 0xb1, 0xd2, 0x19, 0x71, 0x16, 0xcb, 0x89, 0x9f, 0x27,
+	0x4b, 0xae, 0x87, 0x57, 0x02, 0x4f, 0xbe, 0x87, 0xb6, 0xd6, 0x63, 0xd2, 0x81, 0xd6, 0xd9, 0xe4,
+	0xc5, 0xe4, 0xe4, 0xd5, 0xa4, 0x5f, 0x23, 0x00, 0xcd, 0xe3, 0x93, 0xd3, 0xa3, 0x93, 0x49, 0xdf,
+	0x20, 0x36, 0x34, 0xe8, 0xd1, 0xe4, 0x79, 0xbf, 0x8e, 0xe8, 0x78, 0x32, 0x7d, 0x75, 0x40, 0xfb,
+	0x26, 0x9e, 0x0f, 0xc7, 0x93, 0xe7, 0x67, 0xdf, 0xf6, 0x1b, 0xa4, 0x0f, 0xdd, 0xf1, 0x64, 0x72,
+	0x72, 0x36, 0x79, 0x76, 0x70, 0x7c, 0x30, 0x39, 0xed, 0x5b, 0x7b, 0x4f, 0xc1, 0x3e, 0x2a, 0x3e,
+	0x32, 0xc9, 0x63, 0x68, 0x3d, 0x93, 0x9a, 0x47, 0xc0, 0xd5, 0xdf, 0x99, 0xc3, 0xca, 0xd9, 0xa9,
+	0x8d, 0x8c, 0x8f, 0x8d, 0xbd, 0xbf, 0x4d, 0xd8, 0x52, 0x4f, 0x09, 0x69, 0x20, 0x9f, 0x40, 0xa7,
+	0xa2, 0xec, 0xe4, 0xae, 0x7b, 0x5b, 0xe7, 0x87, 0x4a, 0xc8, 0xd1, 0xe5, 0xd4, 0xc8, 0x23, 0x68,
+	0xbc, 0x08, 0xfd, 0x2b, 0xb2, 0xed, 0xae, 0x8a, 0xdd, 0xb0, 0xe3, 0x56, 0xe4, 0xa6, 0x46, 0x1e,
+	0x40, 0x03, 0x15, 0x8e, 0x74, 0xdd, 0x8a, 0xd0, 0xad, 0x07, 0x8d, 0xc0, 0x56, 0x3a, 0x46, 0xb6,
+	0xdc, 0xaa, 0xa4, 0xad, 0x47, 0x7e, 0x04, 0x9d, 0x8a, 0x7e, 0x91, 0xbb, 0xee, 0x6d, 0x35, 0x1b,
+	0xb6, 0x5d, 0x85, 0x88, 0xab, 0x7b, 0x67, 0x69, 0xe0, 0x71, 0xa6, 0x9f, 0x29, 0xdd, 0xab, 0x91,
+	0xae, 0xd0, 0xed, 0x8c, 0x97, 0x32, 0xd4, 0x73, 0x57, 0xf4, 0x6c, 0x58, 0x61, 0x93, 0x53, 0x23,
+	0x9f, 0xc2, 0xd6, 0x8a, 0x92, 0x91, 0xb7, 0xdc, 0x4d, 0xca, 0xb6, 0xf6, 0xd4, 0x23, 0xb0, 0x95,
+	0x1c, 0x91, 0xbe, 0xbb, 0xa6, 0x4c, 0xc3, 0xa6, 0xe8, 0x26, 0x66, 0xf3, 0x21, 0xb4, 0x35, 0x45,
+	0xc9, 0x1d, 0x77, 0x9d, 0xae, 0xc3, 0xb6, 0xab, 0xd8, 0xea, 0xd4, 0xc8, 0x13, 0x49, 0x1b, 0xf5,
+	0x61, 0xd4, 0x75, 0x2b, 0x24, 0x5a, 0xeb, 0xe0, 0x7e, 0xeb, 0xb5, 0x25, 0xfe, 0x1d, 0x39, 0x6f,
+	0x8a, 0x3f, 0x4f, 0xff, 0x1d, 0x00, 0xd0, 0x62, 0xf0, 0xd4, 0xa7, 0x0c, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// IntercomClient is the client API for Intercom service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type IntercomClient interface {
+	// Connect will hold the connection to the server
+	Connect(ctx context.Context, opts ...grpc.CallOption) (Intercom_ConnectClient, error)
+}
+
+type intercomClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewIntercomClient(cc *grpc.ClientConn) IntercomClient {
+	return &intercomClient{cc}
+}
+
+func (c *intercomClient) Connect(ctx context.Context, opts ...grpc.CallOption) (Intercom_ConnectClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Intercom_serviceDesc.Streams[0], "/Intercom/Connect", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &intercomConnectClient{stream}
+	return x, nil
+}
+
+type Intercom_ConnectClient interface {
+	Send(*Broadcast) error
+	Recv() (*Broadcast, error)
+	grpc.ClientStream
+}
+
+type intercomConnectClient struct {
+	grpc.ClientStream
+}
+
+func (x *intercomConnectClient) Send(m *Broadcast) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *intercomConnectClient) Recv() (*Broadcast, error) {
+	m := new(Broadcast)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IntercomServer is the server API for Intercom service.
+type IntercomServer interface {
+	// Connect will hold the connection to the server
+	Connect(Intercom_ConnectServer) error
+}
+
+// UnimplementedIntercomServer can be embedded to have forward compatible implementations.
+type UnimplementedIntercomServer struct {
+}
+
+func (*UnimplementedIntercomServer) Connect(srv Intercom_ConnectServer) error {
+	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+
+func RegisterIntercomServer(s *grpc.Server, srv IntercomServer) {
+	s.RegisterService(&_Intercom_serviceDesc, srv)
+}
+
+func _Intercom_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IntercomServer).Connect(&intercomConnectServer{stream})
+}
+
+type Intercom_ConnectServer interface {
+	Send(*Broadcast) error
+	Recv() (*Broadcast, error)
+	grpc.ServerStream
+}
+
+type intercomConnectServer struct {
+	grpc.ServerStream
+}
+
+func (x *intercomConnectServer) Send(m *Broadcast) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *intercomConnectServer) Recv() (*Broadcast, error) {
+	m := new(Broadcast)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _Intercom_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "Intercom",
+	HandlerType: (*IntercomServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       _Intercom_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "intercom.proto",
+}
+
+// IntercomAdminClient is the client API for IntercomAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type IntercomAdminClient interface {
+	// ListStreams lists the connected streams and the stations on them
+	ListStreams(ctx context.Context, in *ListStreamsRequest, opts ...grpc.CallOption) (*StreamList, error)
+	// Kick disconnects a station
+	Kick(ctx context.Context, in *StationRequest, opts ...grpc.CallOption) (*AdminReply, error)
+	// Mute drops a station's audio on the server, until it is unmuted
+	Mute(ctx context.Context, in *MuteRequest, opts ...grpc.CallOption) (*AdminReply, error)
+	// Announce shows a message at every station
+	Announce(ctx context.Context, in *Announcement, opts ...grpc.CallOption) (*AdminReply, error)
+	// GetSettings returns the server's runtime settings
+	GetSettings(ctx context.Context, in *GetSettingsRequest, opts ...grpc.CallOption) (*Settings, error)
+	// UpdateSettings changes the settings that are set, and returns them all
+	UpdateSettings(ctx context.Context, in *Settings, opts ...grpc.CallOption) (*Settings, error)
+	// StartRecording records the audio relayed by the server to a WAV file in its recording directory
+	StartRecording(ctx context.Context, in *RecordRequest, opts ...grpc.CallOption) (*Recording, error)
+	// StopRecording finishes the recording in progress
+	StopRecording(ctx context.Context, in *StopRecordingRequest, opts ...grpc.CallOption) (*Recording, error)
+	// GetStats returns the server's counters, as served to Prometheus
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+	// ListRooms lists the rooms with stations in them
+	ListRooms(ctx context.Context, in *ListRoomsRequest, opts ...grpc.CallOption) (*RoomList, error)
+	// MoveStation moves a station to another room, it then only hears and sees the stations there
+	MoveStation(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*AdminReply, error)
+}
+
+type intercomAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewIntercomAdminClient(cc *grpc.ClientConn) IntercomAdminClient {
+	return &intercomAdminClient{cc}
+}
+
+func (c *intercomAdminClient) ListStreams(ctx context.Context, in *ListStreamsRequest, opts ...grpc.CallOption) (*StreamList, error) {
+	out := new(StreamList)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/ListStreams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) Kick(ctx context.Context, in *StationRequest, opts ...grpc.CallOption) (*AdminReply, error) {
+	out := new(AdminReply)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/Kick", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) Mute(ctx context.Context, in *MuteRequest, opts ...grpc.CallOption) (*AdminReply, error) {
+	out := new(AdminReply)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/Mute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) Announce(ctx context.Context, in *Announcement, opts ...grpc.CallOption) (*AdminReply, error) {
+	out := new(AdminReply)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/Announce", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) GetSettings(ctx context.Context, in *GetSettingsRequest, opts ...grpc.CallOption) (*Settings, error) {
+	out := new(Settings)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/GetSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) UpdateSettings(ctx context.Context, in *Settings, opts ...grpc.CallOption) (*Settings, error) {
+	out := new(Settings)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/UpdateSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) StartRecording(ctx context.Context, in *RecordRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/StartRecording", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) StopRecording(ctx context.Context, in *StopRecordingRequest, opts ...grpc.CallOption) (*Recording, error) {
+	out := new(Recording)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/StopRecording", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) ListRooms(ctx context.Context, in *ListRoomsRequest, opts ...grpc.CallOption) (*RoomList, error) {
+	out := new(RoomList)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/ListRooms", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *intercomAdminClient) MoveStation(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*AdminReply, error) {
+	out := new(AdminReply)
+	err := c.cc.Invoke(ctx, "/IntercomAdmin/MoveStation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IntercomAdminServer is the server API for IntercomAdmin service.
+type IntercomAdminServer interface {
+	// ListStreams lists the connected streams and the stations on them
+	ListStreams(context.Context, *ListStreamsRequest) (*StreamList, error)
+	// Kick disconnects a station
+	Kick(context.Context, *StationRequest) (*AdminReply, error)
+	// Mute drops a station's audio on the server, until it is unmuted
+	Mute(context.Context, *MuteRequest) (*AdminReply, error)
+	// Announce shows a message at every station
+	Announce(context.Context, *Announcement) (*AdminReply, error)
+	// GetSettings returns the server's runtime settings
+	GetSettings(context.Context, *GetSettingsRequest) (*Settings, error)
+	// UpdateSettings changes the settings that are set, and returns them all
+	UpdateSettings(context.Context, *Settings) (*Settings, error)
+	// StartRecording records the audio relayed by the server to a WAV file in its recording directory
+	StartRecording(context.Context, *RecordRequest) (*Recording, error)
+	// StopRecording finishes the recording in progress
+	StopRecording(context.Context, *StopRecordingRequest) (*Recording, error)
+	// GetStats returns the server's counters, as served to Prometheus
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	// ListRooms lists the rooms with stations in them
+	ListRooms(context.Context, *ListRoomsRequest) (*RoomList, error)
+	// MoveStation moves a station to another room, it then only hears and sees the stations there
+	MoveStation(context.Context, *MoveRequest) (*AdminReply, error)
+}
+
+// UnimplementedIntercomAdminServer can be embedded to have forward compatible implementations.
+type UnimplementedIntercomAdminServer struct {
+}
+
+func (*UnimplementedIntercomAdminServer) ListStreams(ctx context.Context, req *ListStreamsRequest) (*StreamList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStreams not implemented")
+}
+func (*UnimplementedIntercomAdminServer) Kick(ctx context.Context, req *StationRequest) (*AdminReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Kick not implemented")
+}
+func (*UnimplementedIntercomAdminServer) Mute(ctx context.Context, req *MuteRequest) (*AdminReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mute not implemented")
+}
+func (*UnimplementedIntercomAdminServer) Announce(ctx context.Context, req *Announcement) (*AdminReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Announce not implemented")
+}
+func (*UnimplementedIntercomAdminServer) GetSettings(ctx context.Context, req *GetSettingsRequest) (*Settings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSettings not implemented")
+}
+func (*UnimplementedIntercomAdminServer) UpdateSettings(ctx context.Context, req *Settings) (*Settings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSettings not implemented")
+}
+func (*UnimplementedIntercomAdminServer) StartRecording(ctx context.Context, req *RecordRequest) (*Recording, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartRecording not implemented")
+}
+func (*UnimplementedIntercomAdminServer) StopRecording(ctx context.Context, req *StopRecordingRequest) (*Recording, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopRecording not implemented")
+}
+func (*UnimplementedIntercomAdminServer) GetStats(ctx context.Context, req *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (*UnimplementedIntercomAdminServer) ListRooms(ctx context.Context, req *ListRoomsRequest) (*RoomList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRooms not implemented")
+}
+func (*UnimplementedIntercomAdminServer) MoveStation(ctx context.Context, req *MoveRequest) (*AdminReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveStation not implemented")
+}
+
+func RegisterIntercomAdminServer(s *grpc.Server, srv IntercomAdminServer) {
+	s.RegisterService(&_IntercomAdmin_serviceDesc, srv)
+}
+
+func _IntercomAdmin_ListStreams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStreamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).ListStreams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/ListStreams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).ListStreams(ctx, req.(*ListStreamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_Kick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).Kick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/Kick",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).Kick(ctx, req.(*StationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_Mute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MuteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).Mute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/Mute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).Mute(ctx, req.(*MuteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_Announce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Announcement)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).Announce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/Announce",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).Announce(ctx, req.(*Announcement))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_GetSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).GetSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/GetSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).GetSettings(ctx, req.(*GetSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_UpdateSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Settings)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).UpdateSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/UpdateSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).UpdateSettings(ctx, req.(*Settings))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_StartRecording_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).StartRecording(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/StartRecording",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).StartRecording(ctx, req.(*RecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_StopRecording_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopRecordingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).StopRecording(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/StopRecording",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).StopRecording(ctx, req.(*StopRecordingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_ListRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).ListRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/ListRooms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).ListRooms(ctx, req.(*ListRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntercomAdmin_MoveStation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntercomAdminServer).MoveStation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IntercomAdmin/MoveStation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntercomAdminServer).MoveStation(ctx, req.(*MoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _IntercomAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "IntercomAdmin",
+	HandlerType: (*IntercomAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListStreams",
+			Handler:    _IntercomAdmin_ListStreams_Handler,
+		},
+		{
+			MethodName: "Kick",
+			Handler:    _IntercomAdmin_Kick_Handler,
+		},
+		{
+			MethodName: "Mute",
+			Handler:    _IntercomAdmin_Mute_Handler,
+		},
+		{
+			MethodName: "Announce",
+			Handler:    _IntercomAdmin_Announce_Handler,
+		},
+		{
+			MethodName: "GetSettings",
+			Handler:    _IntercomAdmin_GetSettings_Handler,
+		},
+		{
+			MethodName: "UpdateSettings",
+			Handler:    _IntercomAdmin_UpdateSettings_Handler,
+		},
+		{
+			MethodName: "StartRecording",
+			Handler:    _IntercomAdmin_StartRecording_Handler,
+		},
+		{
+			MethodName: "StopRecording",
+			Handler:    _IntercomAdmin_StopRecording_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _IntercomAdmin_GetStats_Handler,
+		},
+		{
+			MethodName: "ListRooms",
+			Handler:    _IntercomAdmin_ListRooms_Handler,
+		},
+		{
+			MethodName: "MoveStation",
+			Handler:    _IntercomAdmin_MoveStation_Handler,
 		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "intercom.proto",
 }
@@ -0,0 +1,118 @@
+package video
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// motionStep is how many pixels apart, in each direction, the detector samples
+	motionStep = 4
+	// pixelThreshold is how far a sampled pixel's brightness has to move from the background to count as changed
+	pixelThreshold = 25
+	// backgroundRate is how fast the background takes in each frame, so lighting
+	// changes and things left in view fade into it
+	backgroundRate = 0.05
+)
+
+// Region is a part of a frame as fractions, 0 to 1, of its width and height.
+type Region struct {
+	X, Y, Width, Height float64
+}
+
+// ParseRegion parses a region from "x,y,width,height", an empty string is the whole frame.
+func ParseRegion(s string) (Region, error) {
+	if s == "" {
+		return Region{Width: 1, Height: 1}, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return Region{}, fmt.Errorf("region %q is not x,y,width,height", s)
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || v < 0 || v > 1 {
+			return Region{}, fmt.Errorf("region %q must be fractions from 0 to 1", s)
+		}
+		values[i] = v
+	}
+	return Region{X: values[0], Y: values[1], Width: values[2], Height: values[3]}, nil
+}
+
+// MotionConfig tunes the motion detector.
+type MotionConfig struct {
+	// Region is the part of the frame watched for motion
+	Region Region
+	// Threshold is the fraction of the region that has to change to be motion
+	Threshold float64
+}
+
+// MotionDetector finds motion by subtracting a running average background
+// from the brightness of each frame.
+type MotionDetector struct {
+	config        MotionConfig
+	background    []float64
+	width, height int
+}
+
+// NewMotionDetector creates a detector with the given settings.
+func NewMotionDetector(config MotionConfig) *MotionDetector {
+	return &MotionDetector{config: config}
+}
+
+// Detect reports the fraction of the region that changed in a BGR frame, and
+// whether that is motion.  The first frame, and any after the size changes,
+// only starts the background.
+func (m *MotionDetector) Detect(pix []byte, width, height int) (float64, bool) {
+	if len(pix) < width*height*channels {
+		return 0, false
+	}
+
+	x0, y0 := int(m.config.Region.X*float64(width)), int(m.config.Region.Y*float64(height))
+	x1 := minInt(x0+int(m.config.Region.Width*float64(width)), width)
+	y1 := minInt(y0+int(m.config.Region.Height*float64(height)), height)
+
+	cols := (x1 - x0 + motionStep - 1) / motionStep
+	rows := (y1 - y0 + motionStep - 1) / motionStep
+	if cols <= 0 || rows <= 0 {
+		return 0, false
+	}
+
+	fresh := width != m.width || height != m.height || len(m.background) != cols*rows
+	if fresh {
+		m.background = make([]float64, cols*rows)
+		m.width, m.height = width, height
+	}
+
+	changed := 0
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			i := ((y0+row*motionStep)*width + x0 + col*motionStep) * channels
+			// BT.601 luma from BGR
+			luma := 0.114*float64(pix[i]) + 0.587*float64(pix[i+1]) + 0.299*float64(pix[i+2])
+
+			b := &m.background[row*cols+col]
+			if fresh {
+				*b = luma
+				continue
+			}
+			if luma-*b > pixelThreshold || *b-luma > pixelThreshold {
+				changed++
+			}
+			*b += (luma - *b) * backgroundRate
+		}
+	}
+
+	fraction := float64(changed) / float64(cols*rows)
+	return fraction, !fresh && fraction > m.config.Threshold
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
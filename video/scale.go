@@ -0,0 +1,36 @@
+// Package video holds the pure Go image helpers shared by the intercom binaries.
+package video
+
+// Downscale shrinks interleaved 8 bit pixels by factor in each direction,
+// averaging each factor by factor block, and returns the new pixels and size.
+// Pixels are returned as is for a factor under 2 or an image too small to shrink.
+func Downscale(pix []byte, width, height, channels, factor int) ([]byte, int, int) {
+	if factor < 2 || width < factor || height < factor || channels < 1 || len(pix) < width*height*channels {
+		return pix, width, height
+	}
+
+	outWidth, outHeight := width/factor, height/factor
+	out := make([]byte, outWidth*outHeight*channels)
+	sums := make([]int, channels)
+	area := factor * factor
+
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			for ch := range sums {
+				sums[ch] = 0
+			}
+			for dy := 0; dy < factor; dy++ {
+				row := ((y*factor+dy)*width + x*factor) * channels
+				for i := 0; i < factor*channels; i++ {
+					sums[i%channels] += int(pix[row+i])
+				}
+			}
+
+			o := (y*outWidth + x) * channels
+			for ch, sum := range sums {
+				out[o+ch] = byte(sum / area)
+			}
+		}
+	}
+	return out, outWidth, outHeight
+}
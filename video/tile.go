@@ -0,0 +1,274 @@
+package video
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"time"
+)
+
+// channels is the layout Encoder and Decoder work in, 8 bit BGR as captured by gocv
+const channels = 3
+
+// changeThreshold is the mean difference per pixel value a tile must move from
+// what was last sent before it is sent again, so camera noise is not
+const changeThreshold = 3
+
+// MaxDimension is the largest width or height of a frame, MaxTileSize of a
+// tile and MaxChannels the most channels of a frame's pixels.  Sizes come from
+// other stations, frames are checked against them before anything is allocated.
+const (
+	MaxDimension = 4096
+	MaxTileSize  = 256
+	MaxChannels  = 4
+)
+
+// CheckTiled returns an error unless a tiled frame's width and height are 1
+// to MaxDimension and its tile size 1 to MaxTileSize.
+func CheckTiled(width, height, tileSize int) error {
+	if err := checkDimensions(width, height); err != nil {
+		return err
+	}
+	if tileSize < 1 || tileSize > MaxTileSize {
+		return fmt.Errorf("video: tile size %v outside 1 to %v", tileSize, MaxTileSize)
+	}
+	return nil
+}
+
+// CheckFrame returns the channels of a frame of raw pixels, or an error
+// unless its width and height are 1 to MaxDimension and pix holds exactly
+// width by height pixels of 1 to MaxChannels channels.
+func CheckFrame(pix []byte, width, height int) (int, error) {
+	if err := checkDimensions(width, height); err != nil {
+		return 0, err
+	}
+	channels := len(pix) / (width * height)
+	if channels < 1 || channels > MaxChannels || len(pix) != width*height*channels {
+		return 0, fmt.Errorf("video: %v bytes are not %vx%v pixels", len(pix), width, height)
+	}
+	return channels, nil
+}
+
+func checkDimensions(width, height int) error {
+	if width < 1 || height < 1 || width > MaxDimension || height > MaxDimension {
+		return fmt.Errorf("video: frame size %vx%v outside 1x1 to %vx%v", width, height, MaxDimension, MaxDimension)
+	}
+	return nil
+}
+
+// ErrNoKeyframe is returned for a delta frame that does not build on the frame
+// the decoder holds, a keyframe is needed before video can be shown again.
+var ErrNoKeyframe = errors.New("video: delta frame without a keyframe")
+
+// Tile is one JPEG compressed square of a frame, at column X and row Y.
+type Tile struct {
+	X, Y int
+	JPEG []byte
+}
+
+// Encoder splits frames into tiles and sends only the tiles that changed,
+// with every tile sent in a keyframe on an interval or when asked for.
+type Encoder struct {
+	tileSize         int
+	quality          int
+	keyframeInterval time.Duration
+
+	// reference holds the pixels as last sent, tiles are compared against it
+	reference     []byte
+	width, height int
+	lastKeyframe  time.Time
+	wantKeyframe  bool
+}
+
+// NewEncoder creates an encoder for tiles of tileSize pixels square at a JPEG
+// quality of 1 to 100, sending a keyframe at least every keyframeInterval.
+func NewEncoder(tileSize, quality int, keyframeInterval time.Duration) *Encoder {
+	return &Encoder{tileSize: tileSize, quality: quality, keyframeInterval: keyframeInterval}
+}
+
+// RequestKeyframe has the next frame sent in full.
+func (e *Encoder) RequestKeyframe() {
+	e.wantKeyframe = true
+}
+
+// TileSize is the width and height of the tiles in pixels.
+func (e *Encoder) TileSize() int {
+	return e.tileSize
+}
+
+// Encode returns the tiles of a BGR frame that changed since they were last
+// sent, or all of them and true for a keyframe.
+func (e *Encoder) Encode(pix []byte, width, height int) ([]Tile, bool, error) {
+	if len(pix) < width*height*channels {
+		return nil, false, errors.New("video: frame is smaller than its size")
+	}
+
+	keyframe := e.wantKeyframe || width != e.width || height != e.height ||
+		time.Since(e.lastKeyframe) >= e.keyframeInterval
+	if keyframe {
+		e.reference = make([]byte, width*height*channels)
+		e.width, e.height = width, height
+		e.lastKeyframe = time.Now()
+		e.wantKeyframe = false
+	}
+
+	var tiles []Tile
+	for y := 0; y*e.tileSize < height; y++ {
+		for x := 0; x*e.tileSize < width; x++ {
+			bounds := tileBounds(x, y, e.tileSize, width, height)
+			if !keyframe && tileDifference(pix, e.reference, bounds, width) < changeThreshold {
+				continue
+			}
+
+			data, err := encodeTile(pix, bounds, width, e.quality)
+			if err != nil {
+				return nil, false, err
+			}
+			copyTile(e.reference, pix, bounds, width)
+			tiles = append(tiles, Tile{X: x, Y: y, JPEG: data})
+		}
+	}
+	return tiles, keyframe, nil
+}
+
+// Decoder rebuilds frames from the tiles an Encoder sends.
+type Decoder struct {
+	pix           []byte
+	width, height int
+}
+
+// Decode draws tiles into the frame being rebuilt and returns its BGR pixels,
+// which are reused by the next call.  A delta frame is only drawn over a
+// frame of the same size that started from a keyframe, otherwise ErrNoKeyframe
+// is returned.  Sizes that fail CheckTiled, and tiles larger than the tile
+// size, are errors.
+func (d *Decoder) Decode(width, height, tileSize int, tiles []Tile, keyframe bool) ([]byte, error) {
+	if err := CheckTiled(width, height, tileSize); err != nil {
+		return nil, err
+	}
+	if keyframe {
+		if width != d.width || height != d.height || d.pix == nil {
+			d.pix = make([]byte, width*height*channels)
+			d.width, d.height = width, height
+		}
+	} else if d.pix == nil || width != d.width || height != d.height {
+		return nil, ErrNoKeyframe
+	}
+
+	for _, tile := range tiles {
+		bounds := tileBounds(tile.X, tile.Y, tileSize, width, height)
+		if bounds.Empty() {
+			continue
+		}
+		img, err := decodeJPEG(tile.JPEG, tileSize)
+		if err != nil {
+			return nil, err
+		}
+		drawTile(d.pix, img, bounds, width)
+	}
+	return d.pix, nil
+}
+
+// Reset drops the frame being rebuilt, so only a keyframe can start it again.
+func (d *Decoder) Reset() {
+	d.pix = nil
+}
+
+func tileBounds(x, y, tileSize, width, height int) image.Rectangle {
+	return image.Rect(x*tileSize, y*tileSize, (x+1)*tileSize, (y+1)*tileSize).
+		Intersect(image.Rect(0, 0, width, height))
+}
+
+// tileDifference is the mean absolute difference of the pixel values in bounds
+func tileDifference(a, b []byte, bounds image.Rectangle, width int) float64 {
+	sum := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := (y*width + bounds.Min.X) * channels
+		for i := row; i < row+bounds.Dx()*channels; i++ {
+			diff := int(a[i]) - int(b[i])
+			if diff < 0 {
+				diff = -diff
+			}
+			sum += diff
+		}
+	}
+	return float64(sum) / float64(bounds.Dx()*bounds.Dy()*channels)
+}
+
+func copyTile(dst, src []byte, bounds image.Rectangle, width int) {
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := (y*width + bounds.Min.X) * channels
+		copy(dst[row:row+bounds.Dx()*channels], src[row:])
+	}
+}
+
+// EncodeJPEG encodes a whole frame of BGR pixels.
+func EncodeJPEG(pix []byte, width, height, quality int) ([]byte, error) {
+	return encodeTile(pix, image.Rect(0, 0, width, height), width, quality)
+}
+
+// DecodeJPEG decodes a whole JPEG frame to BGR pixels and returns them with
+// its size, which has to be at most MaxDimension either way.
+func DecodeJPEG(data []byte) ([]byte, int, int, error) {
+	img, err := decodeJPEG(data, MaxDimension)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	pix := make([]byte, width*height*channels)
+	drawTile(pix, img, image.Rect(0, 0, width, height), width)
+	return pix, width, height, nil
+}
+
+// decodeJPEG decodes data, reading its header first so a JPEG claiming to be
+// larger than maxSize either way is refused before its pixels are allocated
+func decodeJPEG(data []byte, maxSize int) (image.Image, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxSize || config.Height > maxSize {
+		return nil, fmt.Errorf("video: %vx%v JPEG larger than %v", config.Width, config.Height, maxSize)
+	}
+	return jpeg.Decode(bytes.NewReader(data))
+}
+
+func encodeTile(pix []byte, bounds image.Rectangle, width, quality int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		row := ((bounds.Min.Y+y)*width + bounds.Min.X) * channels
+		for x := 0; x < bounds.Dx(); x++ {
+			i := row + x*channels
+			img.SetRGBA(x, y, color.RGBA{R: pix[i+2], G: pix[i+1], B: pix[i], A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawTile(pix []byte, img image.Image, bounds image.Rectangle, width int) {
+	src := img.Bounds()
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+	for y := 0; y < bounds.Dy() && y < src.Dy(); y++ {
+		row := ((bounds.Min.Y+y)*width + bounds.Min.X) * channels
+		for x := 0; x < bounds.Dx() && x < src.Dx(); x++ {
+			var r, g, b uint8
+			if isYCbCr {
+				c := ycbcr.YCbCrAt(src.Min.X+x, src.Min.Y+y)
+				r, g, b = color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+			} else {
+				c := color.RGBAModel.Convert(img.At(src.Min.X+x, src.Min.Y+y)).(color.RGBA)
+				r, g, b = c.R, c.G, c.B
+			}
+			i := row + x*channels
+			pix[i], pix[i+1], pix[i+2] = b, g, r
+		}
+	}
+}
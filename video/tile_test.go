@@ -0,0 +1,67 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestDecodeChecksSizes(t *testing.T) {
+	tests := []struct {
+		name                    string
+		width, height, tileSize int
+	}{
+		{"no width", 0, 48, 16},
+		{"negative height", 64, -1, 16},
+		{"too wide", MaxDimension + 1, 48, 16},
+		{"huge", 1 << 20, 1 << 20, 16},
+		{"no tile size", 64, 48, 0},
+		{"tile size too large", 64, 48, MaxTileSize + 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var d Decoder
+			if _, err := d.Decode(test.width, test.height, test.tileSize, nil, true); err == nil {
+				t.Error("decoded")
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsOversizedTiles(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	var d Decoder
+	if _, err := d.Decode(64, 48, 16, []Tile{{JPEG: buf.Bytes()}}, true); err == nil {
+		t.Error("decoded a tile wider than the tile size")
+	}
+}
+
+func TestCheckFrame(t *testing.T) {
+	tests := []struct {
+		name          string
+		bytes         int
+		width, height int
+		want          int
+	}{
+		{"gray", 64 * 48, 64, 48, 1},
+		{"BGR", 64 * 48 * 3, 64, 48, 3},
+		{"BGRA", 64 * 48 * 4, 64, 48, 4},
+		{"partial pixel", 64*48*3 + 1, 64, 48, 0},
+		{"too few bytes", 100, 64, 48, 0},
+		{"too many channels", 64 * 48 * 5, 64, 48, 0},
+		{"no height", 64, 64, 0, 0},
+		{"too tall", MaxDimension + 1, 1, MaxDimension + 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			channels, err := CheckFrame(make([]byte, test.bytes), test.width, test.height)
+			if channels != test.want || (err == nil) != (test.want > 0) {
+				t.Errorf("%v channels, %v, want %v", channels, err, test.want)
+			}
+		})
+	}
+}